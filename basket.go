@@ -0,0 +1,352 @@
+package kiteconnect
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// basketCancelTimeout is the maximum time a cancelled leg is awaited for on rollback.
+const basketCancelTimeout = 10 * time.Second
+
+// BasketSequence represents the order in which the legs of a basket are placed.
+type BasketSequence int
+
+const (
+	// SequenceAsGiven places the legs in the order they are given.
+	SequenceAsGiven BasketSequence = iota
+	// SequenceBuysFirst places the buy legs before the sell legs, which
+	// reduces the margin required for hedged positions.
+	SequenceBuysFirst
+	// SequenceSellsFirst places the sell legs before the buy legs.
+	SequenceSellsFirst
+)
+
+// RollbackPolicy represents what's done with the placed legs of a basket when a leg fails.
+type RollbackPolicy int
+
+const (
+	// RollbackNone leaves the placed legs as they are.
+	RollbackNone RollbackPolicy = iota
+	// RollbackCancel cancels the placed legs which aren't complete yet.
+	RollbackCancel
+	// RollbackExit cancels the placed legs which aren't complete yet and exits the
+	// filled quantity of every placed leg, except cover orders, with an opposite
+	// MARKET order.
+	RollbackExit
+)
+
+// BasketLegStatus represents the state of a leg after the basket is executed.
+type BasketLegStatus int
+
+const (
+	// LegSkipped is the status of a leg which wasn't placed as an earlier leg failed.
+	LegSkipped BasketLegStatus = iota
+	// LegPlaced is the status of a leg which is open at the exchange.
+	LegPlaced
+	// LegFilled is the status of a leg which is completely filled.
+	LegFilled
+	// LegFailed is the status of a leg which couldn't be placed, was rejected
+	// or cancelled, or didn't reach the awaited status in time.
+	LegFailed
+	// LegCancelled is the status of a placed leg which was cancelled by a rollback.
+	LegCancelled
+	// LegExited is the status of a placed leg whose filled quantity was exited by a rollback.
+	LegExited
+)
+
+// String returns the name of the leg status.
+func (s BasketLegStatus) String() string {
+	switch s {
+	case LegSkipped:
+		return "skipped"
+	case LegPlaced:
+		return "placed"
+	case LegFilled:
+		return "filled"
+	case LegFailed:
+		return "failed"
+	case LegCancelled:
+		return "cancelled"
+	case LegExited:
+		return "exited"
+	default:
+		return "unknown"
+	}
+}
+
+// OrderBasket represents a multi-leg order which is placed as a unit, eg: a spread or a straddle.
+type OrderBasket struct {
+	Legs     []OrderRequest
+	Sequence BasketSequence
+	Rollback RollbackPolicy
+
+	// CheckMargins fetches the margins required by the basket, considering
+	// the existing positions, and fails before placing any leg if they're
+	// more than the available margins.
+	CheckMargins bool
+
+	// WaitForFill waits for each leg to be completely filled before placing
+	// the next one, else a leg is only awaited till it's open at the exchange,
+	// or received by it for after market orders.
+	WaitForFill bool
+
+	// LegTimeout is the maximum time a leg is awaited for, after which it's
+	// considered failed. Zero waits till the context is done.
+	LegTimeout time.Duration
+}
+
+// BasketReport is the result of executing a basket.
+type BasketReport struct {
+	// Legs are the reports of the legs in the order they were placed.
+	Legs []BasketLegReport
+
+	// Margins are the margins of the basket if they were checked.
+	Margins *BasketMargins
+
+	// RolledBack is true if a leg failed and the rollback policy was applied.
+	RolledBack bool
+}
+
+// BasketLegReport is the result of a leg of a basket.
+type BasketLegReport struct {
+	// Index is the position of the leg in OrderBasket.Legs.
+	Index  int
+	Leg    OrderRequest
+	Status BasketLegStatus
+
+	OrderID string
+	// Order is the latest known state of the order.
+	Order  Order
+	Trades []Trade
+	Err    error
+
+	// ExitOrderID is the order which exited the filled quantity of the leg on rollback.
+	ExitOrderID string
+	// RollbackErr is the error in cancelling or exiting the leg on rollback.
+	RollbackErr error
+}
+
+// PlaceBasket places the legs of a basket one at a time, waiting for each to
+// be accepted (or filled) by the exchange before placing the next one. If a
+// leg fails, the remaining legs are skipped and the placed ones are rolled
+// back as per the basket's rollback policy. The report has the state of every
+// leg, and the error is that of the failed leg or the margin check.
+func (c *Client) PlaceBasket(ctx context.Context, b OrderBasket) (BasketReport, error) {
+	var report BasketReport
+
+	if len(b.Legs) == 0 {
+		return report, NewError(InputError, "Basket has no legs", nil)
+	}
+
+	if b.CheckMargins {
+		margins, err := c.checkBasketMargins(b.Legs)
+		report.Margins = margins
+		if err != nil {
+			return report, err
+		}
+	}
+
+	for _, i := range basketSequence(b.Legs, b.Sequence) {
+		report.Legs = append(report.Legs, BasketLegReport{Index: i, Leg: b.Legs[i]})
+	}
+
+	var failed error
+	for i := range report.Legs {
+		leg := &report.Legs[i]
+		if failed != nil {
+			continue
+		}
+
+		if err := c.placeBasketLeg(ctx, b, leg); err != nil {
+			failed = err
+		}
+	}
+
+	if failed != nil && b.Rollback != RollbackNone {
+		report.RolledBack = true
+		for i := len(report.Legs) - 1; i >= 0; i-- {
+			if report.Legs[i].OrderID != "" {
+				c.rollbackBasketLeg(b.Rollback, &report.Legs[i])
+			}
+		}
+	}
+
+	return report, failed
+}
+
+// checkBasketMargins returns the margins required by the legs, if they could be
+// fetched, and an error if they're more than the available margins of the
+// segments of the legs.
+func (c *Client) checkBasketMargins(legs []OrderRequest) (*BasketMargins, error) {
+	params := make([]OrderMarginParam, 0, len(legs))
+	for _, l := range legs {
+		params = append(params, l.MarginParam())
+	}
+
+	margins, err := c.GetBasketMargins(GetBasketParams{OrderParams: params, ConsiderPositions: true})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.GetUserMargins()
+	if err != nil {
+		return &margins, err
+	}
+
+	var equity, commodity bool
+	for _, l := range legs {
+		if l.Params.Exchange == ExchangeMCX {
+			commodity = true
+		} else {
+			equity = true
+		}
+	}
+
+	var available float64
+	if equity {
+		available += user.Equity.Net
+	}
+	if commodity {
+		available += user.Commodity.Net
+	}
+
+	if margins.Final.Total > available {
+		return &margins, NewError(OrderError, fmt.Sprintf("Insufficient margins for the basket: required %.2f, available %.2f",
+			margins.Final.Total, available), nil)
+	}
+	return &margins, nil
+}
+
+// placeBasketLeg places a leg and waits for it to reach the awaited status.
+func (c *Client) placeBasketLeg(ctx context.Context, b OrderBasket, leg *BasketLegReport) error {
+	resp, err := c.PlaceOrder(leg.Leg.Variety, leg.Leg.Params)
+	if err != nil {
+		leg.Status, leg.Err = LegFailed, err
+		return err
+	}
+	leg.OrderID = resp.OrderID
+
+	statuses := []string{OrderStatusComplete}
+	if !b.WaitForFill {
+		statuses = append(statuses, OrderStatusOpen, OrderStatusTriggerPending)
+		// After market orders are only sent to the exchange when it opens.
+		if leg.Leg.Variety == VarietyAMO {
+			statuses = append(statuses, OrderStatusAMOReqReceived)
+		}
+	}
+
+	wctx := ctx
+	if b.LegTimeout > 0 {
+		var cancel context.CancelFunc
+		wctx, cancel = context.WithTimeout(ctx, b.LegTimeout)
+		defer cancel()
+	}
+
+	leg.Order, leg.Trades, err = c.WaitForOrder(wctx, leg.OrderID, statuses...)
+	if err != nil {
+		leg.Status, leg.Err = LegFailed, err
+		return err
+	}
+
+	leg.Status = LegPlaced
+	if leg.Order.Status == OrderStatusComplete {
+		leg.Status = LegFilled
+	}
+	return nil
+}
+
+// rollbackBasketLeg cancels the unfilled part of a placed leg and exits its
+// filled part if the policy says so. The failed leg keeps its status and its
+// rollback is only recorded in its order and rollback error.
+func (c *Client) rollbackBasketLeg(policy RollbackPolicy, leg *BasketLegReport) {
+	// The latest state is fetched as the leg may have been filled since it was placed.
+	history, err := c.GetOrderHistory(leg.OrderID)
+	if err != nil {
+		leg.RollbackErr = err
+		return
+	}
+	if len(history) > 0 {
+		leg.Order = history[len(history)-1]
+	}
+
+	if !IsTerminalStatus(leg.Order.Status) {
+		if _, err := c.CancelOrder(leg.Leg.Variety, leg.OrderID, nil); err != nil {
+			leg.RollbackErr = err
+			return
+		}
+		if leg.Status != LegFailed {
+			leg.Status = LegCancelled
+		}
+
+		// The cancellation is awaited so that the filled quantity is final.
+		ctx, cancel := context.WithTimeout(context.Background(), basketCancelTimeout)
+		o, _, err := c.WaitForOrder(ctx, leg.OrderID)
+		cancel()
+		if o.OrderID != "" {
+			leg.Order = o
+		}
+		if err != nil && !IsTerminalStatus(o.Status) {
+			leg.RollbackErr = err
+			return
+		}
+	}
+
+	// Cover orders have their own stoploss leg and are only cancelled.
+	if policy != RollbackExit || leg.Order.FilledQuantity <= 0 || leg.Leg.Variety == VarietyCO {
+		return
+	}
+
+	p := OrderParams{
+		Exchange:        leg.Leg.Params.Exchange,
+		Tradingsymbol:   leg.Leg.Params.Tradingsymbol,
+		Validity:        ValidityDay,
+		Product:         leg.Leg.Params.Product,
+		OrderType:       OrderTypeMarket,
+		TransactionType: TransactionTypeSell,
+		Quantity:        int(leg.Order.FilledQuantity),
+		Tag:             leg.Leg.Params.Tag,
+	}
+	if leg.Leg.Params.TransactionType == TransactionTypeSell {
+		p.TransactionType = TransactionTypeBuy
+	}
+
+	variety := Variety(VarietyRegular)
+	if leg.Leg.Variety == VarietyAMO {
+		variety = VarietyAMO
+	}
+
+	resp, err := c.PlaceOrder(variety, p)
+	if err != nil {
+		leg.RollbackErr = err
+		return
+	}
+	leg.ExitOrderID = resp.OrderID
+	if leg.Status != LegFailed {
+		leg.Status = LegExited
+	}
+}
+
+// basketSequence returns the indices of the legs in the order they're to be placed.
+func basketSequence(legs []OrderRequest, seq BasketSequence) []int {
+	idx := make([]int, len(legs))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	first := TransactionType("")
+	switch seq {
+	case SequenceBuysFirst:
+		first = TransactionTypeBuy
+	case SequenceSellsFirst:
+		first = TransactionTypeSell
+	default:
+		return idx
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool {
+		return legs[idx[i]].Params.TransactionType == first && legs[idx[j]].Params.TransactionType != first
+	})
+	return idx
+}
@@ -0,0 +1,136 @@
+package kiteconnect
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func basketLeg(t *testing.T, b *OrderBuilder) OrderRequest {
+	req, err := b.Build()
+	require.NoError(t, err)
+	return req
+}
+
+func legStatuses(r BasketReport) []BasketLegStatus {
+	var out []BasketLegStatus
+	for _, l := range r.Legs {
+		out = append(out, l.Status)
+	}
+	return out
+}
+
+func TestPlaceBasket(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.required, s.available = 1000, 5000
+
+	report, err := c.PlaceBasket(context.Background(), OrderBasket{
+		Legs: []OrderRequest{
+			basketLeg(t, NewOrder("NFO:NIFTYCE").Sell(50).Market().Product(ProductNRML)),
+			basketLeg(t, NewOrder("NFO:NIFTYPE").Buy(50).Market().Product(ProductNRML)),
+		},
+		Sequence:     SequenceBuysFirst,
+		CheckMargins: true,
+		WaitForFill:  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1000.0, report.Margins.Final.Total)
+	require.False(t, report.RolledBack)
+	require.Equal(t, []BasketLegStatus{LegFilled, LegFilled}, legStatuses(report))
+
+	// The buy leg is placed first.
+	require.Equal(t, 1, report.Legs[0].Index)
+	require.Equal(t, "NIFTYPE", s.placed[0]["tradingsymbol"])
+	require.Equal(t, "NIFTYCE", s.placed[1]["tradingsymbol"])
+	require.Len(t, report.Legs[1].Trades, 1)
+}
+
+func TestPlaceBasketMargins(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.required, s.available = 5000, 1000
+
+	report, err := c.PlaceBasket(context.Background(), OrderBasket{
+		Legs:         []OrderRequest{basketLeg(t, NewOrder("NSE:INFY").Buy(10).Market().Product(ProductCNC))},
+		CheckMargins: true,
+	})
+	require.Error(t, err)
+	require.Equal(t, OrderError, err.(Error).ErrorType)
+	require.Equal(t, 5000.0, report.Margins.Final.Total)
+	require.Empty(t, report.Legs)
+	require.Empty(t, s.placed)
+}
+
+func TestPlaceBasketRollback(t *testing.T) {
+	t.Parallel()
+
+	legs := []OrderRequest{
+		basketLeg(t, NewOrder("NSE:INFY").Buy(10).Market().Product(ProductMIS).Tag("spread")),
+		basketLeg(t, NewOrder("NSE:OPEN").Sell(5).Limit(10).Product(ProductMIS)),
+		basketLeg(t, NewOrder("NSE:REJECT").Sell(10).Market().Product(ProductMIS)),
+		basketLeg(t, NewOrder("NSE:SBIN").Buy(1).Market().Product(ProductMIS)),
+	}
+
+	// Open legs are cancelled and filled legs are exited.
+	s, c := newExchangeServer(t)
+	report, err := c.PlaceBasket(context.Background(), OrderBasket{Legs: legs, Rollback: RollbackExit})
+	require.Error(t, err)
+	require.Equal(t, OrderError, err.(Error).ErrorType)
+	require.True(t, report.RolledBack)
+	require.Equal(t, []BasketLegStatus{LegExited, LegCancelled, LegFailed, LegSkipped}, legStatuses(report))
+	require.Equal(t, "Insufficient funds", report.Legs[2].Order.StatusMessage)
+	require.Equal(t, []string{"2"}, s.cancelled)
+
+	require.Len(t, s.placed, 4)
+	exit := s.placed[3]
+	require.Equal(t, report.Legs[0].ExitOrderID, exit["order_id"])
+	require.Equal(t, "INFY", exit["tradingsymbol"])
+	require.Equal(t, TransactionTypeSell, exit["transaction_type"])
+	require.Equal(t, OrderTypeMarket, exit["order_type"])
+	require.Equal(t, 10, exit["quantity"])
+	require.Equal(t, "spread", exit["tag"])
+
+	// Filled legs are left as they are when only cancelling.
+	s, c = newExchangeServer(t)
+	report, err = c.PlaceBasket(context.Background(), OrderBasket{Legs: legs, Rollback: RollbackCancel})
+	require.Error(t, err)
+	require.Equal(t, []BasketLegStatus{LegFilled, LegCancelled, LegFailed, LegSkipped}, legStatuses(report))
+	require.Len(t, s.placed, 3)
+
+	// Legs which aren't filled in time fail.
+	s, c = newExchangeServer(t)
+	report, err = c.PlaceBasket(context.Background(), OrderBasket{
+		Legs:        legs[1:2],
+		WaitForFill: true,
+		LegTimeout:  100 * time.Millisecond,
+		Rollback:    RollbackCancel,
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, []BasketLegStatus{LegFailed}, legStatuses(report))
+	require.Equal(t, OrderStatusCancelled, report.Legs[0].Order.Status)
+	require.NoError(t, report.Legs[0].RollbackErr)
+	require.Equal(t, []string{"1"}, s.cancelled)
+}
+
+func TestPlaceBasketAMO(t *testing.T) {
+	t.Parallel()
+
+	// After market orders are placed once they're received by the exchange.
+	s, c := newExchangeServer(t)
+	report, err := c.PlaceBasket(context.Background(), OrderBasket{
+		Legs: []OrderRequest{
+			basketLeg(t, NewOrder("NSE:INFY").Buy(10).Limit(100).Product(ProductCNC).AMO()),
+			basketLeg(t, NewOrder("NSE:SBIN").Buy(10).Limit(100).Product(ProductCNC).AMO()),
+		},
+		LegTimeout: time.Second,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []BasketLegStatus{LegPlaced, LegPlaced}, legStatuses(report))
+	require.Equal(t, OrderStatusAMOReqReceived, report.Legs[1].Order.Status)
+	require.Len(t, s.placed, 2)
+}
@@ -0,0 +1,808 @@
+package kiteconnect
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+const (
+	// bracketAttempts is the number of times placing the stoploss order of a
+	// bracket is attempted on network and server errors.
+	bracketAttempts = 3
+	// bracketRetryInterval is the interval between the attempts.
+	bracketRetryInterval = time.Second
+)
+
+// BracketState represents the state of a bracket.
+type BracketState int
+
+const (
+	// BracketPending is the state of a bracket whose entry order isn't filled yet.
+	BracketPending BracketState = iota
+	// BracketPlacingExits is the state of a bracket whose entry is filled and
+	// whose exits are being placed.
+	BracketPlacingExits
+	// BracketOpen is the state of a bracket whose entry is filled and whose
+	// stoploss is placed. The target may be missing if it couldn't be placed,
+	// in which case Err is set.
+	BracketOpen
+	// BracketUnprotected is the state of a bracket whose position is open
+	// without a stoploss as it couldn't be placed or was cancelled or rejected.
+	BracketUnprotected
+	// BracketFlattening is the state of a bracket whose stoploss couldn't be
+	// placed and whose position is being exited with a MARKET order.
+	BracketFlattening
+	// BracketTargetHit is the state of a bracket which exited at its target.
+	BracketTargetHit
+	// BracketStoppedOut is the state of a bracket which exited at its stoploss.
+	BracketStoppedOut
+	// BracketFlattened is the state of a bracket which was exited with a MARKET
+	// order as its stoploss couldn't be placed.
+	BracketFlattened
+	// BracketCancelled is the state of a bracket whose entry was cancelled or
+	// rejected, or which was cancelled with Cancel.
+	BracketCancelled
+)
+
+// String returns the name of the bracket state.
+func (s BracketState) String() string {
+	switch s {
+	case BracketPending:
+		return "pending"
+	case BracketPlacingExits:
+		return "placing_exits"
+	case BracketOpen:
+		return "open"
+	case BracketUnprotected:
+		return "unprotected"
+	case BracketFlattening:
+		return "flattening"
+	case BracketTargetHit:
+		return "target_hit"
+	case BracketStoppedOut:
+		return "stopped_out"
+	case BracketFlattened:
+		return "flattened"
+	case BracketCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// BracketFailurePolicy represents what's done with the position of a bracket
+// whose stoploss couldn't be placed.
+type BracketFailurePolicy int
+
+const (
+	// BracketKeepPosition leaves the position as it is in the BracketUnprotected state.
+	BracketKeepPosition BracketFailurePolicy = iota
+	// BracketFlatten cancels the target and exits the position with a MARKET order.
+	BracketFlatten
+)
+
+// BracketParams represents an entry order along with its target and stoploss.
+type BracketParams struct {
+	Entry OrderRequest
+	// InstrumentToken is used to match the ticks for the P&L of the bracket.
+	InstrumentToken uint32
+
+	// Target and StopLoss are the distances of the exits from the average
+	// price of the entry.
+	Target   float64
+	StopLoss float64
+
+	// StopLossType is OrderTypeSLM, which is the default, or OrderTypeSL with
+	// the limit price at LimitOffset beyond the trigger.
+	StopLossType OrderType
+	LimitOffset  float64
+	TickSize     float64
+
+	// StopLossFailure is what's done when the stoploss can't be placed, after
+	// retrying network and server errors, or is cancelled or rejected.
+	// Defaults to BracketKeepPosition.
+	StopLossFailure BracketFailurePolicy
+
+	// UseGTT places the exits as a GTT which cancels the other leg on
+	// triggering one, instead of placing a LIMIT and stoploss order. The orders
+	// placed by the GTT are matched to the bracket by their instrument and side,
+	// and confirmed with the orders of the triggered GTT.
+	UseGTT bool
+}
+
+// Bracket is the state of a bracket.
+type Bracket struct {
+	Params BracketParams
+	State  BracketState
+
+	EntryOrderID string
+	// TargetOrderID and StopLossOrderID are the ids of the exits. The order
+	// exiting a flattened position is the stoploss.
+	TargetOrderID   string
+	StopLossOrderID string
+	GTTID           int
+
+	// Quantity and EntryPrice are the filled quantity and average price of the entry.
+	Quantity   float64
+	EntryPrice float64
+	// ExitedQuantity and ExitPrice are the filled quantity and average price of the exits.
+	ExitedQuantity float64
+	ExitPrice      float64
+	LastPrice      float64
+
+	// Err is the latest error in managing the bracket.
+	Err error
+}
+
+// Done returns true if the bracket can't change anymore.
+func (b Bracket) Done() bool {
+	switch b.State {
+	case BracketTargetHit, BracketStoppedOut, BracketFlattened, BracketCancelled:
+		return true
+	}
+	return false
+}
+
+// PnL returns the realised P&L of the exited quantity and the unrealised
+// P&L of the rest at the last price.
+func (b Bracket) PnL() float64 {
+	dir := 1.0
+	if b.Params.Entry.Params.TransactionType == TransactionTypeSell {
+		dir = -1
+	}
+
+	pnl := dir * b.ExitedQuantity * (b.ExitPrice - b.EntryPrice)
+	if open := b.Quantity - b.ExitedQuantity; open > 0 && b.LastPrice > 0 && !b.Done() {
+		pnl += dir * open * (b.LastPrice - b.EntryPrice)
+	}
+	return pnl
+}
+
+// BracketManager emulates bracket orders by placing the target and stoploss
+// of an entry once it's filled and cancelling the other when one of them is
+// filled. Partial fills of an exit reduce the quantity of the other. Feed it
+// the order updates and ticks of the ticker, eg: ticker.OnOrderUpdate(m.OnOrderUpdate)
+// and ticker.OnTick(m.OnTick). The orders of a bracket are placed and cancelled
+// by a goroutine of the bracket in the order of its updates, so that the
+// ticker isn't blocked by the requests. It's safe for concurrent use.
+type BracketManager struct {
+	c *Client
+
+	mu       sync.Mutex
+	brackets map[string]*bracket
+	// orders are the brackets of the entry and exit orders.
+	orders   map[string]*bracket
+	onUpdate func(Bracket)
+
+	retryInterval time.Duration
+	// jobs counts the queued work of all the brackets.
+	jobs sync.WaitGroup
+}
+
+type bracket struct {
+	Bracket
+	// Filled quantities and average prices of the exits.
+	targetFilled, targetPrice float64
+	slFilled, slPrice         float64
+
+	// queue is the work of the bracket which is done in order by a
+	// goroutine which runs while working is set.
+	queue   []func()
+	working bool
+}
+
+// NewBracketManager creates a bracket manager which places orders with the client.
+func NewBracketManager(c *Client) *BracketManager {
+	return &BracketManager{
+		c:             c,
+		brackets:      map[string]*bracket{},
+		orders:        map[string]*bracket{},
+		retryInterval: bracketRetryInterval,
+	}
+}
+
+// OnUpdate sets the callback for the changes of the brackets. It's called by
+// the goroutines of the brackets, so it may be called concurrently for
+// different brackets.
+func (m *BracketManager) OnUpdate(f func(Bracket)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onUpdate = f
+}
+
+// Place places the entry order of a bracket.
+func (m *BracketManager) Place(p BracketParams) (Bracket, error) {
+	if p.Target <= 0 || p.StopLoss <= 0 {
+		return Bracket{}, NewError(InputError, "Target and stoploss should be positive", nil)
+	}
+	if !p.Entry.Params.TransactionType.Valid() || p.Entry.Params.Quantity <= 0 {
+		return Bracket{}, NewError(InputError, "Entry order should be built with NewOrder", nil)
+	}
+	if p.Entry.Variety == VarietyCO || p.Entry.Variety == VarietyBO {
+		return Bracket{}, NewError(InputError, "Entry order can't be a cover or bracket order", nil)
+	}
+	if p.StopLossType == "" {
+		p.StopLossType = OrderTypeSLM
+	}
+	if p.StopLossType != OrderTypeSL && p.StopLossType != OrderTypeSLM {
+		return Bracket{}, NewError(InputError, "Stoploss type should be SL or SL-M", nil)
+	}
+	if p.TickSize <= 0 {
+		p.TickSize = defaultTickSize
+	}
+
+	resp, err := m.c.PlaceOrder(p.Entry.Variety, p.Entry.Params)
+	if err != nil {
+		return Bracket{}, err
+	}
+
+	b := &bracket{Bracket: Bracket{Params: p, EntryOrderID: resp.OrderID}}
+
+	m.mu.Lock()
+	m.brackets[b.EntryOrderID] = b
+	m.orders[b.EntryOrderID] = b
+	m.mu.Unlock()
+
+	return b.Bracket, nil
+}
+
+// Bracket returns the state of a bracket by its entry order id.
+func (m *BracketManager) Bracket(entryOrderID string) (Bracket, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.brackets[entryOrderID]
+	if !ok {
+		return Bracket{}, false
+	}
+	return b.Bracket, true
+}
+
+// Brackets returns the state of all the brackets.
+func (m *BracketManager) Brackets() []Bracket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Bracket, 0, len(m.brackets))
+	for _, b := range m.brackets {
+		out = append(out, b.Bracket)
+	}
+	return out
+}
+
+// OnTick updates the last price of the brackets of the tick's instrument.
+func (m *BracketManager) OnTick(tick models.Tick) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, b := range m.brackets {
+		if b.Params.InstrumentToken != 0 && b.Params.InstrumentToken == tick.InstrumentToken {
+			b.LastPrice = tick.LastPrice
+		}
+	}
+}
+
+// Cancel cancels a bracket. A pending entry is cancelled, leaving any quantity
+// filled before as it is, and the exits of an open bracket are cancelled leaving
+// its position as it is. It waits for the work queued for the bracket, so it
+// mustn't be called from the OnUpdate callback.
+func (m *BracketManager) Cancel(entryOrderID string) error {
+	m.mu.Lock()
+	b, ok := m.brackets[entryOrderID]
+	if !ok {
+		m.mu.Unlock()
+		return NewError(InputError, fmt.Sprintf("Unknown bracket %s", entryOrderID), nil)
+	}
+	done := make(chan error, 1)
+	m.enqueue(b, func() { done <- m.cancel(b) })
+	m.mu.Unlock()
+
+	return <-done
+}
+
+// cancel cancels a bracket on its goroutine.
+func (m *BracketManager) cancel(b *bracket) error {
+	var (
+		cur = m.snapshot(b)
+		err error
+	)
+	switch cur.State {
+	case BracketPending:
+		_, err = m.c.CancelOrder(cur.Params.Entry.Variety, cur.EntryOrderID, nil)
+	case BracketOpen, BracketUnprotected:
+		err = m.cancelExits(cur, true, true)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.update(b, func() {
+		if !b.Done() {
+			b.State = BracketCancelled
+		}
+	})
+	return nil
+}
+
+// OnOrderUpdate queues an order update for its bracket. The entry's fill is
+// claimed right away so that the exits are placed once even if the update is
+// received more than once.
+func (m *BracketManager) OnOrderUpdate(o Order) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.orders[o.OrderID]
+	if !ok {
+		// The order may have been placed by the GTT of a bracket, which is
+		// confirmed with the GTT by the bracket.
+		for _, b := range m.brackets {
+			if b.gttCandidate(o) {
+				b := b
+				m.enqueue(b, func() { m.gttOrderUpdate(b, o) })
+			}
+		}
+		return
+	}
+
+	switch {
+	case b.Done():
+	case o.OrderID == b.EntryOrderID:
+		if b.State != BracketPending || !IsTerminalStatus(o.Status) {
+			return
+		}
+
+		// Exits are placed for the quantity filled, even if the rest was cancelled.
+		if o.FilledQuantity > 0 {
+			b.State = BracketPlacingExits
+			b.Quantity, b.EntryPrice = o.FilledQuantity, o.AveragePrice
+			m.enqueue(b, func() { m.placeExits(b) })
+			return
+		}
+
+		b.State = BracketCancelled
+		if o.Status == OrderStatusRejected {
+			b.Err = NewError(OrderError, fmt.Sprintf("Entry order rejected: %s", o.StatusMessage), nil)
+		}
+		m.enqueue(b, func() { m.update(b, func() {}) })
+	default:
+		m.enqueue(b, func() { m.exitUpdate(b, o) })
+	}
+}
+
+// gttCandidate returns true if an order may have been placed by the GTT of
+// the bracket, as it's an order of the instrument on the exit's side.
+func (b *bracket) gttCandidate(o Order) bool {
+	e := b.Params.Entry.Params
+	return b.Params.UseGTT && b.GTTID != 0 && !b.Done() &&
+		o.Exchange == e.Exchange && o.TradingSymbol == e.Tradingsymbol && o.TransactionType != e.TransactionType
+}
+
+// gttOrderUpdate records an order as an exit of a bracket if it was placed
+// by the bracket's GTT.
+func (m *BracketManager) gttOrderUpdate(b *bracket, o Order) {
+	m.mu.Lock()
+	owner, ok := m.orders[o.OrderID]
+	cur := b.Bracket
+	m.mu.Unlock()
+
+	// The order may have been confirmed by an earlier update.
+	if ok {
+		if owner == b {
+			m.exitUpdate(b, o)
+		}
+		return
+	}
+	if cur.Done() {
+		return
+	}
+
+	gtt, err := m.c.GetGTT(cur.GTTID)
+	if err != nil {
+		m.update(b, func() { b.Err = err })
+		return
+	}
+
+	leg := -1
+	for i, r := range gtt.Results {
+		if r.OrderID == o.OrderID && i < len(gtt.Orders) {
+			leg = i
+		}
+	}
+	if leg < 0 {
+		return
+	}
+
+	// The target is on the profitable side of the entry.
+	price := gtt.Orders[leg].Price
+	target := price >= cur.EntryPrice
+	if cur.Params.Entry.Params.TransactionType == TransactionTypeSell {
+		target = price <= cur.EntryPrice
+	}
+
+	m.mu.Lock()
+	if _, ok := m.orders[o.OrderID]; ok {
+		m.mu.Unlock()
+		return
+	}
+	m.orders[o.OrderID] = b
+	if target {
+		b.TargetOrderID = o.OrderID
+	} else {
+		b.StopLossOrderID = o.OrderID
+	}
+	m.mu.Unlock()
+
+	m.exitUpdate(b, o)
+}
+
+// exitPrices returns the target and stoploss trigger prices of a bracket.
+func (b *bracket) exitPrices() (float64, float64) {
+	p := b.Params
+	if p.Entry.Params.TransactionType == TransactionTypeBuy {
+		return roundToTick(b.EntryPrice+p.Target, p.TickSize, math.Floor),
+			roundToTick(b.EntryPrice-p.StopLoss, p.TickSize, math.Ceil)
+	}
+	return roundToTick(b.EntryPrice-p.Target, p.TickSize, math.Ceil),
+		roundToTick(b.EntryPrice+p.StopLoss, p.TickSize, math.Floor)
+}
+
+// exitParams returns the order parameters common to the exits of a bracket.
+func (b *bracket) exitParams() OrderParams {
+	e := b.Params.Entry.Params
+	o := OrderParams{
+		Exchange:        e.Exchange,
+		Tradingsymbol:   e.Tradingsymbol,
+		Validity:        ValidityDay,
+		Product:         e.Product,
+		TransactionType: TransactionTypeSell,
+		Quantity:        int(b.Quantity),
+		Tag:             e.Tag,
+	}
+	if e.TransactionType == TransactionTypeSell {
+		o.TransactionType = TransactionTypeBuy
+	}
+	return o
+}
+
+// stopLossParams returns the parameters of the stoploss order of a bracket.
+func (b *bracket) stopLossParams() OrderParams {
+	_, trigger := b.exitPrices()
+	o := b.exitParams()
+	o.OrderType, o.TriggerPrice = b.Params.StopLossType, trigger
+	if o.OrderType == OrderTypeSL {
+		if o.TransactionType == TransactionTypeSell {
+			o.Price = roundToTick(trigger-b.Params.LimitOffset, b.Params.TickSize, math.Floor)
+		} else {
+			o.Price = roundToTick(trigger+b.Params.LimitOffset, b.Params.TickSize, math.Ceil)
+		}
+	}
+	return o
+}
+
+// targetParams returns the parameters of the target order of a bracket.
+func (b *bracket) targetParams() OrderParams {
+	target, _ := b.exitPrices()
+	o := b.exitParams()
+	o.OrderType, o.Price = OrderTypeLimit, target
+	return o
+}
+
+// placeExits places the exits of a bracket whose entry is filled. The target
+// and stoploss are placed independently so that a failed target doesn't leave
+// the position without a stoploss.
+func (m *BracketManager) placeExits(b *bracket) {
+	if b.Params.UseGTT {
+		// GTTs aren't retried as there's no way to find a GTT placed by a failed request.
+		if err := m.placeGTT(b); err != nil {
+			m.stopLossFailed(b, err)
+			return
+		}
+		m.update(b, func() { b.State = BracketOpen })
+		return
+	}
+
+	var targetErr error
+	if resp, err := m.c.PlaceOrder(VarietyRegular, b.targetParams()); err != nil {
+		targetErr = err
+	} else {
+		m.track(b, resp.OrderID, func() { b.TargetOrderID = resp.OrderID })
+	}
+
+	id, err := m.placeOrder(b.stopLossParams())
+	if err != nil {
+		m.stopLossFailed(b, err)
+		return
+	}
+	m.track(b, id, func() {
+		b.StopLossOrderID = id
+		b.State, b.Err = BracketOpen, targetErr
+	})
+}
+
+// placeOrder places an order of a bracket, retrying network and server errors.
+// As the order may have been placed by a request which failed, an untracked
+// open order with the same parameters is taken to be the order before retrying.
+func (m *BracketManager) placeOrder(p OrderParams) (string, error) {
+	var (
+		id       string
+		attempts int
+	)
+	err := m.retry(func() error {
+		if attempts++; attempts > 1 {
+			if id = m.findOrder(p); id != "" {
+				return nil
+			}
+		}
+		resp, err := m.c.PlaceOrder(VarietyRegular, p)
+		id = resp.OrderID
+		return err
+	})
+	return id, err
+}
+
+// findOrder returns the id of an open order with the parameters which isn't
+// tracked by the manager, if any.
+func (m *BracketManager) findOrder(p OrderParams) string {
+	orders, err := m.c.GetOrders()
+	if err != nil {
+		return ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, o := range orders {
+		if _, ok := m.orders[o.OrderID]; ok || IsTerminalStatus(o.Status) {
+			continue
+		}
+		if o.Exchange == p.Exchange && o.TradingSymbol == p.Tradingsymbol && o.TransactionType == p.TransactionType &&
+			o.OrderType == p.OrderType && o.Tag == p.Tag && int(o.Quantity) == p.Quantity &&
+			o.Price == p.Price && o.TriggerPrice == p.TriggerPrice {
+			return o.OrderID
+		}
+	}
+	return ""
+}
+
+// retry calls f till it succeeds, fails with an error which isn't transient
+// or fails as many times as the attempts.
+func (m *BracketManager) retry(f func() error) error {
+	var err error
+	for i := 0; i < bracketAttempts; i++ {
+		if i > 0 {
+			time.Sleep(m.retryInterval)
+		}
+		if err = f(); err == nil || !isTransientError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// stopLossFailed handles a bracket whose position isn't protected by a
+// stoploss as per its policy.
+func (m *BracketManager) stopLossFailed(b *bracket, err error) {
+	cur := m.snapshot(b)
+	if cur.Params.StopLossFailure != BracketFlatten {
+		m.update(b, func() { b.State, b.Err = BracketUnprotected, err })
+		return
+	}
+
+	// The target is cancelled before exiting so that both can't be filled.
+	if cerr := m.cancelExits(cur, true, false); cerr != nil {
+		m.update(b, func() { b.State, b.Err = BracketUnprotected, cerr })
+		return
+	}
+
+	p := b.exitParams()
+	p.OrderType = OrderTypeMarket
+	p.Quantity = int(cur.Quantity - cur.ExitedQuantity)
+	id, ferr := m.placeOrder(p)
+	if ferr != nil {
+		m.update(b, func() { b.State, b.Err = BracketUnprotected, ferr })
+		return
+	}
+	m.track(b, id, func() {
+		b.StopLossOrderID = id
+		b.State, b.Err = BracketFlattening, err
+	})
+}
+
+// placeGTT places the exits of a bracket as a GTT.
+func (m *BracketManager) placeGTT(b *bracket) error {
+	var (
+		e  = b.Params.Entry.Params
+		tp = b.targetParams()
+		sp = b.stopLossParams()
+	)
+
+	limit := sp.TriggerPrice
+	if sp.OrderType == OrderTypeSL {
+		limit = sp.Price
+	}
+
+	var (
+		qty    = b.Quantity
+		target = TriggerParams{TriggerValue: tp.Price, LimitPrice: tp.Price, Quantity: qty}
+		stop   = TriggerParams{TriggerValue: sp.TriggerPrice, LimitPrice: limit, Quantity: qty}
+		oco    = &GTTOneCancelsOtherTrigger{Upper: target, Lower: stop}
+	)
+	if e.TransactionType == TransactionTypeSell {
+		oco.Upper, oco.Lower = stop, target
+	}
+
+	resp, err := m.c.PlaceGTT(GTTParams{
+		Tradingsymbol:   e.Tradingsymbol,
+		Exchange:        e.Exchange,
+		LastPrice:       b.EntryPrice,
+		TransactionType: tp.TransactionType,
+		Product:         e.Product,
+		Trigger:         oco,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.update(b, func() { b.GTTID = resp.TriggerID })
+	return nil
+}
+
+// exitUpdate records the fills of an exit and closes the bracket once one of them is filled.
+func (m *BracketManager) exitUpdate(b *bracket, o Order) {
+	cur := m.snapshot(b)
+	if cur.Done() {
+		return
+	}
+
+	isTarget := o.OrderID == cur.TargetOrderID
+	if !isTarget && o.OrderID != cur.StopLossOrderID {
+		// An earlier stoploss which was replaced to flatten the position.
+		return
+	}
+
+	m.update(b, func() {
+		if isTarget {
+			b.targetFilled, b.targetPrice = o.FilledQuantity, o.AveragePrice
+		} else {
+			b.slFilled, b.slPrice = o.FilledQuantity, o.AveragePrice
+		}
+		b.ExitedQuantity = b.targetFilled + b.slFilled
+		if b.ExitedQuantity > 0 {
+			b.ExitPrice = (b.targetFilled*b.targetPrice + b.slFilled*b.slPrice) / b.ExitedQuantity
+		}
+	})
+
+	switch {
+	case o.Status == OrderStatusComplete:
+		var err error
+		if !b.Params.UseGTT {
+			// The other exit is cancelled as the position is closed.
+			err = m.cancelExits(m.snapshot(b), !isTarget, isTarget)
+		}
+		m.update(b, func() {
+			switch {
+			case isTarget:
+				b.State = BracketTargetHit
+			case b.State == BracketFlattening:
+				b.State = BracketFlattened
+			default:
+				b.State = BracketStoppedOut
+			}
+			b.Err = err
+		})
+
+	case IsTerminalStatus(o.Status):
+		err := NewError(OrderError, fmt.Sprintf("Exit order %s is %s: %s", o.OrderID, o.Status, o.StatusMessage), nil)
+		switch {
+		case isTarget && cur.State == BracketFlattening:
+			// The target was cancelled to flatten the position.
+		case isTarget:
+			m.update(b, func() { b.Err = err })
+		case cur.State == BracketFlattening:
+			m.update(b, func() { b.State, b.Err = BracketUnprotected, err })
+		default:
+			// The position isn't protected anymore.
+			m.stopLossFailed(b, err)
+		}
+
+	case o.FilledQuantity > 0 && !b.Params.UseGTT && cur.State == BracketOpen:
+		// The other exit is reduced to the quantity which is still open.
+		cur = m.snapshot(b)
+		other, params := cur.StopLossOrderID, b.stopLossParams()
+		if !isTarget {
+			other, params = cur.TargetOrderID, b.targetParams()
+		}
+		params.Quantity = int(cur.Quantity - cur.ExitedQuantity)
+
+		if other == "" || params.Quantity <= 0 {
+			return
+		}
+		_, err := m.c.ModifyOrder(VarietyRegular, other, params)
+		m.update(b, func() { b.Err = err })
+	}
+}
+
+// cancelExits cancels the target and/or the stoploss of a bracket, or deletes its GTT.
+func (m *BracketManager) cancelExits(b Bracket, target, stopLoss bool) error {
+	if b.Params.UseGTT {
+		if b.GTTID == 0 {
+			return nil
+		}
+		_, err := m.c.DeleteGTT(b.GTTID)
+		return err
+	}
+
+	if target && b.TargetOrderID != "" {
+		if _, err := m.c.CancelOrder(VarietyRegular, b.TargetOrderID, nil); err != nil {
+			return err
+		}
+	}
+	if stopLoss && b.StopLossOrderID != "" {
+		if _, err := m.c.CancelOrder(VarietyRegular, b.StopLossOrderID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue queues work for a bracket and starts its goroutine if it isn't
+// running. It must be called with the lock held.
+func (m *BracketManager) enqueue(b *bracket, f func()) {
+	m.jobs.Add(1)
+	b.queue = append(b.queue, f)
+	if !b.working {
+		b.working = true
+		go m.work(b)
+	}
+}
+
+// work does the queued work of a bracket till the queue is empty.
+func (m *BracketManager) work(b *bracket) {
+	for {
+		m.mu.Lock()
+		if len(b.queue) == 0 {
+			b.working = false
+			m.mu.Unlock()
+			return
+		}
+		f := b.queue[0]
+		b.queue = b.queue[1:]
+		m.mu.Unlock()
+
+		f()
+		m.jobs.Done()
+	}
+}
+
+// snapshot returns the current state of a bracket.
+func (m *BracketManager) snapshot(b *bracket) Bracket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return b.Bracket
+}
+
+// track records an exit order of a bracket.
+func (m *BracketManager) track(b *bracket, orderID string, f func()) {
+	m.mu.Lock()
+	m.orders[orderID] = b
+	m.mu.Unlock()
+	m.update(b, f)
+}
+
+// update applies a change to a bracket and calls the update callback.
+func (m *BracketManager) update(b *bracket, f func()) {
+	m.mu.Lock()
+	f()
+	state, cb := b.Bracket, m.onUpdate
+	m.mu.Unlock()
+
+	if cb != nil {
+		cb(state)
+	}
+}
@@ -0,0 +1,388 @@
+package kiteconnect
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func newBracketManager(t *testing.T) (*exchangeServer, *BracketManager) {
+	s, c := newExchangeServer(t)
+	m := NewBracketManager(c)
+	m.retryInterval = time.Millisecond
+	return s, m
+}
+
+// orderUpdates feeds order updates to the manager and waits till they're handled.
+func orderUpdates(m *BracketManager, orders ...Order) {
+	for _, o := range orders {
+		m.OnOrderUpdate(o)
+	}
+	m.jobs.Wait()
+}
+
+func bracketState(t *testing.T, m *BracketManager, entryOrderID string) Bracket {
+	b, ok := m.Bracket(entryOrderID)
+	require.True(t, ok)
+	return b
+}
+
+// longBracket places a bracket buying 10 OPEN at 100 with the target at 110
+// and the stoploss at 95. OPEN orders stay open at the exchange while their
+// updates are fed by the tests.
+func longBracket(t *testing.T, m *BracketManager, p BracketParams) Bracket {
+	p.Entry = basketLeg(t, NewOrder("NSE:OPEN").Buy(10).Limit(100).Product(ProductMIS))
+	p.Target, p.StopLoss = 10, 5
+
+	b, err := m.Place(p)
+	require.NoError(t, err)
+	return b
+}
+
+func TestBracketTargetHit(t *testing.T) {
+	t.Parallel()
+
+	s, m := newBracketManager(t)
+
+	var states []BracketState
+	m.OnUpdate(func(b Bracket) {
+		if len(states) == 0 || states[len(states)-1] != b.State {
+			states = append(states, b.State)
+		}
+	})
+
+	b, err := m.Place(BracketParams{
+		Entry:           basketLeg(t, NewOrder("NSE:OPEN").Buy(10).Limit(100).Product(ProductMIS).Tag("bkt")),
+		InstrumentToken: 1,
+		Target:          10,
+		StopLoss:        5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, BracketPending, b.State)
+
+	// Exits aren't placed till the entry is filled.
+	orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusOpen, FilledQuantity: 4, AveragePrice: 100})
+	require.Len(t, s.placed, 1)
+
+	orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+	require.Len(t, s.placed, 3)
+	target, sl := s.placed[1], s.placed[2]
+	require.Equal(t, TransactionTypeSell, target["transaction_type"])
+	require.Equal(t, OrderTypeLimit, target["order_type"])
+	require.Equal(t, 110.0, target["price"])
+	require.Equal(t, 10, target["quantity"])
+	require.Equal(t, "bkt", target["tag"])
+	require.Equal(t, OrderTypeSLM, sl["order_type"])
+	require.Equal(t, 95.0, sl["trigger_price"])
+
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, BracketOpen, b.State)
+	require.Equal(t, target["order_id"], b.TargetOrderID)
+	require.Equal(t, sl["order_id"], b.StopLossOrderID)
+
+	m.OnTick(models.Tick{InstrumentToken: 1, LastPrice: 105})
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, 50.0, b.PnL())
+
+	// A partial fill of the target reduces the stoploss.
+	orderUpdates(m, Order{OrderID: b.TargetOrderID, Status: OrderStatusOpen, FilledQuantity: 4, AveragePrice: 110})
+	require.Len(t, s.modified, 1)
+	require.Equal(t, b.StopLossOrderID, s.modified[0]["order_id"])
+	require.Equal(t, 6, s.modified[0]["quantity"])
+	require.Equal(t, 95.0, s.modified[0]["trigger_price"])
+
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, 4*10.0+6*5.0, b.PnL())
+
+	// The stoploss is cancelled once the target is filled.
+	orderUpdates(m, Order{OrderID: b.TargetOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 110})
+	require.Equal(t, []string{b.StopLossOrderID}, s.cancelled)
+
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, BracketTargetHit, b.State)
+	require.True(t, b.Done())
+	require.NoError(t, b.Err)
+	require.Equal(t, 100.0, b.PnL())
+	require.Equal(t, []BracketState{BracketPlacingExits, BracketOpen, BracketTargetHit}, states)
+
+	// Updates of done brackets are ignored.
+	orderUpdates(m, Order{OrderID: b.StopLossOrderID, Status: OrderStatusCancelled})
+	require.Equal(t, BracketTargetHit, bracketState(t, m, b.EntryOrderID).State)
+}
+
+func TestBracketStoppedOut(t *testing.T) {
+	t.Parallel()
+
+	s, m := newBracketManager(t)
+
+	b, err := m.Place(BracketParams{
+		Entry:        basketLeg(t, NewOrder("NSE:OPEN").Sell(10).Market().Product(ProductMIS)),
+		Target:       10,
+		StopLoss:     5,
+		StopLossType: OrderTypeSL,
+		LimitOffset:  1,
+	})
+	require.NoError(t, err)
+
+	// Exits are placed for the quantity filled before the entry was cancelled.
+	orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusCancelled, FilledQuantity: 4, AveragePrice: 200})
+	target, sl := s.placed[1], s.placed[2]
+	require.Equal(t, TransactionTypeBuy, target["transaction_type"])
+	require.Equal(t, 190.0, target["price"])
+	require.Equal(t, 4, target["quantity"])
+	require.Equal(t, OrderTypeSL, sl["order_type"])
+	require.Equal(t, 205.0, sl["trigger_price"])
+	require.Equal(t, 206.0, sl["price"])
+
+	b = bracketState(t, m, b.EntryOrderID)
+	orderUpdates(m, Order{OrderID: b.StopLossOrderID, Status: OrderStatusComplete, FilledQuantity: 4, AveragePrice: 205.5})
+	require.Equal(t, []string{b.TargetOrderID}, s.cancelled)
+
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, BracketStoppedOut, b.State)
+	require.Equal(t, -22.0, b.PnL())
+}
+
+func TestBracketRepeatedEntryUpdates(t *testing.T) {
+	t.Parallel()
+
+	s, m := newBracketManager(t)
+	b := longBracket(t, m, BracketParams{})
+
+	// The exits are placed once though the fill is received more than once.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.OnOrderUpdate(Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		}()
+	}
+	wg.Wait()
+	m.jobs.Wait()
+
+	require.Len(t, s.placed, 3)
+	require.Equal(t, BracketOpen, bracketState(t, m, b.EntryOrderID).State)
+}
+
+func TestBracketEntry(t *testing.T) {
+	t.Parallel()
+
+	s, m := newBracketManager(t)
+	entry := basketLeg(t, NewOrder("NSE:OPEN").Buy(10).Limit(100).Product(ProductMIS))
+
+	// Rejected entries cancel the bracket.
+	b, err := m.Place(BracketParams{Entry: entry, Target: 10, StopLoss: 5})
+	require.NoError(t, err)
+	orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusRejected, StatusMessage: "Insufficient funds"})
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, BracketCancelled, b.State)
+	require.Error(t, b.Err)
+
+	// Pending brackets are cancelled with their entry.
+	b, err = m.Place(BracketParams{Entry: entry, Target: 10, StopLoss: 5})
+	require.NoError(t, err)
+	require.NoError(t, m.Cancel(b.EntryOrderID))
+	require.Equal(t, []string{b.EntryOrderID}, s.cancelled)
+	require.Equal(t, BracketCancelled, bracketState(t, m, b.EntryOrderID).State)
+	require.Len(t, m.Brackets(), 2)
+
+	for _, p := range []BracketParams{
+		{Entry: entry, StopLoss: 5},
+		{Entry: OrderRequest{}, Target: 10, StopLoss: 5},
+		{Entry: entry, Target: 10, StopLoss: 5, StopLossType: OrderTypeLimit},
+	} {
+		_, err := m.Place(p)
+		require.Error(t, err)
+		require.Equal(t, InputError, err.(Error).ErrorType)
+	}
+	require.Error(t, m.Cancel("unknown"))
+}
+
+func TestBracketExitFailures(t *testing.T) {
+	t.Parallel()
+
+	var (
+		unavailable = newError(NetworkError, "Service unavailable", http.StatusServiceUnavailable, nil)
+		invalid     = newError(InputError, "Invalid trigger price", http.StatusBadRequest, nil)
+	)
+
+	t.Run("target", func(t *testing.T) {
+		t.Parallel()
+
+		// A failed target doesn't stop the stoploss from being placed.
+		s, m := newBracketManager(t)
+		b := longBracket(t, m, BracketParams{})
+		s.placeErrors = map[string][]Error{OrderTypeLimit: {invalid}}
+
+		orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketOpen, b.State)
+		require.Error(t, b.Err)
+		require.Empty(t, b.TargetOrderID)
+		require.NotEmpty(t, b.StopLossOrderID)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		t.Parallel()
+
+		// Network and server errors of the stoploss are retried.
+		s, m := newBracketManager(t)
+		s.placeErrors = map[string][]Error{OrderTypeSLM: {unavailable, unavailable}}
+		b := longBracket(t, m, BracketParams{})
+
+		orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketOpen, b.State)
+		require.NoError(t, b.Err)
+		require.Len(t, s.placed, 3)
+		require.Equal(t, s.placed[2]["order_id"], b.StopLossOrderID)
+	})
+
+	t.Run("placed", func(t *testing.T) {
+		t.Parallel()
+
+		// A stoploss placed by a request which failed isn't placed again.
+		s, m := newBracketManager(t)
+		b := longBracket(t, m, BracketParams{})
+		s.seedOrders(map[string]interface{}{
+			"exchange": "NSE", "tradingsymbol": "OPEN", "transaction_type": "SELL", "order_type": "SL-M",
+			"quantity": 10, "trigger_price": 95, "status": OrderStatusTriggerPending,
+		})
+		s.placeErrors = map[string][]Error{OrderTypeSLM: {unavailable}}
+
+		orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketOpen, b.State)
+		require.Equal(t, "2", b.StopLossOrderID)
+		require.Len(t, s.placed, 2)
+	})
+
+	t.Run("unprotected", func(t *testing.T) {
+		t.Parallel()
+
+		s, m := newBracketManager(t)
+		s.placeErrors = map[string][]Error{OrderTypeSLM: {unavailable, unavailable, unavailable}}
+		b := longBracket(t, m, BracketParams{})
+
+		orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketUnprotected, b.State)
+		require.Equal(t, NetworkError, b.Err.(Error).ErrorType)
+		require.False(t, b.Done())
+
+		// The target still closes the bracket.
+		orderUpdates(m, Order{OrderID: b.TargetOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 110})
+		require.Equal(t, BracketTargetHit, bracketState(t, m, b.EntryOrderID).State)
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		t.Parallel()
+
+		s, m := newBracketManager(t)
+		s.placeErrors = map[string][]Error{OrderTypeSLM: {invalid}}
+		b := longBracket(t, m, BracketParams{StopLossFailure: BracketFlatten})
+
+		// The target is cancelled and the position is exited at the market.
+		orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketFlattening, b.State)
+		require.Equal(t, []string{b.TargetOrderID}, s.cancelled)
+		require.Len(t, s.placed, 3)
+		require.Equal(t, OrderTypeMarket, s.placed[2]["order_type"])
+		require.Equal(t, TransactionTypeSell, s.placed[2]["transaction_type"])
+		require.Equal(t, 10, s.placed[2]["quantity"])
+		require.Equal(t, s.placed[2]["order_id"], b.StopLossOrderID)
+
+		orderUpdates(m,
+			Order{OrderID: b.TargetOrderID, Status: OrderStatusCancelled},
+			Order{OrderID: b.StopLossOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 99},
+		)
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketFlattened, b.State)
+		require.True(t, b.Done())
+		require.Equal(t, -10.0, b.PnL())
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		t.Parallel()
+
+		// A stoploss rejected after it's placed is handled as per the policy too.
+		s, m := newBracketManager(t)
+		b := longBracket(t, m, BracketParams{StopLossFailure: BracketFlatten})
+
+		orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+		b = bracketState(t, m, b.EntryOrderID)
+		orderUpdates(m, Order{OrderID: b.StopLossOrderID, Status: OrderStatusRejected, StatusMessage: "Trigger price breached"})
+
+		b = bracketState(t, m, b.EntryOrderID)
+		require.Equal(t, BracketFlattening, b.State)
+		require.Len(t, s.placed, 4)
+		require.Equal(t, OrderTypeMarket, s.placed[3]["order_type"])
+	})
+}
+
+func TestBracketGTT(t *testing.T) {
+	t.Parallel()
+
+	s, m := newBracketManager(t)
+
+	b, err := m.Place(BracketParams{
+		Entry:    basketLeg(t, NewOrder("NSE:OPEN").Buy(10).Limit(100).Product(ProductCNC)),
+		Target:   10,
+		StopLoss: 5,
+		UseGTT:   true,
+	})
+	require.NoError(t, err)
+
+	orderUpdates(m, Order{OrderID: b.EntryOrderID, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 100})
+	require.Len(t, s.placed, 1)
+	require.Len(t, s.gtts, 1)
+	require.Equal(t, string(GTTTypeOCO), s.gtts[0].Get("type"))
+
+	var condition GTTCondition
+	require.NoError(t, json.Unmarshal([]byte(s.gtts[0].Get("condition")), &condition))
+	require.Equal(t, []float64{95, 110}, condition.TriggerValues)
+
+	var orders []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s.gtts[0].Get("orders")), &orders))
+	require.Equal(t, TransactionTypeSell, orders[0]["transaction_type"])
+	require.Equal(t, 95.0, orders[0]["price"])
+	require.Equal(t, 110.0, orders[1]["price"])
+
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, BracketOpen, b.State)
+	require.Equal(t, 1, b.GTTID)
+
+	// Orders which weren't placed by the GTT aren't matched, even if they're
+	// of the instrument on the exit's side.
+	orderUpdates(m,
+		Order{OrderID: "x", Exchange: ExchangeNSE, TradingSymbol: "INFY", TransactionType: TransactionTypeSell, Price: 110, Status: OrderStatusComplete},
+		Order{OrderID: "y", Exchange: ExchangeNSE, TradingSymbol: "OPEN", TransactionType: TransactionTypeBuy, Price: 110, Status: OrderStatusComplete},
+		Order{OrderID: "z", Exchange: ExchangeNSE, TradingSymbol: "OPEN", TransactionType: TransactionTypeSell, Price: 110, Status: OrderStatusComplete},
+	)
+	b = bracketState(t, m, b.EntryOrderID)
+	require.Equal(t, BracketOpen, b.State)
+	require.Empty(t, b.TargetOrderID)
+
+	// The order placed by the GTT's target closes the bracket.
+	s.mu.Lock()
+	s.triggered = map[int][]string{1: {"", "g1"}}
+	s.mu.Unlock()
+	orderUpdates(m,
+		Order{OrderID: "g1", Exchange: ExchangeNSE, TradingSymbol: "OPEN", TransactionType: TransactionTypeSell, Status: OrderStatusOpen},
+		Order{OrderID: "g1", Exchange: ExchangeNSE, TradingSymbol: "OPEN", TransactionType: TransactionTypeSell, Status: OrderStatusComplete, FilledQuantity: 10, AveragePrice: 110},
+	)
+	b = bracketState(t, m, b.EntryOrderID)
+	require.NoError(t, b.Err)
+	require.Equal(t, BracketTargetHit, b.State)
+	require.Equal(t, "g1", b.TargetOrderID)
+	require.Equal(t, 100.0, b.PnL())
+	require.Empty(t, s.cancelled)
+	require.Empty(t, s.deleted)
+}
@@ -0,0 +1,333 @@
+package kiteconnect
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Limits of the number of legs of an iceberg order.
+	icebergMinLegs = 2
+	icebergMaxLegs = 50
+)
+
+// OrderBuilder builds the parameters of an order, picking the variety from the
+// options used and validating their combination when the order is built, eg:
+//
+//	req, err := kiteconnect.NewOrder("NSE:INFY").Buy(10).Limit(1500).
+//		Product(kiteconnect.ProductMIS).Iceberg(5, 2).Tag("strat1").Build()
+//	resp, err := kc.PlaceOrder(req.Variety, req.Params)
+type OrderBuilder struct {
+	params OrderParams
+
+	amo     bool
+	iceberg bool
+	auction bool
+	cover   bool
+
+	coverTrigger float64
+}
+
+// OrderRequest is a built order, ready to be placed.
+type OrderRequest struct {
+	Variety Variety
+	Params  OrderParams
+}
+
+// NewOrder starts building an order for an instrument in the exchange:tradingsymbol
+// format, eg: NSE:INFY.
+func NewOrder(instrument string) *OrderBuilder {
+	b := &OrderBuilder{}
+	if i := strings.Index(instrument, ":"); i > 0 {
+		b.params.Exchange = Exchange(instrument[:i])
+		b.params.Tradingsymbol = instrument[i+1:]
+	}
+	return b
+}
+
+// Buy sets the order to buy a quantity.
+func (b *OrderBuilder) Buy(quantity int) *OrderBuilder {
+	b.params.TransactionType = TransactionTypeBuy
+	b.params.Quantity = quantity
+	return b
+}
+
+// Sell sets the order to sell a quantity.
+func (b *OrderBuilder) Sell(quantity int) *OrderBuilder {
+	b.params.TransactionType = TransactionTypeSell
+	b.params.Quantity = quantity
+	return b
+}
+
+// Market sets the order type to MARKET.
+func (b *OrderBuilder) Market() *OrderBuilder {
+	b.params.OrderType = OrderTypeMarket
+	b.params.Price, b.params.TriggerPrice = 0, 0
+	return b
+}
+
+// Limit sets the order type to LIMIT at a price.
+func (b *OrderBuilder) Limit(price float64) *OrderBuilder {
+	b.params.OrderType = OrderTypeLimit
+	b.params.Price, b.params.TriggerPrice = price, 0
+	return b
+}
+
+// StopLoss sets the order type to SL, which is placed as a LIMIT order at
+// the price once the trigger price is hit.
+func (b *OrderBuilder) StopLoss(triggerPrice, price float64) *OrderBuilder {
+	b.params.OrderType = OrderTypeSL
+	b.params.Price, b.params.TriggerPrice = price, triggerPrice
+	return b
+}
+
+// StopLossMarket sets the order type to SL-M, which is placed as a MARKET
+// order once the trigger price is hit.
+func (b *OrderBuilder) StopLossMarket(triggerPrice float64) *OrderBuilder {
+	b.params.OrderType = OrderTypeSLM
+	b.params.Price, b.params.TriggerPrice = 0, triggerPrice
+	return b
+}
+
+// Product sets the product of the order.
+func (b *OrderBuilder) Product(product Product) *OrderBuilder {
+	b.params.Product = product
+	return b
+}
+
+// Day sets the validity of the order to DAY, which is the default.
+func (b *OrderBuilder) Day() *OrderBuilder {
+	b.params.Validity, b.params.ValidityTTL = ValidityDay, 0
+	return b
+}
+
+// IOC sets the validity of the order to IOC.
+func (b *OrderBuilder) IOC() *OrderBuilder {
+	b.params.Validity, b.params.ValidityTTL = ValidityIOC, 0
+	return b
+}
+
+// TTL sets the validity of the order to a number of minutes.
+func (b *OrderBuilder) TTL(minutes int) *OrderBuilder {
+	b.params.Validity, b.params.ValidityTTL = ValidityTTL, minutes
+	return b
+}
+
+// DisclosedQuantity sets the quantity disclosed to the market.
+func (b *OrderBuilder) DisclosedQuantity(quantity int) *OrderBuilder {
+	b.params.DisclosedQuantity = quantity
+	return b
+}
+
+// MarketProtection sets the market protection percentage of MARKET and SL-M
+// orders, or MarketProtectionAuto.
+func (b *OrderBuilder) MarketProtection(protection float64) *OrderBuilder {
+	b.params.MarketProtection = protection
+	return b
+}
+
+// Autoslice splits the order into multiple orders if its quantity is
+// above the freeze quantity of the instrument.
+func (b *OrderBuilder) Autoslice() *OrderBuilder {
+	b.params.Autoslice = true
+	return b
+}
+
+// AMO places the order as an after market order.
+func (b *OrderBuilder) AMO() *OrderBuilder {
+	b.amo = true
+	return b
+}
+
+// Iceberg places the order as an iceberg order of a number of legs of a quantity each.
+func (b *OrderBuilder) Iceberg(legs, quantity int) *OrderBuilder {
+	b.iceberg = true
+	b.params.IcebergLegs, b.params.IcebergQty = legs, quantity
+	return b
+}
+
+// Auction places the order in an auction.
+func (b *OrderBuilder) Auction(auctionNumber string) *OrderBuilder {
+	b.auction = true
+	b.params.AuctionNumber = auctionNumber
+	return b
+}
+
+// Cover places the order as a cover order with a stoploss at the trigger price.
+// The product defaults to CO.
+func (b *OrderBuilder) Cover(triggerPrice float64) *OrderBuilder {
+	b.cover = true
+	b.coverTrigger = triggerPrice
+	return b
+}
+
+// Tag sets the tag of the order.
+func (b *OrderBuilder) Tag(tag string) *OrderBuilder {
+	b.params.Tag = tag
+	return b
+}
+
+// Build validates the order and returns its variety and parameters.
+func (b *OrderBuilder) Build() (OrderRequest, error) {
+	var (
+		p       = b.params
+		variety = Variety(VarietyRegular)
+		n       int
+	)
+
+	for _, v := range []struct {
+		set     bool
+		variety Variety
+	}{
+		{b.amo, VarietyAMO},
+		{b.iceberg, VarietyIceberg},
+		{b.auction, VarietyAuction},
+		{b.cover, VarietyCO},
+	} {
+		if v.set {
+			variety = v.variety
+			n++
+		}
+	}
+	if n > 1 {
+		return OrderRequest{}, builderError("only one of AMO, iceberg, auction and cover can be used")
+	}
+
+	if p.Validity == "" {
+		p.Validity = ValidityDay
+	}
+	if b.cover {
+		p.TriggerPrice = b.coverTrigger
+		if p.Product == "" {
+			p.Product = ProductCO
+		}
+	}
+
+	if err := validateOrder(variety, p); err != nil {
+		return OrderRequest{}, err
+	}
+
+	return OrderRequest{Variety: variety, Params: p}, nil
+}
+
+// Place builds the order and places it.
+func (b *OrderBuilder) Place(c *Client) (OrderResponse, error) {
+	req, err := b.Build()
+	if err != nil {
+		return OrderResponse{}, err
+	}
+	return c.PlaceOrder(req.Variety, req.Params)
+}
+
+// MarginParam returns the parameters to calculate the margins of the order.
+func (r OrderRequest) MarginParam() OrderMarginParam {
+	return OrderMarginParam{
+		Exchange:        r.Params.Exchange,
+		Tradingsymbol:   r.Params.Tradingsymbol,
+		TransactionType: r.Params.TransactionType,
+		Variety:         r.Variety,
+		Product:         r.Params.Product,
+		OrderType:       r.Params.OrderType,
+		Quantity:        float64(r.Params.Quantity),
+		Price:           r.Params.Price,
+		TriggerPrice:    r.Params.TriggerPrice,
+	}
+}
+
+// validateOrder checks the combination of an order's variety and parameters.
+func validateOrder(variety Variety, p OrderParams) error {
+	switch {
+	case p.Exchange == "" || p.Tradingsymbol == "":
+		return builderError("instrument should be in the exchange:tradingsymbol format")
+	case !p.TransactionType.Valid():
+		return builderError("transaction type is required, use Buy or Sell")
+	case p.Quantity <= 0:
+		return builderError("quantity should be positive")
+	case !p.OrderType.Valid():
+		return builderError("order type is required, use Market, Limit, StopLoss or StopLossMarket")
+	case !p.Product.Valid():
+		return builderError(fmt.Sprintf("invalid product: %q", p.Product))
+	}
+
+	switch p.OrderType {
+	case OrderTypeLimit:
+		if p.Price <= 0 {
+			return builderError("price of a LIMIT order should be positive")
+		}
+	case OrderTypeSL:
+		if p.Price <= 0 || p.TriggerPrice <= 0 {
+			return builderError("price and trigger price of an SL order should be positive")
+		}
+	case OrderTypeSLM:
+		if p.TriggerPrice <= 0 {
+			return builderError("trigger price of an SL-M order should be positive")
+		}
+	}
+
+	if p.Validity == ValidityTTL && p.ValidityTTL <= 0 {
+		return builderError("TTL validity should be a positive number of minutes")
+	}
+	if p.DisclosedQuantity < 0 || p.DisclosedQuantity > p.Quantity {
+		return builderError("disclosed quantity should be between zero and the quantity")
+	}
+	if p.MarketProtection != 0 && p.MarketProtection != MarketProtectionAuto &&
+		(p.MarketProtection < 0 || p.MarketProtection > 100) {
+		return builderError("market protection should be a percentage or MarketProtectionAuto")
+	}
+	if p.MarketProtection != 0 && p.OrderType != OrderTypeMarket && p.OrderType != OrderTypeSLM {
+		return builderError("market protection applies only to MARKET and SL-M orders")
+	}
+
+	if p.Product == ProductMTF {
+		if p.Exchange != ExchangeNSE && p.Exchange != ExchangeBSE {
+			return builderError("MTF product is only available on NSE and BSE")
+		}
+	}
+	if (p.Product == ProductCO) != (variety == VarietyCO) {
+		return builderError("CO product should be used with cover orders only")
+	}
+	if p.Product == ProductBO {
+		return builderError("BO product isn't supported")
+	}
+
+	switch variety {
+	case VarietyIceberg:
+		if p.IcebergLegs < icebergMinLegs || p.IcebergLegs > icebergMaxLegs {
+			return builderError(fmt.Sprintf("iceberg legs should be between %d and %d", icebergMinLegs, icebergMaxLegs))
+		}
+		if p.IcebergQty <= 0 || p.IcebergQty*p.IcebergLegs < p.Quantity {
+			return builderError("iceberg legs of the iceberg quantity should add up to the quantity")
+		}
+		if p.Validity == ValidityIOC {
+			return builderError("iceberg orders can't be IOC")
+		}
+		if p.Autoslice {
+			return builderError("iceberg orders can't be autosliced")
+		}
+
+	case VarietyAuction:
+		if p.AuctionNumber == "" {
+			return builderError("auction number is required")
+		}
+		if p.OrderType != OrderTypeLimit || p.Product != ProductCNC {
+			return builderError("auction orders should be CNC LIMIT orders")
+		}
+
+	case VarietyCO:
+		if p.OrderType != OrderTypeMarket && p.OrderType != OrderTypeLimit {
+			return builderError("cover orders should be MARKET or LIMIT orders")
+		}
+		if p.TriggerPrice <= 0 {
+			return builderError("stoploss trigger price of a cover order should be positive")
+		}
+		if p.Validity != ValidityDay {
+			return builderError("cover orders should have DAY validity")
+		}
+	}
+
+	return nil
+}
+
+func builderError(msg string) error {
+	return NewError(InputError, msg, nil)
+}
@@ -0,0 +1,98 @@
+package kiteconnect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderBuilder(t *testing.T) {
+	t.Parallel()
+
+	req, err := NewOrder("NSE:INFY").Buy(10).Limit(1500).Product(ProductMIS).Iceberg(5, 2).Tag("strat1").Build()
+	require.NoError(t, err)
+	require.Equal(t, Variety(VarietyIceberg), req.Variety)
+	require.Equal(t, OrderParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		Validity:        ValidityDay,
+		Product:         ProductMIS,
+		OrderType:       OrderTypeLimit,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        10,
+		Price:           1500,
+		IcebergLegs:     5,
+		IcebergQty:      2,
+		Tag:             "strat1",
+	}, req.Params)
+
+	require.Equal(t, OrderMarginParam{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		TransactionType: TransactionTypeBuy,
+		Variety:         VarietyIceberg,
+		Product:         ProductMIS,
+		OrderType:       OrderTypeLimit,
+		Quantity:        10,
+		Price:           1500,
+	}, req.MarginParam())
+
+	// Cover orders default to the CO product irrespective of the order of calls.
+	req, err = NewOrder("NSE:SBIN").Cover(590).Sell(1).Market().Build()
+	require.NoError(t, err)
+	require.Equal(t, Variety(VarietyCO), req.Variety)
+	require.Equal(t, Product(ProductCO), req.Params.Product)
+	require.Equal(t, 590.0, req.Params.TriggerPrice)
+
+	req, err = NewOrder("NFO:NIFTY24JANFUT").Sell(50).StopLossMarket(21000).Product(ProductNRML).TTL(5).MarketProtection(MarketProtectionAuto).Build()
+	require.NoError(t, err)
+	require.Equal(t, Variety(VarietyRegular), req.Variety)
+	require.Equal(t, Validity(ValidityTTL), req.Params.Validity)
+	require.Equal(t, 5, req.Params.ValidityTTL)
+
+	req, err = NewOrder("BSE:INFY").Sell(1).Limit(1500).Product(ProductCNC).Auction("22").Build()
+	require.NoError(t, err)
+	require.Equal(t, Variety(VarietyAuction), req.Variety)
+
+	req, err = NewOrder("NSE:INFY").Buy(1).Market().Product(ProductMTF).AMO().Build()
+	require.NoError(t, err)
+	require.Equal(t, Variety(VarietyAMO), req.Variety)
+}
+
+func TestOrderBuilderInvalid(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		order *OrderBuilder
+	}{
+		{"no exchange", NewOrder("INFY").Buy(1).Market().Product(ProductCNC)},
+		{"no side", NewOrder("NSE:INFY").Market().Product(ProductCNC)},
+		{"zero quantity", NewOrder("NSE:INFY").Buy(0).Market().Product(ProductCNC)},
+		{"no order type", NewOrder("NSE:INFY").Buy(1).Product(ProductCNC)},
+		{"no product", NewOrder("NSE:INFY").Buy(1).Market()},
+		{"zero limit price", NewOrder("NSE:INFY").Buy(1).Limit(0).Product(ProductCNC)},
+		{"sl without price", NewOrder("NSE:INFY").Buy(1).StopLoss(100, 0).Product(ProductCNC)},
+		{"ttl without minutes", NewOrder("NSE:INFY").Buy(1).Limit(1).Product(ProductCNC).TTL(0)},
+		{"disclosed above quantity", NewOrder("NSE:INFY").Buy(1).Limit(1).Product(ProductCNC).DisclosedQuantity(2)},
+		{"limit market protection", NewOrder("NSE:INFY").Buy(1).Limit(1).Product(ProductCNC).MarketProtection(2)},
+		{"mtf derivatives", NewOrder("NFO:NIFTY24JANFUT").Buy(50).Market().Product(ProductMTF)},
+		{"co product regular", NewOrder("NSE:INFY").Buy(1).Market().Product(ProductCO)},
+		{"two varieties", NewOrder("NSE:INFY").Buy(10).Limit(1).Product(ProductCNC).AMO().Iceberg(5, 2)},
+		{"iceberg one leg", NewOrder("NSE:INFY").Buy(10).Limit(1).Product(ProductCNC).Iceberg(1, 10)},
+		{"iceberg short", NewOrder("NSE:INFY").Buy(10).Limit(1).Product(ProductCNC).Iceberg(2, 4)},
+		{"iceberg ioc", NewOrder("NSE:INFY").Buy(10).Limit(1).Product(ProductCNC).Iceberg(5, 2).IOC()},
+		{"iceberg autoslice", NewOrder("NSE:INFY").Buy(10).Limit(1).Product(ProductCNC).Iceberg(5, 2).Autoslice()},
+		{"auction market", NewOrder("NSE:INFY").Sell(1).Market().Product(ProductCNC).Auction("1")},
+		{"auction number", NewOrder("NSE:INFY").Sell(1).Limit(1).Product(ProductCNC).Auction("")},
+		{"cover sl", NewOrder("NSE:INFY").Buy(1).StopLoss(1, 1).Cover(1)},
+		{"cover trigger", NewOrder("NSE:INFY").Buy(1).Market().Cover(0)},
+		{"cover ioc", NewOrder("NSE:INFY").Buy(1).Market().Cover(1).IOC()},
+	}
+
+	for _, tc := range tt {
+		_, err := tc.order.Build()
+		require.Error(t, err, tc.name)
+		require.Equal(t, InputError, err.(Error).ErrorType, tc.name)
+	}
+}
@@ -0,0 +1,220 @@
+package kiteconnect
+
+import (
+	"context"
+	"sync"
+)
+
+// bulkWorkers is the number of orders cancelled, exited or placed concurrently.
+const bulkWorkers = 5
+
+// OrderFilter selects orders by their fields. Empty fields match all orders
+// and an order has to match all of the non-empty ones.
+type OrderFilter struct {
+	Tags           []string
+	Exchanges      []Exchange
+	Products       []Product
+	Tradingsymbols []string
+	Varieties      []Variety
+}
+
+// Match returns true if the order matches the filter.
+func (f OrderFilter) Match(o Order) bool {
+	if len(f.Tags) > 0 && !matchTags(f.Tags, o) {
+		return false
+	}
+	if len(f.Tradingsymbols) > 0 && !containsString(f.Tradingsymbols, o.TradingSymbol) {
+		return false
+	}
+
+	if len(f.Exchanges) > 0 {
+		ok := false
+		for _, e := range f.Exchanges {
+			ok = ok || e == o.Exchange
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(f.Products) > 0 {
+		ok := false
+		for _, p := range f.Products {
+			ok = ok || p == o.Product
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(f.Varieties) > 0 {
+		ok := false
+		for _, v := range f.Varieties {
+			ok = ok || v == o.Variety
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchTags returns true if the order has any of the tags.
+func matchTags(tags []string, o Order) bool {
+	if containsString(tags, o.Tag) {
+		return true
+	}
+	for _, t := range o.Tags {
+		if containsString(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkResult is the result of cancelling or exiting multiple orders.
+type BulkResult struct {
+	Succeeded []BulkOrderResult
+	Failed    []BulkOrderResult
+}
+
+// BulkOrderResult is the result of cancelling or exiting an order.
+type BulkOrderResult struct {
+	Order    Order
+	Response OrderResponse
+	Err      error
+}
+
+// CancelAll cancels all the open orders which match the filter. Orders are
+// cancelled concurrently within the order rate limit. The stoploss legs of
+// cover and bracket orders are left as they are since cancelling them exits
+// the position, use ExitAll for that, and those whose parent order is being
+// cancelled are cancelled along with it. The error is returned only if the
+// orders couldn't be fetched or the context is done, while the errors of the
+// individual orders are in the result.
+func (c *Client) CancelAll(ctx context.Context, filter OrderFilter) (BulkResult, error) {
+	return c.bulkCancel(ctx, filter, false)
+}
+
+// ExitAll cancels all the open orders which match the filter, like CancelAll,
+// and also exits the positions of cover and bracket orders by exiting their
+// pending stoploss legs.
+func (c *Client) ExitAll(ctx context.Context, filter OrderFilter) (BulkResult, error) {
+	return c.bulkCancel(ctx, filter, true)
+}
+
+func (c *Client) bulkCancel(ctx context.Context, filter OrderFilter, exit bool) (BulkResult, error) {
+	orders, err := c.GetOrders()
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	targets := bulkTargets(orders, filter, exit)
+	results := make([]BulkOrderResult, len(targets))
+	runBulk(ctx, len(targets), func(i int) {
+		results[i] = c.cancelTarget(ctx, targets[i])
+	})
+
+	var out BulkResult
+	for i, r := range results {
+		if r.Order.OrderID == "" {
+			// Not attempted as the context was done.
+			continue
+		}
+		if r.Err != nil {
+			out.Failed = append(out.Failed, results[i])
+		} else {
+			out.Succeeded = append(out.Succeeded, results[i])
+		}
+	}
+
+	return out, ctx.Err()
+}
+
+// bulkTargets returns the open orders to be cancelled or exited.
+func bulkTargets(orders Orders, filter OrderFilter, exit bool) Orders {
+	cancelled := map[string]bool{}
+	for _, o := range orders {
+		if o.ParentOrderID == "" && !IsTerminalStatus(o.Status) && filter.Match(o) {
+			cancelled[o.OrderID] = true
+		}
+	}
+
+	var targets Orders
+	for _, o := range orders {
+		if IsTerminalStatus(o.Status) || !filter.Match(o) {
+			continue
+		}
+
+		if o.ParentOrderID != "" {
+			// Legs of an order being cancelled go along with it.
+			if !exit || cancelled[o.ParentOrderID] {
+				continue
+			}
+		}
+		targets = append(targets, o)
+	}
+	return targets
+}
+
+// cancelTarget cancels an order or exits the leg of a cover or bracket order.
+func (c *Client) cancelTarget(ctx context.Context, o Order) BulkOrderResult {
+	res := BulkOrderResult{Order: o}
+
+	if err := c.orderLimiter.wait(ctx); err != nil {
+		res.Err = err
+		return res
+	}
+
+	var parent *string
+	if o.ParentOrderID != "" {
+		p := o.ParentOrderID
+		parent = &p
+	}
+
+	variety := o.Variety
+	if variety == "" {
+		variety = VarietyRegular
+	}
+
+	if parent != nil {
+		res.Response, res.Err = c.ExitOrder(variety, o.OrderID, parent)
+	} else {
+		res.Response, res.Err = c.CancelOrder(variety, o.OrderID, nil)
+	}
+	return res
+}
+
+// runBulk calls f with the indices from 0 to n concurrently, stopping once the context is done.
+func runBulk(ctx context.Context, n int, f func(i int)) {
+	var (
+		wg   sync.WaitGroup
+		jobs = make(chan int)
+	)
+	for w := 0; w < bulkWorkers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package kiteconnect
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func bulkOrders() []map[string]interface{} {
+	return []map[string]interface{}{
+		// 1: Open regular order.
+		{"variety": "regular", "exchange": "NSE", "tradingsymbol": "INFY", "product": "MIS", "status": OrderStatusOpen, "tag": "strat1"},
+		// 2: Complete regular order.
+		{"variety": "regular", "exchange": "NSE", "tradingsymbol": "SBIN", "product": "MIS", "status": OrderStatusComplete},
+		// 3: Open cover order and 4: its stoploss leg.
+		{"variety": "co", "exchange": "NSE", "tradingsymbol": "TCS", "product": "CO", "status": OrderStatusOpen},
+		{"variety": "co", "exchange": "NSE", "tradingsymbol": "TCS", "product": "CO", "status": OrderStatusTriggerPending, "parent_order_id": "3"},
+		// 5: Complete cover order and 6: its stoploss leg.
+		{"variety": "co", "exchange": "NSE", "tradingsymbol": "ITC", "product": "CO", "status": OrderStatusComplete},
+		{"variety": "co", "exchange": "NSE", "tradingsymbol": "ITC", "product": "CO", "status": OrderStatusTriggerPending, "parent_order_id": "5"},
+		// 7: Open derivatives order.
+		{"variety": "regular", "exchange": "NFO", "tradingsymbol": "NIFTYFUT", "product": "NRML", "status": OrderStatusTriggerPending, "tags": []string{"hedge", "strat2"}},
+	}
+}
+
+func bulkOrderIDs(results []BulkOrderResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Order.OrderID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestCancelAll(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.seedOrders(bulkOrders()...)
+
+	res, err := c.CancelAll(context.Background(), OrderFilter{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "3", "7"}, bulkOrderIDs(res.Succeeded))
+	require.Empty(t, res.Failed)
+
+	sort.Strings(s.cancelled)
+	require.Equal(t, []string{"1", "3", "7"}, s.cancelled)
+}
+
+func TestExitAll(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.seedOrders(bulkOrders()...)
+
+	// The leg of the open cover order is cancelled along with it and the
+	// leg of the complete one is exited with its parent order id.
+	res, err := c.ExitAll(context.Background(), OrderFilter{Varieties: []Variety{VarietyCO}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"3", "6"}, bulkOrderIDs(res.Succeeded))
+
+	sort.Strings(s.cancelled)
+	require.Equal(t, []string{"3", "6:5"}, s.cancelled)
+}
+
+func TestBulkFilters(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		filter OrderFilter
+		ids    []string
+	}{
+		{"tag", OrderFilter{Tags: []string{"strat2"}}, []string{"7"}},
+		{"exchange", OrderFilter{Exchanges: []Exchange{ExchangeNSE}}, []string{"1", "3"}},
+		{"product", OrderFilter{Products: []Product{ProductMIS, ProductNRML}}, []string{"1", "7"}},
+		{"symbol", OrderFilter{Tradingsymbols: []string{"TCS"}}, []string{"3"}},
+		{"all fields", OrderFilter{Tags: []string{"strat1"}, Exchanges: []Exchange{ExchangeNFO}}, nil},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, c := newExchangeServer(t)
+			s.seedOrders(bulkOrders()...)
+
+			res, err := c.CancelAll(context.Background(), tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, tc.ids, bulkOrderIDs(res.Succeeded))
+		})
+	}
+}
+
+func TestBulkFailures(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.seedOrders(bulkOrders()...)
+	s.seedOrders(map[string]interface{}{"variety": "regular", "exchange": "NSE", "tradingsymbol": "LOCKED", "status": OrderStatusOpen})
+
+	res, err := c.CancelAll(context.Background(), OrderFilter{Exchanges: []Exchange{ExchangeNSE}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "3"}, bulkOrderIDs(res.Succeeded))
+	require.Equal(t, []string{"8"}, bulkOrderIDs(res.Failed))
+	require.Equal(t, OrderError, res.Failed[0].Err.(Error).ErrorType)
+
+	// Nothing is attempted once the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = c.CancelAll(ctx, OrderFilter{})
+	require.Equal(t, context.Canceled, err)
+	require.Empty(t, res.Succeeded)
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := newRateLimiter(50, 2)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	// The burst is immediate and the rest take 20ms each.
+	require.True(t, time.Since(start) >= 70*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, l.wait(ctx))
+}
@@ -0,0 +1,439 @@
+package candles
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+const (
+	// Mode of ticks which don't carry volume and OI.
+	modeLTP = "ltp"
+	// Mode of ticks which carry OI.
+	modeFull = "full"
+
+	// Default number of completed candles retained per instrument.
+	defaultMaxHistory = 500
+)
+
+// BuilderOptions represents the optional params for a candle Builder.
+type BuilderOptions struct {
+	// Session used to align candles. Defaults to NSESession.
+	Session Session
+
+	// Grace is the time a candle is kept open after its end for late and out
+	// of order ticks before it's emitted. Defaults to 0, which emits a candle
+	// as soon as a tick for a later candle is received.
+	Grace time.Duration
+
+	// ExtendedHours includes ticks outside the session open and close. By
+	// default such ticks, for example pre-open and post-close ticks, are ignored.
+	ExtendedHours bool
+
+	// MaxHistory is the number of completed candles retained per instrument
+	// and returned by History. Defaults to 500.
+	MaxHistory int
+
+	// Clock returns the current time. It's used to timestamp ticks which don't
+	// carry an exchange timestamp (LTP and quote modes). Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// Builder builds OHLC candles of a fixed interval from ticks of multiple
+// instruments. It's safe for concurrent use and its AddTick method can be
+// directly assigned as the ticker's OnTick callback.
+//
+// Per candle volume is derived from the cumulative day volume (VolumeTraded)
+// in quote and full mode ticks and OI is taken from full mode ticks.
+type Builder struct {
+	interval Interval
+	opt      BuilderOptions
+
+	mu       sync.Mutex
+	series   map[uint32]*series
+	onCandle func(token uint32, candle kiteconnect.HistoricalData)
+
+	lateTicks uint64
+}
+
+// series is the candle state of a single instrument.
+type series struct {
+	cur     *bar
+	pending *bar
+
+	// Cumulative volume seen so far on cumDay.
+	cumVolume uint32
+	cumDay    time.Time
+	hasCum    bool
+
+	lastOI  int
+	history []kiteconnect.HistoricalData
+}
+
+// bar is a candle which hasn't been emitted yet.
+type bar struct {
+	candle kiteconnect.HistoricalData
+	end    time.Time
+}
+
+// emit is a completed candle waiting to be sent to the callback.
+type emit struct {
+	token  uint32
+	candle kiteconnect.HistoricalData
+}
+
+// NewBuilder creates a new candle builder for the given interval. Only
+// intraday and single day intervals are supported.
+func NewBuilder(interval Interval, opt BuilderOptions) (*Builder, error) {
+	if err := interval.Valid(); err != nil {
+		return nil, err
+	}
+
+	if !interval.Intraday() && interval != Days(1) {
+		return nil, ErrInvalidInterval
+	}
+
+	if opt.Session == (Session{}) {
+		opt.Session = NSESession
+	}
+
+	if opt.MaxHistory <= 0 {
+		opt.MaxHistory = defaultMaxHistory
+	}
+
+	if opt.Clock == nil {
+		opt.Clock = time.Now
+	}
+
+	return &Builder{
+		interval: interval,
+		opt:      opt,
+		series:   map[uint32]*series{},
+	}, nil
+}
+
+// OnCandle sets the callback which is triggered for every completed candle.
+func (b *Builder) OnCandle(f func(token uint32, candle kiteconnect.HistoricalData)) {
+	b.mu.Lock()
+	b.onCandle = f
+	b.mu.Unlock()
+}
+
+// Interval returns the interval of candles built.
+func (b *Builder) Interval() Interval {
+	return b.interval
+}
+
+// LateTicks returns the number of ticks dropped as they were older than
+// the candles still open.
+func (b *Builder) LateTicks() uint64 {
+	return atomic.LoadUint64(&b.lateTicks)
+}
+
+// AddTick adds a tick to the candle of its instrument.
+func (b *Builder) AddTick(tick models.Tick) {
+	ts := b.tickTime(tick)
+	if !b.opt.ExtendedHours && !b.opt.Session.Contains(ts) {
+		return
+	}
+
+	start := b.bucketStart(ts)
+
+	b.mu.Lock()
+	s := b.getSeries(tick.InstrumentToken)
+	vol := s.volumeDelta(tick, b.opt.Session.Day(ts))
+	if tick.Mode == modeFull {
+		s.lastOI = int(tick.OI)
+	}
+
+	// Candles which are past their grace period are complete.
+	out := b.expire(nil, tick.InstrumentToken, s, ts)
+
+	switch {
+	case s.cur == nil || start.After(s.cur.candle.Date.Time):
+		if s.cur != nil {
+			if s.pending != nil {
+				out = b.complete(out, tick.InstrumentToken, s, s.pending)
+			}
+			s.pending = s.cur
+		}
+		s.cur = b.newBar(start, tick.LastPrice, vol, s.lastOI)
+
+	case start.Equal(s.cur.candle.Date.Time):
+		s.cur.add(tick.LastPrice, vol, s.lastOI)
+
+	case s.pending != nil && start.Equal(s.pending.candle.Date.Time):
+		s.pending.add(tick.LastPrice, vol, s.lastOI)
+
+	default:
+		atomic.AddUint64(&b.lateTicks, 1)
+	}
+
+	b.mu.Unlock()
+	b.trigger(out)
+}
+
+// Seed warms up an instrument with historical candles, typically fetched with
+// GetHistoricalData, which are resampled to the builder's interval. Completed
+// candles are added to the history and if the last candle belongs to the
+// current interval it's continued with the ticks received thereafter. It should
+// be called before ticks for the instrument are added.
+func (b *Builder) Seed(token uint32, data []kiteconnect.HistoricalData) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	candles, err := Resample(data, b.interval, Options{Session: b.opt.Session})
+	if err != nil {
+		return err
+	}
+
+	var (
+		now   = b.opt.Clock()
+		today = b.opt.Session.Day(now)
+		last  = data[len(data)-1]
+	)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.getSeries(token)
+	s.cur, s.pending = nil, nil
+	s.lastOI = last.OI
+
+	if c := candles[len(candles)-1]; c.Date.Equal(b.bucketStart(now)) {
+		s.cur = &bar{candle: c, end: b.bucketEnd(c.Date.Time)}
+		candles = candles[:len(candles)-1]
+	}
+	s.history = append(s.history, candles...)
+	s.trim(b.opt.MaxHistory)
+
+	// Use the volume traded today as the baseline for the cumulative volume
+	// in ticks so that volume between the candles fetched and the first tick
+	// isn't lost.
+	if b.opt.Session.Day(last.Date.Time).Equal(today) {
+		var cum int
+		for i := len(data) - 1; i >= 0 && b.opt.Session.Day(data[i].Date.Time).Equal(today); i-- {
+			cum += data[i].Volume
+		}
+		s.cumVolume, s.cumDay, s.hasCum = uint32(cum), today, true
+	}
+
+	return nil
+}
+
+// Flush emits the candles whose end along with the grace period is before
+// the given time. It should be called periodically (for example, every second)
+// so that candles of instruments without trades are emitted on time.
+func (b *Builder) Flush(now time.Time) {
+	var out []emit
+
+	b.mu.Lock()
+	for token, s := range b.series {
+		out = b.expire(out, token, s, now)
+	}
+	b.mu.Unlock()
+
+	b.trigger(out)
+}
+
+// FlushAll emits all the open candles irrespective of their end time. It can
+// be used at the end of the session or before shutting down.
+func (b *Builder) FlushAll() {
+	var out []emit
+
+	b.mu.Lock()
+	for token, s := range b.series {
+		if s.pending != nil {
+			out = b.complete(out, token, s, s.pending)
+		}
+		if s.cur != nil {
+			out = b.complete(out, token, s, s.cur)
+		}
+		s.pending, s.cur = nil, nil
+	}
+	b.mu.Unlock()
+
+	b.trigger(out)
+}
+
+// Current returns the candle in progress for an instrument.
+func (b *Builder) Current(token uint32) (kiteconnect.HistoricalData, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.series[token]
+	if !ok || s.cur == nil {
+		return kiteconnect.HistoricalData{}, false
+	}
+	return s.cur.candle, true
+}
+
+// History returns the completed candles of an instrument including the seeded ones.
+func (b *Builder) History(token uint32) []kiteconnect.HistoricalData {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.series[token]
+	if !ok {
+		return nil
+	}
+
+	out := make([]kiteconnect.HistoricalData, len(s.history))
+	copy(out, s.history)
+	return out
+}
+
+func (b *Builder) getSeries(token uint32) *series {
+	s, ok := b.series[token]
+	if !ok {
+		s = &series{}
+		b.series[token] = s
+	}
+	return s
+}
+
+// tickTime returns the exchange timestamp of a tick if available.
+func (b *Builder) tickTime(tick models.Tick) time.Time {
+	if !tick.Timestamp.IsZero() {
+		return tick.Timestamp.Time
+	}
+	return b.opt.Clock()
+}
+
+func (b *Builder) bucketStart(t time.Time) time.Time {
+	return b.opt.Session.BucketStart(t, b.interval)
+}
+
+// bucketEnd returns the end of a candle which is capped at session close.
+func (b *Builder) bucketEnd(start time.Time) time.Time {
+	if !b.interval.Intraday() {
+		if b.opt.ExtendedHours {
+			return start.AddDate(0, 0, 1)
+		}
+		return b.opt.Session.CloseTime(start)
+	}
+
+	end := start.Add(b.interval.Duration())
+	if cl := b.opt.Session.CloseTime(start); !b.opt.ExtendedHours && end.After(cl) {
+		end = cl
+	}
+	return end
+}
+
+func (b *Builder) newBar(start time.Time, price float64, vol uint32, oi int) *bar {
+	return &bar{
+		candle: kiteconnect.HistoricalData{
+			Date:   models.Time{Time: start},
+			Open:   price,
+			High:   price,
+			Low:    price,
+			Close:  price,
+			Volume: int(vol),
+			OI:     oi,
+		},
+		end: b.bucketEnd(start),
+	}
+}
+
+// expire completes the pending and current candles of a series which are
+// past their end and grace period at the given time.
+func (b *Builder) expire(out []emit, token uint32, s *series, now time.Time) []emit {
+	if s.pending != nil && !now.Before(s.pending.end.Add(b.opt.Grace)) {
+		out = b.complete(out, token, s, s.pending)
+		s.pending = nil
+	}
+
+	if s.cur != nil && !now.Before(s.cur.end.Add(b.opt.Grace)) {
+		if s.pending != nil {
+			out = b.complete(out, token, s, s.pending)
+			s.pending = nil
+		}
+		out = b.complete(out, token, s, s.cur)
+		s.cur = nil
+	}
+
+	return out
+}
+
+// complete adds a candle to the history and queues it to be emitted.
+func (b *Builder) complete(out []emit, token uint32, s *series, c *bar) []emit {
+	s.history = append(s.history, c.candle)
+	s.trim(b.opt.MaxHistory)
+	return append(out, emit{token: token, candle: c.candle})
+}
+
+// trigger sends completed candles to the callback. It must be called without
+// holding the lock so that the callback can call back into the builder.
+func (b *Builder) trigger(out []emit) {
+	if len(out) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	f := b.onCandle
+	b.mu.Unlock()
+
+	if f == nil {
+		return
+	}
+
+	for _, e := range out {
+		f(e.token, e.candle)
+	}
+}
+
+// add updates the candle with a trade.
+func (c *bar) add(price float64, vol uint32, oi int) {
+	if price > c.candle.High {
+		c.candle.High = price
+	}
+	if price < c.candle.Low {
+		c.candle.Low = price
+	}
+	c.candle.Close = price
+	c.candle.Volume += int(vol)
+	c.candle.OI = oi
+}
+
+// volumeDelta returns the volume traded since the previous tick using
+// the cumulative day volume in the tick. The first tick of an instrument only
+// sets the baseline unless the instrument was seeded.
+func (s *series) volumeDelta(tick models.Tick, day time.Time) uint32 {
+	if tick.Mode == modeLTP {
+		return 0
+	}
+
+	// Late tick from a previous trading day.
+	if s.hasCum && day.Before(s.cumDay) {
+		return 0
+	}
+
+	// Cumulative volume resets at the start of a new trading day.
+	if !s.hasCum || !day.Equal(s.cumDay) {
+		var delta uint32
+		if s.hasCum {
+			delta = tick.VolumeTraded
+		}
+		s.cumVolume, s.cumDay, s.hasCum = tick.VolumeTraded, day, true
+		return delta
+	}
+
+	// Out of order tick with an older cumulative volume.
+	if tick.VolumeTraded < s.cumVolume {
+		return 0
+	}
+
+	delta := tick.VolumeTraded - s.cumVolume
+	s.cumVolume = tick.VolumeTraded
+	return delta
+}
+
+func (s *series) trim(max int) {
+	if n := len(s.history); n > max {
+		s.history = append(s.history[:0], s.history[n-max:]...)
+	}
+}
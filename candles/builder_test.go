@@ -0,0 +1,198 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func tick(token uint32, ts time.Time, price float64, volume uint32) models.Tick {
+	return models.Tick{
+		Mode:            modeFull,
+		InstrumentToken: token,
+		Timestamp:       models.Time{Time: ts},
+		LastPrice:       price,
+		VolumeTraded:    volume,
+		OI:              volume / 10,
+	}
+}
+
+type collector struct {
+	tokens  []uint32
+	candles []kiteconnect.HistoricalData
+}
+
+func (c *collector) add(token uint32, candle kiteconnect.HistoricalData) {
+	c.tokens = append(c.tokens, token)
+	c.candles = append(c.candles, candle)
+}
+
+func TestBuilderCandles(t *testing.T) {
+	t.Parallel()
+	b, err := NewBuilder(Minutes(1), BuilderOptions{})
+	require.NoError(t, err)
+
+	var c collector
+	b.OnCandle(c.add)
+
+	base := ist(2021, 7, 5, 9, 15)
+	b.AddTick(tick(1, base.Add(1*time.Second), 100, 1000))
+	b.AddTick(tick(2, base.Add(2*time.Second), 50, 10))
+	b.AddTick(tick(1, base.Add(20*time.Second), 102, 1100))
+	b.AddTick(tick(1, base.Add(40*time.Second), 99, 1150))
+	b.AddTick(tick(1, base.Add(59*time.Second), 101, 1200))
+	require.Empty(t, c.candles)
+
+	cur, ok := b.Current(1)
+	require.True(t, ok)
+	require.Equal(t, 101.0, cur.Close)
+
+	// Tick in the next minute completes the first candle of token 1 only.
+	b.AddTick(tick(1, base.Add(61*time.Second), 103, 1300))
+	require.Equal(t, []uint32{1}, c.tokens)
+	require.Equal(t, kiteconnect.HistoricalData{
+		Date: models.Time{Time: base}, Open: 100, High: 102, Low: 99, Close: 101, Volume: 200, OI: 120,
+	}, c.candles[0])
+
+	// Volume of the second candle is derived from the previous cumulative volume.
+	b.FlushAll()
+	require.Equal(t, []uint32{1, 1, 2}, sortTokens(c.tokens))
+	for i, tk := range c.tokens {
+		if tk == 1 && i > 0 {
+			require.Equal(t, 100, c.candles[i].Volume)
+			require.Equal(t, base.Add(time.Minute), c.candles[i].Date.Time)
+		}
+	}
+	require.Len(t, b.History(1), 2)
+	_, ok = b.Current(1)
+	require.False(t, ok)
+}
+
+func TestBuilderLateTicks(t *testing.T) {
+	t.Parallel()
+	base := ist(2021, 7, 5, 10, 0)
+
+	// Without grace, a late tick for an emitted candle is dropped.
+	b, err := NewBuilder(Minutes(1), BuilderOptions{})
+	require.NoError(t, err)
+	var c collector
+	b.OnCandle(c.add)
+
+	b.AddTick(tick(1, base.Add(10*time.Second), 100, 100))
+	b.AddTick(tick(1, base.Add(65*time.Second), 101, 200))
+	b.AddTick(tick(1, base.Add(59*time.Second), 90, 150))
+	require.Equal(t, uint64(1), b.LateTicks())
+	require.Len(t, c.candles, 1)
+	require.Equal(t, 100.0, c.candles[0].Low)
+
+	// With grace, the late tick updates the candle before it's emitted.
+	b, err = NewBuilder(Minutes(1), BuilderOptions{Grace: 2 * time.Second})
+	require.NoError(t, err)
+	c = collector{}
+	b.OnCandle(c.add)
+
+	b.AddTick(tick(1, base.Add(10*time.Second), 100, 100))
+	b.AddTick(tick(1, base.Add(61*time.Second), 101, 200))
+	b.AddTick(tick(1, base.Add(59*time.Second), 90, 150))
+	require.Empty(t, c.candles)
+	require.Equal(t, uint64(0), b.LateTicks())
+
+	b.AddTick(tick(1, base.Add(62*time.Second), 102, 250))
+	require.Len(t, c.candles, 1)
+	require.Equal(t, 90.0, c.candles[0].Low)
+	require.Equal(t, 90.0, c.candles[0].Close)
+
+	// The second candle only has the volume after the first tick in it.
+	cur, _ := b.Current(1)
+	require.Equal(t, 150, cur.Volume)
+}
+
+func TestBuilderSession(t *testing.T) {
+	t.Parallel()
+	b, err := NewBuilder(Minutes(5), BuilderOptions{})
+	require.NoError(t, err)
+	var c collector
+	b.OnCandle(c.add)
+
+	// Pre-open ticks are ignored.
+	b.AddTick(tick(1, ist(2021, 7, 5, 9, 8), 100, 500))
+	b.AddTick(tick(1, ist(2021, 7, 5, 15, 28), 100, 5000))
+	b.AddTick(tick(1, ist(2021, 7, 5, 15, 29), 101, 5100))
+	_, ok := b.Current(1)
+	require.True(t, ok)
+
+	// Last candle of the day ends at session close and is flushed.
+	b.Flush(ist(2021, 7, 5, 15, 29))
+	require.Empty(t, c.candles)
+	b.Flush(ist(2021, 7, 5, 15, 30))
+	require.Len(t, c.candles, 1)
+	require.Equal(t, ist(2021, 7, 5, 15, 25), c.candles[0].Date.Time)
+	require.Equal(t, 100, c.candles[0].Volume)
+
+	// Cumulative volume resets on the next day.
+	b.AddTick(tick(1, ist(2021, 7, 6, 9, 15), 105, 300))
+	cur, _ := b.Current(1)
+	require.Equal(t, 300, cur.Volume)
+}
+
+func TestBuilderSeed(t *testing.T) {
+	t.Parallel()
+	now := ist(2021, 7, 5, 9, 32)
+	b, err := NewBuilder(Minutes(15), BuilderOptions{Clock: func() time.Time { return now }})
+	require.NoError(t, err)
+	var c collector
+	b.OnCandle(c.add)
+
+	var data []kiteconnect.HistoricalData
+	for i := 0; i < 17; i++ {
+		p := float64(100 + i)
+		data = append(data, candle(ist(2021, 7, 5, 9, 15).Add(time.Duration(i)*time.Minute), p, p, p, p, 10, 0))
+	}
+	require.NoError(t, b.Seed(1, data))
+
+	// 09:15 is complete and 09:30 is in progress.
+	hist := b.History(1)
+	require.Len(t, hist, 1)
+	require.Equal(t, 150, hist[0].Volume)
+	cur, ok := b.Current(1)
+	require.True(t, ok)
+	require.Equal(t, ist(2021, 7, 5, 9, 30), cur.Date.Time)
+	require.Equal(t, 20, cur.Volume)
+
+	// First tick continues the seeded candle and the day volume is the baseline.
+	b.AddTick(tick(1, ist(2021, 7, 5, 9, 33), 120, 200))
+	cur, _ = b.Current(1)
+	require.Equal(t, 50, cur.Volume)
+	require.Equal(t, 120.0, cur.High)
+	require.Equal(t, 115.0, cur.Open)
+
+	// Quote mode ticks without timestamp use the clock.
+	now = ist(2021, 7, 5, 9, 45)
+	b.AddTick(models.Tick{Mode: "quote", InstrumentToken: 1, LastPrice: 121, VolumeTraded: 210})
+	require.Len(t, c.candles, 1)
+	require.Equal(t, 50, c.candles[0].Volume)
+	cur, _ = b.Current(1)
+	require.Equal(t, ist(2021, 7, 5, 9, 45), cur.Date.Time)
+	require.Equal(t, 10, cur.Volume)
+}
+
+func TestNewBuilderInterval(t *testing.T) {
+	t.Parallel()
+	_, err := NewBuilder(Weeks(1), BuilderOptions{})
+	require.Equal(t, ErrInvalidInterval, err)
+	_, err = NewBuilder(Days(1), BuilderOptions{})
+	require.NoError(t, err)
+}
+
+func sortTokens(in []uint32) []uint32 {
+	out := append([]uint32{}, in...)
+	for i := 1; i < len(out); i++ {
+		for j := i; j > 0 && out[j] < out[j-1]; j-- {
+			out[j], out[j-1] = out[j-1], out[j]
+		}
+	}
+	return out
+}
@@ -0,0 +1,214 @@
+// Package candles provides utilities for building and resampling OHLC candles
+// aligned to Indian exchange trading sessions.
+package candles
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Unit represents the unit of a candle interval.
+type Unit int
+
+const (
+	// UnitMinute represents intraday intervals which are aligned to session open.
+	UnitMinute Unit = iota
+	// UnitDay represents intervals of one or more trading days.
+	UnitDay
+	// UnitWeek represents intervals of one or more calendar weeks starting on Monday.
+	UnitWeek
+	// UnitMonth represents intervals of one or more calendar months.
+	UnitMonth
+)
+
+// Interval represents the size of a candle.
+type Interval struct {
+	Unit  Unit
+	Count int
+}
+
+// Session represents the trading session used to align candles.
+type Session struct {
+	// Location is the timezone in which the session times are defined.
+	Location *time.Location
+	// Open is the offset from midnight at which the session opens.
+	Open time.Duration
+	// Close is the offset from midnight at which the session closes.
+	Close time.Duration
+}
+
+var (
+	// IST is the Indian standard time zone. It is a fixed zone as IST
+	// doesn't observe daylight saving and this avoids depending on tzdata.
+	IST = time.FixedZone("IST", 5*60*60+30*60)
+
+	// NSESession is the regular equity and F&O session of NSE and BSE (09:15 - 15:30).
+	NSESession = Session{Location: IST, Open: 9*time.Hour + 15*time.Minute, Close: 15*time.Hour + 30*time.Minute}
+
+	// CDSSession is the currency derivatives session (09:00 - 17:00).
+	CDSSession = Session{Location: IST, Open: 9 * time.Hour, Close: 17 * time.Hour}
+
+	// MCXSession is the commodity derivatives session (09:00 - 23:30).
+	MCXSession = Session{Location: IST, Open: 9 * time.Hour, Close: 23*time.Hour + 30*time.Minute}
+)
+
+var (
+	// ErrInvalidInterval is returned when an interval has a non positive count or unknown unit.
+	ErrInvalidInterval = errors.New("invalid candle interval")
+	// ErrUnsorted is returned when input candles are not in ascending order of time.
+	ErrUnsorted = errors.New("candles are not sorted by date")
+
+	reInterval = regexp.MustCompile(`^(\d*)(minute|hour|day|week|month)$`)
+)
+
+// Minutes returns an intraday interval of n minutes.
+func Minutes(n int) Interval {
+	return Interval{Unit: UnitMinute, Count: n}
+}
+
+// Hours returns an intraday interval of n hours.
+func Hours(n int) Interval {
+	return Interval{Unit: UnitMinute, Count: n * 60}
+}
+
+// Days returns an interval of n trading days.
+func Days(n int) Interval {
+	return Interval{Unit: UnitDay, Count: n}
+}
+
+// Weeks returns an interval of n weeks.
+func Weeks(n int) Interval {
+	return Interval{Unit: UnitWeek, Count: n}
+}
+
+// Months returns an interval of n months.
+func Months(n int) Interval {
+	return Interval{Unit: UnitMonth, Count: n}
+}
+
+// ParseInterval parses Kite style interval strings such as `minute`, `15minute`,
+// `day` along with extended ones such as `2hour`, `week` and `3month`.
+func ParseInterval(s string) (Interval, error) {
+	m := reInterval.FindStringSubmatch(s)
+	if m == nil {
+		return Interval{}, fmt.Errorf("%w: %s", ErrInvalidInterval, s)
+	}
+
+	n := 1
+	if m[1] != "" {
+		var err error
+		if n, err = strconv.Atoi(m[1]); err != nil || n <= 0 {
+			return Interval{}, fmt.Errorf("%w: %s", ErrInvalidInterval, s)
+		}
+	}
+
+	switch m[2] {
+	case "minute":
+		return Minutes(n), nil
+	case "hour":
+		return Hours(n), nil
+	case "day":
+		return Days(n), nil
+	case "week":
+		return Weeks(n), nil
+	default:
+		return Months(n), nil
+	}
+}
+
+// Valid returns an error if the interval can't be used for aggregation.
+func (i Interval) Valid() error {
+	if i.Count <= 0 || i.Unit < UnitMinute || i.Unit > UnitMonth {
+		return ErrInvalidInterval
+	}
+	return nil
+}
+
+// Intraday returns true if the interval is smaller than a trading day.
+func (i Interval) Intraday() bool {
+	return i.Unit == UnitMinute
+}
+
+// Duration returns the length of an intraday interval. It returns 0 for
+// daily and longer intervals as their length isn't fixed.
+func (i Interval) Duration() time.Duration {
+	if i.Unit != UnitMinute {
+		return 0
+	}
+	return time.Duration(i.Count) * time.Minute
+}
+
+// String returns the interval in the format accepted by ParseInterval.
+func (i Interval) String() string {
+	var unit string
+	switch i.Unit {
+	case UnitMinute:
+		unit = "minute"
+	case UnitDay:
+		unit = "day"
+	case UnitWeek:
+		unit = "week"
+	case UnitMonth:
+		unit = "month"
+	}
+
+	if i.Count == 1 {
+		return unit
+	}
+	return strconv.Itoa(i.Count) + unit
+}
+
+// location returns the session location defaulting to IST.
+func (s Session) location() *time.Location {
+	if s.Location == nil {
+		return IST
+	}
+	return s.Location
+}
+
+// Day returns midnight of the trading day t belongs to in the session location.
+func (s Session) Day(t time.Time) time.Time {
+	t = t.In(s.location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// OpenTime returns the session open time on the day of t.
+func (s Session) OpenTime(t time.Time) time.Time {
+	return s.Day(t).Add(s.Open)
+}
+
+// CloseTime returns the session close time on the day of t.
+func (s Session) CloseTime(t time.Time) time.Time {
+	return s.Day(t).Add(s.Close)
+}
+
+// Contains returns true if t falls within the session open and close on its day.
+func (s Session) Contains(t time.Time) bool {
+	return !t.Before(s.OpenTime(t)) && t.Before(s.CloseTime(t))
+}
+
+// BucketStart returns the start time of the intraday candle of the given
+// interval that t falls in. Buckets are aligned to session open and never span
+// across days, so the last bucket of a session may be shorter than the interval.
+func (s Session) BucketStart(t time.Time, i Interval) time.Time {
+	var (
+		open = s.OpenTime(t)
+		d    = i.Duration()
+	)
+
+	if d <= 0 {
+		return s.Day(t)
+	}
+
+	off := t.Sub(open)
+	n := off / d
+	// Floor division for times before the session open.
+	if off < 0 && off%d != 0 {
+		n--
+	}
+
+	return open.Add(n * d)
+}
@@ -0,0 +1,414 @@
+package candles
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+)
+
+// HistoricalSource fetches historical candles of an instrument.
+// kiteconnect.Client implements this interface.
+type HistoricalSource interface {
+	GetHistoricalData(instrumentToken int, interval string, fromDate time.Time, toDate time.Time, continuous bool, OI bool) ([]kiteconnect.HistoricalData, error)
+}
+
+// Adjustment represents how prices before a roll are adjusted.
+type Adjustment int
+
+const (
+	// AdjustNone stitches the raw prices of contracts, leaving gaps at rolls.
+	AdjustNone Adjustment = iota
+	// AdjustDifference back-adjusts prices before a roll by adding the
+	// difference between the new and old contract's close at the roll.
+	AdjustDifference
+	// AdjustRatio back-adjusts prices before a roll by multiplying them with
+	// the ratio of the new and old contract's close at the roll.
+	AdjustRatio
+)
+
+// instrumentTypeFuture is the instrument type of futures in the instrument master.
+const instrumentTypeFuture = "FUT"
+
+// maxHistoricalDays is the maximum number of days of candles which can
+// be fetched in a single historical data request for an interval.
+var maxHistoricalDays = map[string]int{
+	"minute":   60,
+	"3minute":  100,
+	"5minute":  100,
+	"10minute": 100,
+	"15minute": 200,
+	"30minute": 200,
+	"60minute": 400,
+	"day":      2000,
+}
+
+// ErrNoContracts is returned when no futures contracts are found for the period.
+var ErrNoContracts = errors.New("no futures contracts found")
+
+// ContinuousParams represents the params for building a continuous futures series.
+type ContinuousParams struct {
+	// Exchange and Name identify the futures in the instrument master,
+	// for example NFO and NIFTY.
+	Exchange string
+	Name     string
+
+	// Interval of candles. It must be one of the intervals supported by
+	// the historical data API.
+	Interval Interval
+	From     time.Time
+	To       time.Time
+
+	// RollDays is the number of trading days before expiry on which the series
+	// switches to the next contract. 0 switches on the day after expiry.
+	RollDays int
+
+	// RollOnOI switches to the next contract on the trading day after the next
+	// contract's OI at close exceeds the current one's, if that's earlier than
+	// the roll due to RollDays.
+	RollOnOI bool
+
+	Adjustment Adjustment
+
+	// Session used to determine trading days. Defaults to NSESession.
+	Session Session
+}
+
+// Roll represents a switch from one futures contract to the next.
+type Roll struct {
+	// Date is the first trading day on which the series uses the new contract.
+	Date time.Time
+	From kiteconnect.Instrument
+	To   kiteconnect.Instrument
+
+	// FromClose and ToClose are the closes of the contracts on the last
+	// candle before the roll which are used for adjusting prices.
+	FromClose float64
+	ToClose   float64
+}
+
+// Difference returns the price difference between the contracts at the roll.
+func (r Roll) Difference() float64 {
+	if r.FromClose == 0 || r.ToClose == 0 {
+		return 0
+	}
+	return r.ToClose - r.FromClose
+}
+
+// Ratio returns the price ratio between the contracts at the roll.
+func (r Roll) Ratio() float64 {
+	if r.FromClose == 0 || r.ToClose == 0 {
+		return 1
+	}
+	return r.ToClose / r.FromClose
+}
+
+// ContinuousSeries represents a continuous futures series.
+type ContinuousSeries struct {
+	Candles []kiteconnect.HistoricalData
+	Rolls   []Roll
+}
+
+// contract is a futures contract along with its candles.
+type contract struct {
+	inst    kiteconnect.Instrument
+	expiry  time.Time
+	candles []kiteconnect.HistoricalData
+}
+
+// BuildContinuous builds a continuous futures series by stitching the candles
+// of consecutive contracts of an underlying. Contracts and their expiries are
+// taken from the given instrument master. As the current instrument master only
+// has live contracts, building a series for an older period requires the
+// instrument master from that period.
+func BuildContinuous(src HistoricalSource, instruments kiteconnect.Instruments, p ContinuousParams) (ContinuousSeries, error) {
+	var out ContinuousSeries
+
+	if _, ok := maxHistoricalDays[p.Interval.String()]; !ok {
+		return out, fmt.Errorf("%w: %s isn't supported by historical data", ErrInvalidInterval, p.Interval)
+	}
+
+	if p.Session == (Session{}) {
+		p.Session = NSESession
+	}
+
+	contracts := selectContracts(instruments, p)
+	if len(contracts) == 0 {
+		return out, ErrNoContracts
+	}
+
+	// Fetch candles of every contract from the time it becomes the next
+	// contract, which is when OI based rolls to it can happen, till expiry.
+	for i := range contracts {
+		from := p.From
+		if i >= 2 && contracts[i-2].expiry.After(from) {
+			from = contracts[i-2].expiry.AddDate(0, 0, 1)
+		}
+
+		to := contracts[i].expiry.Add(24*time.Hour - time.Second)
+		if p.To.Before(to) {
+			to = p.To
+		}
+
+		if from.After(to) {
+			continue
+		}
+
+		data, err := FetchHistorical(src, contracts[i].inst.InstrumentToken, p.Interval, from, to, true)
+		if err != nil {
+			return out, err
+		}
+		contracts[i].candles = data
+	}
+
+	// Find the roll dates and stitch the candles.
+	var (
+		start = p.Session.Day(p.From)
+		last  = p.Session.Day(p.To).AddDate(0, 0, 1)
+	)
+	for i, c := range contracts {
+		var (
+			end  = last
+			roll *Roll
+		)
+
+		if i < len(contracts)-1 {
+			r := findRoll(c, contracts[i+1], p)
+			// Contract was rolled over before the start.
+			if !r.Date.After(start) {
+				continue
+			}
+			if r.Date.Before(end) {
+				end, roll = r.Date, &r
+			}
+		}
+
+		for _, cd := range c.candles {
+			if !cd.Date.Before(start) && cd.Date.Before(end) &&
+				!cd.Date.Before(p.From) && !cd.Date.After(p.To) {
+				out.Candles = append(out.Candles, cd)
+			}
+		}
+
+		if roll == nil {
+			break
+		}
+		out.Rolls = append(out.Rolls, *roll)
+		start = end
+	}
+
+	adjust(out, p.Adjustment)
+	return out, nil
+}
+
+// FetchHistorical fetches candles of an instrument for the given period splitting
+// it into multiple requests as per the maximum days allowed per request for the interval.
+func FetchHistorical(src HistoricalSource, token int, interval Interval, from, to time.Time, oi bool) ([]kiteconnect.HistoricalData, error) {
+	days, ok := maxHistoricalDays[interval.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s isn't supported by historical data", ErrInvalidInterval, interval)
+	}
+
+	var out []kiteconnect.HistoricalData
+	for start := from; !start.After(to); {
+		end := start.AddDate(0, 0, days).Add(-time.Second)
+		if end.After(to) {
+			end = to
+		}
+
+		data, err := src.GetHistoricalData(token, interval.String(), start, end, false, oi)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data...)
+
+		start = end.Add(time.Second)
+	}
+
+	return out, nil
+}
+
+// selectContracts returns the futures contracts, sorted by expiry, which can
+// be part of the series for the given period.
+func selectContracts(instruments kiteconnect.Instruments, p ContinuousParams) []contract {
+	var all []contract
+	for _, inst := range instruments {
+		if inst.Exchange != p.Exchange || inst.Name != p.Name || inst.InstrumentType != instrumentTypeFuture || inst.Expiry.IsZero() {
+			continue
+		}
+		all = append(all, contract{inst: inst, expiry: p.Session.Day(inst.Expiry.Time)})
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].expiry.Before(all[j].expiry)
+	})
+
+	// From the first contract expiring after the start, till the first one
+	// expiring after the end and the one after that as rolls can happen before expiry.
+	var (
+		out   []contract
+		from  = p.Session.Day(p.From)
+		to    = p.Session.Day(p.To)
+		extra = 1
+	)
+	for _, c := range all {
+		if c.expiry.Before(from) {
+			continue
+		}
+
+		if c.expiry.After(to) || c.expiry.Equal(to) {
+			if extra < 0 {
+				break
+			}
+			extra--
+		}
+		out = append(out, c)
+	}
+
+	return out
+}
+
+// findRoll returns the roll from the current contract to the next one.
+func findRoll(cur, next contract, p ContinuousParams) Roll {
+	var (
+		days = tradingDays(cur, next, p.Session)
+		roll = cur.expiry.AddDate(0, 0, 1)
+	)
+
+	// Roll on the RollDays-th trading day before expiry.
+	if p.RollDays > 0 {
+		for i := len(days) - 1; i >= 0; i-- {
+			if days[i].Equal(cur.expiry) {
+				if i-p.RollDays >= 0 {
+					roll = days[i-p.RollDays]
+				} else if len(days) > 0 {
+					roll = days[0]
+				}
+				break
+			}
+		}
+	}
+
+	// Roll on the trading day after next contract's OI exceeds the current one.
+	if p.RollOnOI {
+		var (
+			curOI  = dayCloseOI(cur.candles, p.Session)
+			nextOI = dayCloseOI(next.candles, p.Session)
+		)
+		for i, d := range days {
+			if !d.Before(roll) || i+1 >= len(days) {
+				break
+			}
+
+			c, n := curOI[d], nextOI[d]
+			if c > 0 && n > c {
+				roll = days[i+1]
+				break
+			}
+		}
+	}
+
+	r := Roll{Date: roll, From: cur.inst, To: next.inst}
+
+	// Closes of both the contracts on the last candle before the roll.
+	for i := len(cur.candles) - 1; i >= 0; i-- {
+		c := cur.candles[i]
+		if c.Date.Before(roll) {
+			r.FromClose = c.Close
+			r.ToClose = closeAt(next.candles, c.Date.Time)
+			break
+		}
+	}
+
+	return r
+}
+
+// tradingDays returns the trading days till the expiry of the current contract.
+// Days without candles after the last candle are assumed to be weekdays.
+func tradingDays(cur, next contract, sess Session) []time.Time {
+	seen := map[time.Time]bool{}
+	for _, cs := range [][]kiteconnect.HistoricalData{cur.candles, next.candles} {
+		for _, c := range cs {
+			if d := sess.Day(c.Date.Time); !d.After(cur.expiry) {
+				seen[d] = true
+			}
+		}
+	}
+
+	days := make([]time.Time, 0, len(seen))
+	for d := range seen {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	last := time.Time{}
+	if len(days) > 0 {
+		last = days[len(days)-1]
+	}
+	for d := last.AddDate(0, 0, 1); !last.IsZero() && !d.After(cur.expiry); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days = append(days, d)
+		}
+	}
+
+	return days
+}
+
+// dayCloseOI returns the OI at the last candle of every trading day.
+func dayCloseOI(data []kiteconnect.HistoricalData, sess Session) map[time.Time]int {
+	out := map[time.Time]int{}
+	for _, c := range data {
+		out[sess.Day(c.Date.Time)] = c.OI
+	}
+	return out
+}
+
+// closeAt returns the close of the last candle at or before t.
+func closeAt(data []kiteconnect.HistoricalData, t time.Time) float64 {
+	i := sort.Search(len(data), func(i int) bool {
+		return data[i].Date.After(t)
+	})
+	if i == 0 {
+		return 0
+	}
+	return data[i-1].Close
+}
+
+// adjust back-adjusts the prices of candles before every roll.
+func adjust(s ContinuousSeries, a Adjustment) {
+	if a == AdjustNone || len(s.Rolls) == 0 {
+		return
+	}
+
+	var (
+		r     = len(s.Rolls) - 1
+		diff  float64
+		ratio = 1.0
+	)
+
+	for i := len(s.Candles) - 1; i >= 0; i-- {
+		c := &s.Candles[i]
+		for r >= 0 && c.Date.Before(s.Rolls[r].Date) {
+			diff += s.Rolls[r].Difference()
+			ratio *= s.Rolls[r].Ratio()
+			r--
+		}
+
+		switch a {
+		case AdjustDifference:
+			c.Open += diff
+			c.High += diff
+			c.Low += diff
+			c.Close += diff
+		case AdjustRatio:
+			c.Open *= ratio
+			c.High *= ratio
+			c.Low *= ratio
+			c.Close *= ratio
+		}
+	}
+}
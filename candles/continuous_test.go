@@ -0,0 +1,205 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Client must be usable as the historical data source.
+var _ HistoricalSource = (*kiteconnect.Client)(nil)
+
+// fakeSource returns daily candles for weekdays with the price
+// offset by base and OI returned by oi for every token.
+type fakeSource struct {
+	base  map[int]float64
+	oi    func(token int, day time.Time) int
+	calls []time.Time
+}
+
+func (f *fakeSource) GetHistoricalData(token int, interval string, from, to time.Time, continuous bool, OI bool) ([]kiteconnect.HistoricalData, error) {
+	f.calls = append(f.calls, from)
+
+	var out []kiteconnect.HistoricalData
+	for d := NSESession.Day(from); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || d.Before(from) {
+			continue
+		}
+
+		p := f.base[token] + float64(d.YearDay())/10
+		c := kiteconnect.HistoricalData{Date: models.Time{Time: d}, Open: p, High: p, Low: p, Close: p, Volume: 1}
+		if f.oi != nil {
+			c.OI = f.oi(token, d)
+		}
+		out = append(out, c)
+	}
+
+	return out, nil
+}
+
+func futures() kiteconnect.Instruments {
+	fut := func(token int, sym string, expiry time.Time) kiteconnect.Instrument {
+		return kiteconnect.Instrument{
+			InstrumentToken: token,
+			Tradingsymbol:   sym,
+			Name:            "NIFTY",
+			Exchange:        "NFO",
+			InstrumentType:  "FUT",
+			Expiry:          models.Time{Time: expiry},
+		}
+	}
+
+	return kiteconnect.Instruments{
+		fut(3, "NIFTY21SEPFUT", ist(2021, 9, 30, 0, 0)),
+		fut(1, "NIFTY21JULFUT", ist(2021, 7, 29, 0, 0)),
+		fut(2, "NIFTY21AUGFUT", ist(2021, 8, 26, 0, 0)),
+		fut(4, "NIFTY21OCTFUT", ist(2021, 10, 28, 0, 0)),
+		{InstrumentToken: 5, Name: "NIFTY", Exchange: "NFO", InstrumentType: "CE", Expiry: models.Time{Time: ist(2021, 7, 29, 0, 0)}},
+		{InstrumentToken: 6, Name: "BANKNIFTY", Exchange: "NFO", InstrumentType: "FUT", Expiry: models.Time{Time: ist(2021, 7, 29, 0, 0)}},
+	}
+}
+
+func contractOf(c kiteconnect.HistoricalData) int {
+	return int(c.Close-float64(c.Date.YearDay())/10+0.5) / 100
+}
+
+func TestBuildContinuousExpiry(t *testing.T) {
+	t.Parallel()
+	src := &fakeSource{base: map[int]float64{1: 100, 2: 200, 3: 300, 4: 400}}
+
+	p := ContinuousParams{
+		Exchange: "NFO",
+		Name:     "NIFTY",
+		Interval: Days(1),
+		From:     ist(2021, 7, 1, 0, 0),
+		To:       ist(2021, 8, 31, 0, 0),
+	}
+
+	s, err := BuildContinuous(src, futures(), p)
+	require.NoError(t, err)
+	require.Len(t, s.Rolls, 2)
+	require.Equal(t, ist(2021, 7, 30, 0, 0), s.Rolls[0].Date)
+	require.Equal(t, "NIFTY21JULFUT", s.Rolls[0].From.Tradingsymbol)
+	require.Equal(t, "NIFTY21AUGFUT", s.Rolls[0].To.Tradingsymbol)
+	require.Equal(t, ist(2021, 8, 27, 0, 0), s.Rolls[1].Date)
+	require.InDelta(t, 100, s.Rolls[0].Difference(), 1e-9)
+
+	// 22 weekdays in July, 22 in August.
+	require.Len(t, s.Candles, 44)
+	for _, c := range s.Candles {
+		switch {
+		case c.Date.Before(s.Rolls[0].Date):
+			require.Equal(t, 1, contractOf(c), c.Date)
+		case c.Date.Before(s.Rolls[1].Date):
+			require.Equal(t, 2, contractOf(c), c.Date)
+		default:
+			require.Equal(t, 3, contractOf(c), c.Date)
+		}
+	}
+
+	// Roll 2 trading days before expiry.
+	p.RollDays = 2
+	s, err = BuildContinuous(src, futures(), p)
+	require.NoError(t, err)
+	require.Equal(t, ist(2021, 7, 27, 0, 0), s.Rolls[0].Date)
+	require.Equal(t, ist(2021, 8, 24, 0, 0), s.Rolls[1].Date)
+	require.Len(t, s.Candles, 44)
+}
+
+func TestBuildContinuousOI(t *testing.T) {
+	t.Parallel()
+	src := &fakeSource{
+		base: map[int]float64{1: 100, 2: 200, 3: 300, 4: 400},
+		oi: func(token int, day time.Time) int {
+			// August OI crosses July's on 22nd July.
+			if token == 2 && day.Month() == time.July {
+				if day.Day() >= 22 {
+					return 2000
+				}
+				return 500
+			}
+			if token == 1 {
+				return 1300 - day.Day()
+			}
+			return 1000
+		},
+	}
+
+	s, err := BuildContinuous(src, futures(), ContinuousParams{
+		Exchange: "NFO",
+		Name:     "NIFTY",
+		Interval: Days(1),
+		From:     ist(2021, 7, 1, 0, 0),
+		To:       ist(2021, 8, 10, 0, 0),
+		RollDays: 1,
+		RollOnOI: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, s.Rolls, 1)
+	require.Equal(t, ist(2021, 7, 23, 0, 0), s.Rolls[0].Date)
+	require.Equal(t, 2, contractOf(s.Candles[len(s.Candles)-1]))
+}
+
+func TestBuildContinuousAdjust(t *testing.T) {
+	t.Parallel()
+	src := &fakeSource{base: map[int]float64{1: 100, 2: 200, 3: 300, 4: 400}}
+	p := ContinuousParams{
+		Exchange: "NFO",
+		Name:     "NIFTY",
+		Interval: Days(1),
+		From:     ist(2021, 7, 26, 0, 0),
+		To:       ist(2021, 8, 31, 0, 0),
+	}
+
+	raw, err := BuildContinuous(src, futures(), p)
+	require.NoError(t, err)
+
+	p.Adjustment = AdjustDifference
+	diff, err := BuildContinuous(src, futures(), p)
+	require.NoError(t, err)
+
+	p.Adjustment = AdjustRatio
+	ratio, err := BuildContinuous(src, futures(), p)
+	require.NoError(t, err)
+
+	require.Len(t, diff.Candles, len(raw.Candles))
+	for i, c := range raw.Candles {
+		switch {
+		case c.Date.Before(raw.Rolls[0].Date):
+			require.InDelta(t, c.Close+200, diff.Candles[i].Close, 1e-9)
+			require.InDelta(t, c.Close*raw.Rolls[0].Ratio()*raw.Rolls[1].Ratio(), ratio.Candles[i].Close, 1e-9)
+		case c.Date.Before(raw.Rolls[1].Date):
+			require.InDelta(t, c.Close+100, diff.Candles[i].Close, 1e-9)
+		default:
+			require.Equal(t, c.Close, diff.Candles[i].Close)
+			require.Equal(t, c.Close, ratio.Candles[i].Close)
+		}
+	}
+}
+
+func TestBuildContinuousErrors(t *testing.T) {
+	t.Parallel()
+	src := &fakeSource{}
+	_, err := BuildContinuous(src, futures(), ContinuousParams{Exchange: "MCX", Name: "NIFTY", Interval: Days(1)})
+	require.Equal(t, ErrNoContracts, err)
+
+	_, err = BuildContinuous(src, futures(), ContinuousParams{Exchange: "NFO", Name: "NIFTY", Interval: Weeks(1)})
+	require.ErrorIs(t, err, ErrInvalidInterval)
+}
+
+func TestFetchHistorical(t *testing.T) {
+	t.Parallel()
+	src := &fakeSource{base: map[int]float64{1: 100}}
+	data, err := FetchHistorical(src, 1, Minutes(1), ist(2021, 1, 1, 0, 0), ist(2021, 5, 31, 0, 0), false)
+	require.NoError(t, err)
+	require.Len(t, src.calls, 3)
+	require.Equal(t, ist(2021, 3, 2, 0, 0), src.calls[1])
+
+	// No duplicates at the boundaries of requests.
+	for i := 1; i < len(data); i++ {
+		require.True(t, data[i].Date.After(data[i-1].Date.Time))
+	}
+}
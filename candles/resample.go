@@ -0,0 +1,154 @@
+package candles
+
+import (
+	"time"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Options represents the optional params for resampling candles.
+type Options struct {
+	// Session used to align intraday candles. Defaults to NSESession.
+	Session Session
+
+	// FillGaps inserts flat candles (OHLC set to the previous close and zero
+	// volume) for intraday buckets without any trades between two candles of
+	// the same trading day. Holidays and the time outside sessions are never filled.
+	FillGaps bool
+}
+
+// epochMonday is the Monday used to align multi week buckets.
+var epochMonday = time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// Resample aggregates candles into candles of the given interval. The input
+// must be sorted by date and be of a smaller interval than the target one.
+//
+// Intraday candles are aligned to the session open (09:15 for NSE) and not
+// midnight, so a 2 hour interval yields candles at 09:15, 11:15, 13:15 and 15:15.
+// Daily and longer candles are stamped with midnight of the first trading day
+// in the bucket, which means a week starting with a holiday is stamped with
+// the next trading day. Days(n) counts trading days present in the input.
+//
+// Open is taken from the first candle, close from the last, high and low are
+// the extremes, volume is summed and OI is the last available value.
+func Resample(data []kiteconnect.HistoricalData, interval Interval, opt Options) ([]kiteconnect.HistoricalData, error) {
+	if err := interval.Valid(); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	sess := opt.Session
+	if sess == (Session{}) {
+		sess = NSESession
+	}
+
+	var (
+		out     = make([]kiteconnect.HistoricalData, 0, len(data)/2+1)
+		lastKey int64
+		dayIdx  int64 = -1
+		lastDay time.Time
+	)
+
+	for i, c := range data {
+		if i > 0 && c.Date.Before(data[i-1].Date.Time) {
+			return nil, ErrUnsorted
+		}
+
+		day := sess.Day(c.Date.Time)
+		if !day.Equal(lastDay) {
+			dayIdx++
+			lastDay = day
+		}
+
+		var (
+			key   int64
+			stamp time.Time
+		)
+
+		switch interval.Unit {
+		case UnitMinute:
+			stamp = sess.BucketStart(c.Date.Time, interval)
+			key = stamp.UnixNano()
+		case UnitDay:
+			stamp = day
+			key = dayIdx / int64(interval.Count)
+		case UnitWeek:
+			stamp = day
+			key = floorDiv(weekIndex(day), int64(interval.Count))
+		case UnitMonth:
+			stamp = day
+			key = floorDiv(int64(day.Year())*12+int64(day.Month())-1, int64(interval.Count))
+		}
+
+		// Continue the current candle.
+		if len(out) > 0 && key == lastKey {
+			merge(&out[len(out)-1], c)
+			continue
+		}
+
+		if opt.FillGaps && interval.Intraday() && len(out) > 0 {
+			out = fillGaps(out, stamp, interval.Duration(), sess)
+		}
+
+		c.Date = models.Time{Time: stamp}
+		out = append(out, c)
+		lastKey = key
+	}
+
+	return out, nil
+}
+
+// merge folds candle c into the aggregated candle dst.
+func merge(dst *kiteconnect.HistoricalData, c kiteconnect.HistoricalData) {
+	if c.High > dst.High {
+		dst.High = c.High
+	}
+	if c.Low < dst.Low {
+		dst.Low = c.Low
+	}
+	dst.Close = c.Close
+	dst.Volume += c.Volume
+	if c.OI != 0 {
+		dst.OI = c.OI
+	}
+}
+
+// fillGaps appends flat candles to out for the missing buckets between
+// the last candle in out and next, provided both are on the same trading day.
+func fillGaps(out []kiteconnect.HistoricalData, next time.Time, d time.Duration, sess Session) []kiteconnect.HistoricalData {
+	prev := out[len(out)-1]
+	if !sess.Day(prev.Date.Time).Equal(sess.Day(next)) {
+		return out
+	}
+
+	for t := prev.Date.Add(d); t.Before(next); t = t.Add(d) {
+		out = append(out, kiteconnect.HistoricalData{
+			Date:  models.Time{Time: t},
+			Open:  prev.Close,
+			High:  prev.Close,
+			Low:   prev.Close,
+			Close: prev.Close,
+			OI:    prev.OI,
+		})
+	}
+
+	return out
+}
+
+// weekIndex returns the number of weeks elapsed between epochMonday and day.
+func weekIndex(day time.Time) int64 {
+	d := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return floorDiv(int64(d.Sub(epochMonday)/(24*time.Hour)), 7)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
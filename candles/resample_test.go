@@ -0,0 +1,184 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func candle(t time.Time, o, h, l, c float64, v, oi int) kiteconnect.HistoricalData {
+	return kiteconnect.HistoricalData{Date: models.Time{Time: t}, Open: o, High: h, Low: l, Close: c, Volume: v, OI: oi}
+}
+
+func ist(y int, m time.Month, d, hh, mm int) time.Time {
+	return time.Date(y, m, d, hh, mm, 0, 0, IST)
+}
+
+func TestParseInterval(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		in  string
+		exp Interval
+		err bool
+	}{
+		{"minute", Minutes(1), false},
+		{"15minute", Minutes(15), false},
+		{"2hour", Minutes(120), false},
+		{"day", Days(1), false},
+		{"week", Weeks(1), false},
+		{"3month", Months(3), false},
+		{"0minute", Interval{}, true},
+		{"fortnight", Interval{}, true},
+	}
+
+	for _, tc := range tt {
+		i, err := ParseInterval(tc.in)
+		if tc.err {
+			require.Error(t, err, tc.in)
+			continue
+		}
+		require.NoError(t, err, tc.in)
+		require.Equal(t, tc.exp, i, tc.in)
+	}
+
+	require.Equal(t, "120minute", Hours(2).String())
+	require.Equal(t, "week", Weeks(1).String())
+}
+
+func TestBucketStart(t *testing.T) {
+	t.Parallel()
+	s := NSESession
+	require.Equal(t, ist(2021, 7, 5, 9, 15), s.BucketStart(ist(2021, 7, 5, 9, 15), Hours(2)))
+	require.Equal(t, ist(2021, 7, 5, 11, 15), s.BucketStart(ist(2021, 7, 5, 13, 14), Hours(2)))
+	require.Equal(t, ist(2021, 7, 5, 15, 15), s.BucketStart(ist(2021, 7, 5, 15, 29), Hours(2)))
+	require.Equal(t, ist(2021, 7, 5, 9, 0), s.BucketStart(ist(2021, 7, 5, 9, 7), Minutes(15)))
+
+	// Times in other zones are aligned on the IST trading day.
+	utc := time.Date(2021, 7, 5, 4, 0, 0, 0, time.UTC) // 09:30 IST
+	require.True(t, ist(2021, 7, 5, 9, 15).Equal(s.BucketStart(utc, Minutes(30))))
+}
+
+func TestResampleIntraday(t *testing.T) {
+	t.Parallel()
+	var data []kiteconnect.HistoricalData
+	// 09:15 to 15:29 one minute candles on a single day.
+	start := ist(2021, 7, 5, 9, 15)
+	for i := 0; i < 375; i++ {
+		p := float64(100 + i)
+		data = append(data, candle(start.Add(time.Duration(i)*time.Minute), p, p+1, p-1, p+0.5, 10, i))
+	}
+
+	out, err := Resample(data, Hours(2), Options{})
+	require.NoError(t, err)
+	require.Len(t, out, 4)
+
+	require.Equal(t, ist(2021, 7, 5, 9, 15), out[0].Date.Time)
+	require.Equal(t, ist(2021, 7, 5, 11, 15), out[1].Date.Time)
+	require.Equal(t, ist(2021, 7, 5, 15, 15), out[3].Date.Time)
+
+	require.Equal(t, kiteconnect.HistoricalData{
+		Date: out[0].Date, Open: 100, High: 220, Low: 99, Close: 219.5, Volume: 1200, OI: 119,
+	}, out[0])
+
+	// Last bucket only has 15 minutes of data.
+	require.Equal(t, 150, out[3].Volume)
+	require.Equal(t, 374, out[3].OI)
+	require.Equal(t, 474.5, out[3].Close)
+}
+
+func TestResampleFillGaps(t *testing.T) {
+	t.Parallel()
+	data := []kiteconnect.HistoricalData{
+		candle(ist(2021, 7, 5, 9, 15), 10, 12, 9, 11, 5, 1),
+		candle(ist(2021, 7, 5, 9, 20), 11, 11, 10, 10.5, 5, 2),
+		// 09:25 and 09:30 missing.
+		candle(ist(2021, 7, 5, 9, 35), 12, 13, 12, 13, 5, 3),
+		// Next day shouldn't be filled from the previous close.
+		candle(ist(2021, 7, 6, 9, 25), 14, 14, 14, 14, 5, 4),
+	}
+
+	out, err := Resample(data, Minutes(5), Options{FillGaps: true})
+	require.NoError(t, err)
+	require.Len(t, out, 6)
+	require.Equal(t, ist(2021, 7, 5, 9, 25), out[2].Date.Time)
+	require.Equal(t, kiteconnect.HistoricalData{Date: out[3].Date, Open: 10.5, High: 10.5, Low: 10.5, Close: 10.5, OI: 2}, out[3])
+	require.Equal(t, ist(2021, 7, 6, 9, 25), out[5].Date.Time)
+
+	out, err = Resample(data, Minutes(5), Options{})
+	require.NoError(t, err)
+	require.Len(t, out, 4)
+}
+
+func TestResampleOI(t *testing.T) {
+	t.Parallel()
+	data := []kiteconnect.HistoricalData{
+		candle(ist(2021, 7, 5, 9, 15), 10, 12, 9, 11, 5, 100),
+		candle(ist(2021, 7, 5, 9, 16), 11, 11, 10, 10.5, 5, 120),
+		// The last candle of the bucket has no OI.
+		candle(ist(2021, 7, 5, 9, 17), 10.5, 11, 10, 10, 5, 0),
+	}
+
+	out, err := Resample(data, Minutes(5), Options{})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Equal(t, 120, out[0].OI)
+}
+
+func TestResampleDaily(t *testing.T) {
+	t.Parallel()
+	var data []kiteconnect.HistoricalData
+	// Daily candles for July 2021 skipping weekends and a Monday holiday on 12th.
+	for d := 1; d <= 31; d++ {
+		day := time.Date(2021, 7, d, 0, 0, 0, 0, IST)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || d == 12 {
+			continue
+		}
+		p := float64(d)
+		data = append(data, candle(day, p, p+2, p-2, p+1, 100, d))
+	}
+	// First trading day of August.
+	data = append(data, candle(time.Date(2021, 8, 2, 0, 0, 0, 0, IST), 50, 50, 50, 50, 100, 50))
+
+	weeks, err := Resample(data, Weeks(1), Options{})
+	require.NoError(t, err)
+	require.Len(t, weeks, 6)
+	// Week 1 is a partial week starting Thursday.
+	require.Equal(t, time.Date(2021, 7, 1, 0, 0, 0, 0, IST), weeks[0].Date.Time)
+	require.Equal(t, 200, weeks[0].Volume)
+	// Holiday on Monday so the week is stamped Tuesday.
+	require.Equal(t, time.Date(2021, 7, 13, 0, 0, 0, 0, IST), weeks[2].Date.Time)
+	require.Equal(t, kiteconnect.HistoricalData{Date: weeks[2].Date, Open: 13, High: 18, Low: 11, Close: 17, Volume: 400, OI: 16}, weeks[2])
+
+	months, err := Resample(data, Months(1), Options{})
+	require.NoError(t, err)
+	require.Len(t, months, 2)
+	require.Equal(t, 2100, months[0].Volume)
+	require.Equal(t, float64(1), months[0].Open)
+	require.Equal(t, float64(31), months[0].Close)
+	require.Equal(t, float64(32), months[0].High)
+
+	days, err := Resample(data, Days(2), Options{})
+	require.NoError(t, err)
+	require.Len(t, days, 11)
+	require.Equal(t, time.Date(2021, 7, 9, 0, 0, 0, 0, IST), days[3].Date.Time)
+	require.Equal(t, float64(14), days[3].Close)
+}
+
+func TestResampleErrors(t *testing.T) {
+	t.Parallel()
+	_, err := Resample(nil, Minutes(0), Options{})
+	require.Equal(t, ErrInvalidInterval, err)
+
+	_, err = Resample([]kiteconnect.HistoricalData{
+		candle(ist(2021, 7, 5, 9, 20), 1, 1, 1, 1, 1, 1),
+		candle(ist(2021, 7, 5, 9, 15), 1, 1, 1, 1, 1, 1),
+	}, Minutes(5), Options{})
+	require.Equal(t, ErrUnsorted, err)
+
+	out, err := Resample(nil, Minutes(5), Options{})
+	require.NoError(t, err)
+	require.Empty(t, out)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -22,6 +23,10 @@ type Client struct {
 	baseURI     string
 	appName     string
 	httpClient  HTTPClient
+
+	trackerMu    sync.RWMutex
+	orderTracker *OrderTracker
+	orderLimiter *rateLimiter
 }
 
 const (
@@ -88,9 +93,19 @@ const (
 	MarginsCommodity = "commodity"
 
 	// Order status
-	OrderStatusComplete  = "COMPLETE"
-	OrderStatusRejected  = "REJECTED"
-	OrderStatusCancelled = "CANCELLED"
+	OrderStatusComplete                = "COMPLETE"
+	OrderStatusRejected                = "REJECTED"
+	OrderStatusCancelled               = "CANCELLED"
+	OrderStatusOpen                    = "OPEN"
+	OrderStatusTriggerPending          = "TRIGGER PENDING"
+	OrderStatusPutOrderReqReceived     = "PUT ORDER REQ RECEIVED"
+	OrderStatusValidationPending       = "VALIDATION PENDING"
+	OrderStatusOpenPending             = "OPEN PENDING"
+	OrderStatusAMOReqReceived          = "AMO REQ RECEIVED"
+	OrderStatusModifyValidationPending = "MODIFY VALIDATION PENDING"
+	OrderStatusModifyPending           = "MODIFY PENDING"
+	OrderStatusModified                = "MODIFIED"
+	OrderStatusCancelPending           = "CANCEL PENDING"
 )
 
 // API endpoints
@@ -158,8 +173,9 @@ const (
 // New creates a new Kite Connect client.
 func New(apiKey string) *Client {
 	client := &Client{
-		apiKey:  apiKey,
-		baseURI: baseURI,
+		apiKey:       apiKey,
+		baseURI:      baseURI,
+		orderLimiter: newRateLimiter(orderRateLimit, orderRateLimit),
 	}
 
 	// Create a default http handler with default timeout.
@@ -0,0 +1,239 @@
+package kiteconnect
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// exchangeServer is a fake order API which decides the fate of an order by
+// its tradingsymbol: REJECT orders are rejected, OPEN orders stay open till
+// cancelled, LOCKED orders can't be cancelled and the rest are filled immediately,
+// except stoploss orders which wait for their trigger and after market orders
+// which wait for the market to open. It's shared by the tests which place orders.
+type exchangeServer struct {
+	mu        sync.Mutex
+	orders    map[string]map[string]interface{}
+	history   map[string][]map[string]interface{}
+	modified  []map[string]interface{}
+	placed    []map[string]interface{}
+	cancelled []string
+	required  float64
+	available float64
+	positions []map[string]interface{}
+	ltp       map[string]float64
+	gtts      []url.Values
+	deleted   []string
+	// triggered are the ids of the orders placed by the GTTs by trigger id.
+	triggered map[int][]string
+	// placeErrors fail the next placements of orders by order type without placing them.
+	placeErrors map[string][]Error
+}
+
+func newExchangeServer(t *testing.T) (*exchangeServer, *Client) {
+	s := &exchangeServer{
+		orders:  map[string]map[string]interface{}{},
+		history: map[string][]map[string]interface{}{},
+	}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	c := New("api_key")
+	c.SetBaseURI(srv.URL)
+	return s, c
+}
+
+func (s *exchangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		data  interface{}
+		parts = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	)
+
+	switch {
+	case r.URL.Path == "/margins/basket":
+		data = map[string]interface{}{"final": map[string]interface{}{"total": s.required}}
+
+	case r.URL.Path == "/user/margins":
+		data = map[string]interface{}{"equity": map[string]interface{}{"net": s.available}}
+
+	case r.URL.Path == "/portfolio/positions":
+		data = map[string]interface{}{"net": s.positions}
+
+	case r.URL.Path == "/quote/ltp":
+		quotes := map[string]interface{}{}
+		for _, i := range r.URL.Query()["i"] {
+			if p, ok := s.ltp[i]; ok {
+				quotes[i] = map[string]interface{}{"last_price": p}
+			}
+		}
+		data = quotes
+
+	case r.URL.Path == "/gtt/triggers" && r.Method == http.MethodPost:
+		r.ParseForm()
+		s.gtts = append(s.gtts, r.PostForm)
+		data = map[string]interface{}{"trigger_id": len(s.gtts)}
+
+	case len(parts) == 3 && parts[0] == "gtt" && r.Method == http.MethodGet:
+		id, _ := strconv.Atoi(parts[2])
+		if id < 1 || id > len(s.gtts) {
+			http.NotFound(w, r)
+			return
+		}
+		var orders []map[string]interface{}
+		json.Unmarshal([]byte(s.gtts[id-1].Get("orders")), &orders)
+		for _, o := range orders {
+			// Zero timestamps don't unmarshal.
+			for k := range o {
+				if strings.HasSuffix(k, "timestamp") {
+					delete(o, k)
+				}
+			}
+		}
+		for i, oid := range s.triggered[id] {
+			if oid != "" && i < len(orders) {
+				orders[i]["result"] = map[string]interface{}{"order_result": map[string]interface{}{"order_id": oid, "status": "success"}}
+			}
+		}
+		data = map[string]interface{}{"id": id, "orders": orders}
+
+	case len(parts) == 3 && parts[0] == "gtt" && r.Method == http.MethodDelete:
+		s.deleted = append(s.deleted, parts[2])
+		data = map[string]interface{}{"trigger_id": parts[2]}
+
+	case r.URL.Path == "/orders" && r.Method == http.MethodGet:
+		var orders []map[string]interface{}
+		for i := 1; i <= len(s.orders); i++ {
+			orders = append(orders, s.orders[strconv.Itoa(i)])
+		}
+		data = orders
+
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		r.ParseForm()
+		if errs := s.placeErrors[r.Form.Get("order_type")]; len(errs) > 0 {
+			s.placeErrors[r.Form.Get("order_type")] = errs[1:]
+			w.WriteHeader(errs[0].Code)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "error_type": errs[0].ErrorType, "message": errs[0].Message})
+			return
+		}
+		id := strconv.Itoa(len(s.orders) + 1)
+		qty, _ := strconv.Atoi(r.Form.Get("quantity"))
+		price, _ := strconv.ParseFloat(r.Form.Get("price"), 64)
+		trigger, _ := strconv.ParseFloat(r.Form.Get("trigger_price"), 64)
+
+		o := map[string]interface{}{
+			"order_id":         id,
+			"variety":          parts[1],
+			"exchange":         r.Form.Get("exchange"),
+			"tradingsymbol":    r.Form.Get("tradingsymbol"),
+			"transaction_type": r.Form.Get("transaction_type"),
+			"product":          r.Form.Get("product"),
+			"order_type":       r.Form.Get("order_type"),
+			"quantity":         qty,
+			"price":            price,
+			"trigger_price":    trigger,
+			"tag":              r.Form.Get("tag"),
+			"status":           OrderStatusComplete,
+			"filled_quantity":  qty,
+			"average_price":    100,
+		}
+		if p := r.Form.Get("parent_order_id"); p != "" {
+			o["parent_order_id"] = p
+		}
+		if o["order_type"] == OrderTypeSL || o["order_type"] == OrderTypeSLM {
+			o["status"], o["filled_quantity"] = OrderStatusTriggerPending, 0
+		}
+		if o["variety"] == VarietyAMO {
+			o["status"], o["filled_quantity"] = OrderStatusAMOReqReceived, 0
+		}
+		switch o["tradingsymbol"] {
+		case "REJECT":
+			o["status"], o["filled_quantity"], o["status_message"] = OrderStatusRejected, 0, "Insufficient funds"
+		case "OPEN":
+			o["status"], o["filled_quantity"] = OrderStatusOpen, 0
+		}
+
+		s.orders[id] = o
+		s.placed = append(s.placed, o)
+		data = map[string]interface{}{"order_id": id}
+
+	case len(parts) == 3 && r.Method == http.MethodPut:
+		o, ok := s.orders[parts[2]]
+		if !ok || IsTerminalStatus(o["status"].(string)) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "error_type": OrderError, "message": "Order can't be modified"})
+			return
+		}
+		r.ParseForm()
+
+		// The modification is recorded in the history before the order changes.
+		m := map[string]interface{}{}
+		for k, v := range o {
+			m[k] = v
+		}
+		m["status"] = OrderStatusModified
+		s.history[parts[2]] = append(s.history[parts[2]], m)
+
+		o["price"], _ = strconv.ParseFloat(r.Form.Get("price"), 64)
+		o["trigger_price"], _ = strconv.ParseFloat(r.Form.Get("trigger_price"), 64)
+		o["quantity"], _ = strconv.Atoi(r.Form.Get("quantity"))
+		s.modified = append(s.modified, map[string]interface{}{"order_id": parts[2], "price": o["price"], "trigger_price": o["trigger_price"], "quantity": o["quantity"]})
+		data = map[string]interface{}{"order_id": parts[2]}
+
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		o, ok := s.orders[parts[2]]
+		if !ok || IsTerminalStatus(o["status"].(string)) || o["tradingsymbol"] == "LOCKED" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "error_type": OrderError, "message": "Order can't be cancelled"})
+			return
+		}
+		r.ParseForm()
+		o["status"] = OrderStatusCancelled
+
+		// Cancellations with a parent order id are recorded as id:parent.
+		id := parts[2]
+		if p := r.Form.Get("parent_order_id"); p != "" {
+			id += ":" + p
+		}
+		s.cancelled = append(s.cancelled, id)
+		data = map[string]interface{}{"order_id": parts[2]}
+
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		o, ok := s.orders[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data = append(append([]map[string]interface{}(nil), s.history[parts[1]]...), o)
+
+	case len(parts) == 3 && parts[2] == "trades":
+		o := s.orders[parts[1]]
+		data = []map[string]interface{}{{"order_id": parts[1], "trade_id": "t" + parts[1], "quantity": o["filled_quantity"], "average_price": 100}}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": data})
+}
+
+// seedOrders adds orders to the exchange server with ids in the order given.
+func (s *exchangeServer) seedOrders(orders ...map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range orders {
+		id := strconv.Itoa(len(s.orders) + 1)
+		o["order_id"] = id
+		s.orders[id] = o
+	}
+}
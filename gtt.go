@@ -31,7 +31,7 @@ type GTTMeta struct {
 
 // GTTCondition represents the condition inside a GTT order.
 type GTTCondition struct {
-	Exchange      string    `json:"exchange"`
+	Exchange      Exchange  `json:"exchange"`
 	Tradingsymbol string    `json:"tradingsymbol"`
 	LastPrice     float64   `json:"last_price"`
 	TriggerValues []float64 `json:"trigger_values"`
@@ -49,6 +49,45 @@ type GTT struct {
 	Condition GTTCondition `json:"condition"`
 	Orders    []Order      `json:"orders"`
 	Meta      GTTMeta      `json:"meta"`
+
+	// Results are the results of placing the orders of a triggered GTT, in the
+	// same order as Orders. The results of orders which weren't placed are empty.
+	Results []GTTOrderResult `json:"-"`
+}
+
+// GTTOrderResult is the result of placing an order of a triggered GTT.
+type GTTOrderResult struct {
+	OrderID         string `json:"order_id"`
+	Status          string `json:"status"`
+	RejectionReason string `json:"rejection_reason"`
+}
+
+// UnmarshalJSON unmarshals a GTT along with the results of its orders, which
+// are nested in the orders.
+func (g *GTT) UnmarshalJSON(b []byte) error {
+	type gtt GTT
+	if err := json.Unmarshal(b, (*gtt)(g)); err != nil {
+		return err
+	}
+
+	var res struct {
+		Orders []struct {
+			Result *struct {
+				OrderResult GTTOrderResult `json:"order_result"`
+			} `json:"result"`
+		} `json:"orders"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return err
+	}
+
+	g.Results = make([]GTTOrderResult, len(res.Orders))
+	for i, o := range res.Orders {
+		if o.Result != nil {
+			g.Results[i] = o.Result.OrderResult
+		}
+	}
+	return nil
 }
 
 // Trigger is an abstraction over multiple GTT types.
@@ -106,10 +145,10 @@ func (t *GTTOneCancelsOtherTrigger) Type() GTTType {
 // actual GTT before sending it to the API.
 type GTTParams struct {
 	Tradingsymbol   string
-	Exchange        string
+	Exchange        Exchange
 	LastPrice       float64
-	TransactionType string
-	Product         string
+	TransactionType TransactionType
+	Product         Product
 	Trigger         Trigger
 }
 
@@ -1,7 +1,10 @@
 package kiteconnect
 
 import (
+	"encoding/json"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func (ts *TestSuite) TestGetGTTs(t *testing.T) {
@@ -84,3 +87,39 @@ func (ts *TestSuite) TestDeleteGTT(t *testing.T) {
 		t.Errorf("Error while parsing order id in GTT order. %v", err)
 	}
 }
+
+func TestGTTResults(t *testing.T) {
+	t.Parallel()
+
+	data := `{
+		"id": 123,
+		"status": "triggered",
+		"orders": [
+			{"exchange": "NSE", "tradingsymbol": "INFY", "price": 95, "result": null},
+			{"exchange": "NSE", "tradingsymbol": "INFY", "price": 110, "result": {"order_result": {"order_id": "g1", "status": "success"}}}
+		]
+	}`
+
+	var gtt GTT
+	require.NoError(t, json.Unmarshal([]byte(data), &gtt))
+	require.Equal(t, 123, gtt.ID)
+	require.Len(t, gtt.Orders, 2)
+	require.Equal(t, 110.0, gtt.Orders[1].Price)
+	require.Equal(t, []GTTOrderResult{{}, {OrderID: "g1", Status: "success"}}, gtt.Results)
+
+	// The orders of an active GTT have no results.
+	data = `{
+		"id": 124,
+		"status": "active",
+		"orders": [
+			{"exchange": "NSE", "tradingsymbol": "INFY", "price": 95},
+			{"exchange": "NSE", "tradingsymbol": "INFY", "price": 110}
+		]
+	}`
+
+	gtt = GTT{}
+	require.NoError(t, json.Unmarshal([]byte(data), &gtt))
+	require.Equal(t, 124, gtt.ID)
+	require.Len(t, gtt.Orders, 2)
+	require.Equal(t, []GTTOrderResult{{}, {}}, gtt.Results)
+}
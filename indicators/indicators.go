@@ -0,0 +1,88 @@
+// Package indicators provides streaming technical indicators which operate on
+// historical candles and live candle updates.
+//
+// Every indicator is updated with one candle at a time using Update, which
+// makes it usable with candles from GetHistoricalData as well as completed
+// candles from a live candle builder. Batch computation over a slice of candles
+// is available through Apply and the Series functions of each indicator.
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+)
+
+// Indicator is a streaming indicator with a single value.
+type Indicator interface {
+	// Update adds the next candle and returns the latest value.
+	Update(c kiteconnect.HistoricalData) float64
+	// Value returns the latest value.
+	Value() float64
+	// Ready returns true once enough candles are added for the value to be valid.
+	Ready() bool
+}
+
+// Apply updates the indicator with all the candles and returns its value after
+// each candle. Values are NaN till the indicator is ready.
+func Apply(ind Indicator, data []kiteconnect.HistoricalData) []float64 {
+	out := make([]float64, len(data))
+	for i, c := range data {
+		v := ind.Update(c)
+		if !ind.Ready() {
+			v = math.NaN()
+		}
+		out[i] = v
+	}
+
+	return out
+}
+
+// Closes returns the close prices of candles.
+func Closes(data []kiteconnect.HistoricalData) []float64 {
+	out := make([]float64, len(data))
+	for i, c := range data {
+		out[i] = c.Close
+	}
+	return out
+}
+
+// TypicalPrice returns the average of high, low and close of a candle.
+func TypicalPrice(c kiteconnect.HistoricalData) float64 {
+	return (c.High + c.Low + c.Close) / 3
+}
+
+// checkPeriod panics if the period is invalid as it's a programming error.
+func checkPeriod(name string, period int) {
+	if period < 1 {
+		panic(fmt.Sprintf("indicators: %s period must be positive, got %d", name, period))
+	}
+}
+
+// window is a fixed size ring buffer of values.
+type window struct {
+	vals  []float64
+	pos   int
+	count int
+}
+
+func newWindow(size int) *window {
+	return &window{vals: make([]float64, size)}
+}
+
+// push adds a value and returns the value evicted, if the window was full.
+func (w *window) push(v float64) (float64, bool) {
+	old, full := w.vals[w.pos], w.count == len(w.vals)
+	w.vals[w.pos] = v
+	w.pos = (w.pos + 1) % len(w.vals)
+	if !full {
+		w.count++
+	}
+
+	return old, full
+}
+
+func (w *window) full() bool {
+	return w.count == len(w.vals)
+}
@@ -0,0 +1,92 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Reference datasets are from the StockCharts ChartSchool spreadsheets for
+// the respective indicators.
+var (
+	// 10 day EMA sample closes.
+	emaCloses = []float64{
+		22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+		22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+		23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+	}
+
+	// 14 day RSI sample closes.
+	rsiCloses = []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+		45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+		46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+		43.42, 42.66, 43.13,
+	}
+
+	// 14 day ATR sample high, low and close.
+	atrHLC = [][3]float64{
+		{48.70, 47.79, 48.16}, {48.72, 48.14, 48.61}, {48.90, 48.39, 48.75}, {48.87, 48.37, 48.63},
+		{48.82, 48.24, 48.74}, {49.05, 48.64, 49.03}, {49.20, 48.94, 49.07}, {49.35, 48.86, 49.32},
+		{49.92, 49.50, 49.91}, {50.19, 49.87, 50.13}, {50.12, 49.20, 49.53}, {49.66, 48.90, 49.50},
+		{49.88, 49.43, 49.75}, {50.19, 49.73, 50.03}, {50.36, 49.26, 50.31}, {50.57, 50.09, 50.52},
+		{50.65, 50.30, 50.41}, {50.43, 49.21, 49.34}, {49.63, 48.98, 49.37}, {50.33, 49.61, 50.23},
+		{50.29, 49.20, 49.24}, {50.17, 49.43, 49.93}, {49.32, 48.08, 48.43}, {48.50, 47.64, 48.18},
+		{48.32, 41.55, 46.57}, {46.80, 44.28, 45.41}, {47.80, 47.31, 47.77}, {48.39, 47.20, 47.72},
+		{48.66, 47.90, 48.62}, {48.79, 47.73, 47.85},
+	}
+)
+
+// fromCloses returns daily candles with the given closes.
+func fromCloses(closes []float64) []kiteconnect.HistoricalData {
+	out := make([]kiteconnect.HistoricalData, len(closes))
+	for i, c := range closes {
+		out[i] = kiteconnect.HistoricalData{
+			Date:  models.Time{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)},
+			Open:  c,
+			High:  c,
+			Low:   c,
+			Close: c,
+		}
+	}
+	return out
+}
+
+// fromHLC returns daily candles with the given high, low and close.
+func fromHLC(hlc [][3]float64) []kiteconnect.HistoricalData {
+	out := make([]kiteconnect.HistoricalData, len(hlc))
+	for i, v := range hlc {
+		out[i] = kiteconnect.HistoricalData{
+			Date:  models.Time{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)},
+			High:  v[0],
+			Low:   v[1],
+			Close: v[2],
+		}
+	}
+	return out
+}
+
+// requireSeries checks that the first skip values are NaN and the rest match exp.
+func requireSeries(t *testing.T, exp []float64, skip int, got []float64) {
+	t.Helper()
+	require.Len(t, got, skip+len(exp))
+	for i := 0; i < skip; i++ {
+		require.True(t, math.IsNaN(got[i]), "expected NaN at %d, got %v", i, got[i])
+	}
+	for i, v := range exp {
+		require.InDelta(t, v, got[skip+i], 1e-4, "value at %d", skip+i)
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	data := fromCloses([]float64{1, 2, 3, 4})
+	requireSeries(t, []float64{2, 3}, 2, Apply(NewSMA(3), data))
+	require.Equal(t, []float64{1, 2, 3, 4}, Closes(data))
+	require.Equal(t, 2.0, TypicalPrice(kiteconnect.HistoricalData{High: 3, Low: 1, Close: 2}))
+	require.Panics(t, func() { NewEMA(0) })
+}
@@ -0,0 +1,127 @@
+package indicators
+
+import (
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+)
+
+// SMA is the simple moving average of close prices.
+type SMA struct {
+	period int
+	win    *window
+	sum    float64
+	value  float64
+}
+
+// NewSMA creates a simple moving average. It panics if period is less than 1.
+func NewSMA(period int) *SMA {
+	checkPeriod("SMA", period)
+	return &SMA{period: period, win: newWindow(period)}
+}
+
+// Update adds the close of the next candle.
+func (s *SMA) Update(c kiteconnect.HistoricalData) float64 {
+	return s.UpdateValue(c.Close)
+}
+
+// UpdateValue adds the next value of the series.
+func (s *SMA) UpdateValue(v float64) float64 {
+	if old, full := s.win.push(v); full {
+		s.sum -= old
+	}
+	s.sum += v
+	s.value = s.sum / float64(s.win.count)
+
+	return s.value
+}
+
+// Value returns the latest average.
+func (s *SMA) Value() float64 {
+	return s.value
+}
+
+// Ready returns true once period values are added.
+func (s *SMA) Ready() bool {
+	return s.win.full()
+}
+
+// EMA is the exponential moving average of close prices. It's seeded with
+// the simple average of the first period values.
+type EMA struct {
+	period int
+	alpha  float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+// NewEMA creates an exponential moving average with the smoothing factor of
+// 2/(period+1). It panics if period is less than 1.
+func NewEMA(period int) *EMA {
+	checkPeriod("EMA", period)
+	return &EMA{period: period, alpha: 2 / float64(period+1)}
+}
+
+// Update adds the close of the next candle.
+func (e *EMA) Update(c kiteconnect.HistoricalData) float64 {
+	return e.UpdateValue(c.Close)
+}
+
+// UpdateValue adds the next value of the series.
+func (e *EMA) UpdateValue(v float64) float64 {
+	e.count++
+	if e.count <= e.period {
+		e.sum += v
+		e.value = e.sum / float64(e.count)
+		return e.value
+	}
+
+	e.value += e.alpha * (v - e.value)
+	return e.value
+}
+
+// Value returns the latest average.
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+// Ready returns true once period values are added.
+func (e *EMA) Ready() bool {
+	return e.count >= e.period
+}
+
+// rma is Wilder's moving average (also known as SMMA), seeded with the simple
+// average of the first period values.
+type rma struct {
+	period int
+	count  int
+	sum    float64
+	value  float64
+}
+
+func (r *rma) update(v float64) float64 {
+	r.count++
+	if r.count <= r.period {
+		r.sum += v
+		r.value = r.sum / float64(r.count)
+		return r.value
+	}
+
+	r.value = (r.value*float64(r.period-1) + v) / float64(r.period)
+	return r.value
+}
+
+func (r *rma) ready() bool {
+	return r.count >= r.period
+}
+
+// SMASeries returns the simple moving average of close prices after every
+// candle. Values are NaN till the average is ready.
+func SMASeries(data []kiteconnect.HistoricalData, period int) []float64 {
+	return Apply(NewSMA(period), data)
+}
+
+// EMASeries returns the exponential moving average of close prices after every
+// candle. Values are NaN till the average is ready.
+func EMASeries(data []kiteconnect.HistoricalData, period int) []float64 {
+	return Apply(NewEMA(period), data)
+}
@@ -0,0 +1,37 @@
+package indicators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMA(t *testing.T) {
+	t.Parallel()
+	exp := []float64{22.221, 22.209, 22.229, 22.259, 22.303, 22.421, 22.613, 22.765, 22.905, 23.076}
+	requireSeries(t, exp, 9, SMASeries(fromCloses(emaCloses[:19]), 10))
+
+	s := NewSMA(2)
+	require.False(t, s.Ready())
+	require.Equal(t, 4.0, s.UpdateValue(4))
+	require.Equal(t, 5.0, s.UpdateValue(6))
+	require.True(t, s.Ready())
+	require.Equal(t, 7.0, s.UpdateValue(8))
+	require.Equal(t, 7.0, s.Value())
+}
+
+func TestEMA(t *testing.T) {
+	t.Parallel()
+	exp := []float64{
+		22.221, 22.2081, 22.2412, 22.2664, 22.3289, 22.5164, 22.7952, 22.9688, 23.1254, 23.2753,
+		23.3398, 23.4271, 23.5076, 23.5335, 23.4711, 23.4036, 23.3902, 23.2611, 23.2318, 23.0806, 22.915,
+	}
+	requireSeries(t, exp, 9, EMASeries(fromCloses(emaCloses), 10))
+
+	e := NewEMA(10)
+	for _, c := range fromCloses(emaCloses) {
+		e.Update(c)
+	}
+	require.True(t, e.Ready())
+	require.InDelta(t, 22.915, e.Value(), 1e-3)
+}
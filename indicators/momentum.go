@@ -0,0 +1,160 @@
+package indicators
+
+import (
+	"math"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+)
+
+// RSI is Wilder's relative strength index of close prices.
+type RSI struct {
+	prev    float64
+	hasPrev bool
+	gain    rma
+	loss    rma
+	value   float64
+}
+
+// NewRSI creates a relative strength index. It panics if period is less than 1.
+func NewRSI(period int) *RSI {
+	checkPeriod("RSI", period)
+	return &RSI{gain: rma{period: period}, loss: rma{period: period}}
+}
+
+// Update adds the close of the next candle.
+func (r *RSI) Update(c kiteconnect.HistoricalData) float64 {
+	return r.UpdateValue(c.Close)
+}
+
+// UpdateValue adds the next value of the series.
+func (r *RSI) UpdateValue(v float64) float64 {
+	if !r.hasPrev {
+		r.prev, r.hasPrev = v, true
+		return r.value
+	}
+
+	ch := v - r.prev
+	r.prev = v
+
+	var (
+		g = r.gain.update(math.Max(ch, 0))
+		l = r.loss.update(math.Max(-ch, 0))
+	)
+
+	switch {
+	case l == 0:
+		r.value = 100
+	case g == 0:
+		r.value = 0
+	default:
+		r.value = 100 - 100/(1+g/l)
+	}
+
+	return r.value
+}
+
+// Value returns the latest index value between 0 and 100.
+func (r *RSI) Value() float64 {
+	return r.value
+}
+
+// Ready returns true once period changes, that is period+1 values, are added.
+func (r *RSI) Ready() bool {
+	return r.gain.ready()
+}
+
+// MACDValue represents the lines of the MACD indicator.
+type MACDValue struct {
+	MACD      float64
+	Signal    float64
+	Histogram float64
+}
+
+// MACD is the moving average convergence divergence of close prices.
+// Value returns the MACD line and Result returns all the lines.
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	value  MACDValue
+}
+
+// NewMACD creates a MACD with the given fast, slow and signal EMA periods,
+// commonly 12, 26 and 9. It panics if any period is less than 1.
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{
+		fast:   NewEMA(fast),
+		slow:   NewEMA(slow),
+		signal: NewEMA(signal),
+	}
+}
+
+// Update adds the close of the next candle.
+func (m *MACD) Update(c kiteconnect.HistoricalData) float64 {
+	return m.UpdateValue(c.Close)
+}
+
+// UpdateValue adds the next value of the series. The signal line starts
+// once the slow EMA is ready.
+func (m *MACD) UpdateValue(v float64) float64 {
+	var (
+		f = m.fast.UpdateValue(v)
+		s = m.slow.UpdateValue(v)
+	)
+
+	if !m.slow.Ready() {
+		return m.value.MACD
+	}
+
+	m.value.MACD = f - s
+	m.value.Signal = m.signal.UpdateValue(m.value.MACD)
+	m.value.Histogram = m.value.MACD - m.value.Signal
+
+	return m.value.MACD
+}
+
+// Value returns the latest MACD line value.
+func (m *MACD) Value() float64 {
+	return m.value.MACD
+}
+
+// Result returns the latest values of all the lines.
+func (m *MACD) Result() MACDValue {
+	return m.value
+}
+
+// Ready returns true once the signal line is ready.
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+// MACDSeries returns the MACD lines after every candle. The MACD line is NaN
+// till the slow EMA is ready and the signal and histogram are NaN till the
+// signal EMA is ready.
+func MACDSeries(data []kiteconnect.HistoricalData, fast, slow, signal int) []MACDValue {
+	var (
+		m   = NewMACD(fast, slow, signal)
+		out = make([]MACDValue, len(data))
+		nan = math.NaN()
+	)
+
+	for i, c := range data {
+		m.Update(c)
+		v := m.Result()
+		if !m.slow.Ready() {
+			v.MACD = nan
+		}
+		if !m.Ready() {
+			v.Signal, v.Histogram = nan, nan
+		}
+		out[i] = v
+	}
+
+	return out
+}
+
+// RSISeries returns the relative strength index of close prices after every
+// candle. Values are NaN till the index is ready.
+func RSISeries(data []kiteconnect.HistoricalData, period int) []float64 {
+	return Apply(NewRSI(period), data)
+}
@@ -0,0 +1,67 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSI(t *testing.T) {
+	t.Parallel()
+	exp := []float64{
+		70.4641, 66.2496, 66.4809, 69.3469, 66.2947, 57.915, 62.8807, 63.2088, 56.0116, 62.3399,
+		54.671, 50.3868, 40.0194, 41.4926, 41.9024, 45.4995, 37.3228, 33.0905, 37.7888,
+	}
+	requireSeries(t, exp, 14, RSISeries(fromCloses(rsiCloses), 14))
+
+	// No losses in the period.
+	r := NewRSI(2)
+	for _, v := range []float64{1, 2, 3} {
+		r.UpdateValue(v)
+	}
+	require.True(t, r.Ready())
+	require.Equal(t, 100.0, r.Value())
+	r.UpdateValue(3)
+	require.Equal(t, 100.0, r.Value())
+}
+
+func TestMACD(t *testing.T) {
+	t.Parallel()
+	var (
+		macd = []float64{
+			0.0474, 0.0209, 0.0415, 0.0487, 0.0845, 0.2126, 0.3741, 0.3941, 0.3932, 0.3871, 0.3118,
+			0.2806, 0.2542, 0.191, 0.0753, -0.006, -0.0152, -0.1177, -0.1029, -0.1946, -0.2677,
+		}
+		signal = []float64{
+			0.0396, 0.0576, 0.1196, 0.2214, 0.2904, 0.3315, 0.3538, 0.337, 0.3144, 0.2903,
+			0.2506, 0.1805, 0.1059, 0.0575, -0.0126, -0.0487, -0.1071, -0.1713,
+		}
+		out = MACDSeries(fromCloses(emaCloses), 5, 10, 4)
+	)
+
+	require.Len(t, out, len(emaCloses))
+	for i, v := range out {
+		if i < 9 {
+			require.True(t, math.IsNaN(v.MACD))
+			continue
+		}
+		require.InDelta(t, macd[i-9], v.MACD, 1e-4)
+
+		if i < 12 {
+			require.True(t, math.IsNaN(v.Signal))
+			require.True(t, math.IsNaN(v.Histogram))
+			continue
+		}
+		require.InDelta(t, signal[i-12], v.Signal, 1e-4)
+		require.InDelta(t, v.MACD-v.Signal, v.Histogram, 1e-9)
+	}
+
+	m := NewMACD(5, 10, 4)
+	for _, c := range fromCloses(emaCloses) {
+		m.Update(c)
+	}
+	require.True(t, m.Ready())
+	require.InDelta(t, -0.2677, m.Value(), 1e-4)
+	require.InDelta(t, -0.1713, m.Result().Signal, 1e-4)
+}
@@ -0,0 +1,239 @@
+package indicators
+
+import (
+	"math"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+)
+
+// ATR is Wilder's average true range.
+type ATR struct {
+	prevClose float64
+	hasPrev   bool
+	avg       rma
+}
+
+// NewATR creates an average true range. It panics if period is less than 1.
+func NewATR(period int) *ATR {
+	checkPeriod("ATR", period)
+	return &ATR{avg: rma{period: period}}
+}
+
+// Update adds the next candle.
+func (a *ATR) Update(c kiteconnect.HistoricalData) float64 {
+	tr := c.High - c.Low
+	if a.hasPrev {
+		tr = math.Max(tr, math.Max(math.Abs(c.High-a.prevClose), math.Abs(c.Low-a.prevClose)))
+	}
+	a.prevClose, a.hasPrev = c.Close, true
+
+	return a.avg.update(tr)
+}
+
+// Value returns the latest average true range.
+func (a *ATR) Value() float64 {
+	return a.avg.value
+}
+
+// Ready returns true once period candles are added.
+func (a *ATR) Ready() bool {
+	return a.avg.ready()
+}
+
+// BollingerValue represents the Bollinger bands.
+type BollingerValue struct {
+	Middle float64
+	Upper  float64
+	Lower  float64
+}
+
+// Bollinger is the Bollinger bands of close prices using the population
+// standard deviation. Value returns the middle band and Result returns all
+// the bands.
+type Bollinger struct {
+	k     float64
+	win   *window
+	sma   *SMA
+	value BollingerValue
+}
+
+// NewBollinger creates Bollinger bands placed k standard deviations away from
+// the period SMA, commonly 20 and 2. It panics if period is less than 1.
+func NewBollinger(period int, k float64) *Bollinger {
+	checkPeriod("Bollinger", period)
+	return &Bollinger{k: k, win: newWindow(period), sma: NewSMA(period)}
+}
+
+// Update adds the close of the next candle.
+func (b *Bollinger) Update(c kiteconnect.HistoricalData) float64 {
+	return b.UpdateValue(c.Close)
+}
+
+// UpdateValue adds the next value of the series.
+func (b *Bollinger) UpdateValue(v float64) float64 {
+	b.win.push(v)
+	mean := b.sma.UpdateValue(v)
+
+	var ss float64
+	for i := 0; i < b.win.count; i++ {
+		d := b.win.vals[i] - mean
+		ss += d * d
+	}
+	sd := math.Sqrt(ss / float64(b.win.count))
+
+	b.value = BollingerValue{
+		Middle: mean,
+		Upper:  mean + b.k*sd,
+		Lower:  mean - b.k*sd,
+	}
+
+	return mean
+}
+
+// Value returns the latest middle band.
+func (b *Bollinger) Value() float64 {
+	return b.value.Middle
+}
+
+// Result returns the latest bands.
+func (b *Bollinger) Result() BollingerValue {
+	return b.value
+}
+
+// Ready returns true once period values are added.
+func (b *Bollinger) Ready() bool {
+	return b.win.full()
+}
+
+// SuperTrendValue represents the SuperTrend line and its bands.
+type SuperTrendValue struct {
+	// Value is the lower band in an uptrend and the upper band in a downtrend.
+	Value   float64
+	Upper   float64
+	Lower   float64
+	Uptrend bool
+}
+
+// SuperTrend is the ATR based trend following indicator. Value returns
+// the SuperTrend line and Result returns the bands and the trend direction.
+type SuperTrend struct {
+	atr        *ATR
+	multiplier float64
+	prevClose  float64
+	started    bool
+	value      SuperTrendValue
+}
+
+// NewSuperTrend creates a SuperTrend with an ATR of the given period and
+// the bands placed multiplier times the ATR away from the candle's median price,
+// commonly 10 and 3. It panics if period is less than 1.
+func NewSuperTrend(period int, multiplier float64) *SuperTrend {
+	return &SuperTrend{atr: NewATR(period), multiplier: multiplier}
+}
+
+// Update adds the next candle. The trend starts as a downtrend on the first
+// candle on which the ATR is ready.
+func (s *SuperTrend) Update(c kiteconnect.HistoricalData) float64 {
+	prevClose := s.prevClose
+	s.prevClose = c.Close
+
+	atr := s.atr.Update(c)
+	if !s.atr.Ready() {
+		return s.value.Value
+	}
+
+	var (
+		hl2   = (c.High + c.Low) / 2
+		upper = hl2 + s.multiplier*atr
+		lower = hl2 - s.multiplier*atr
+	)
+
+	if !s.started {
+		s.started = true
+		s.value = SuperTrendValue{Value: upper, Upper: upper, Lower: lower}
+		return s.value.Value
+	}
+
+	// Bands only move towards the price unless the previous close crossed them.
+	if upper > s.value.Upper && prevClose <= s.value.Upper {
+		upper = s.value.Upper
+	}
+	if lower < s.value.Lower && prevClose >= s.value.Lower {
+		lower = s.value.Lower
+	}
+
+	up := c.Close > upper
+	if s.value.Uptrend {
+		up = c.Close >= lower
+	}
+
+	s.value = SuperTrendValue{Value: upper, Upper: upper, Lower: lower, Uptrend: up}
+	if up {
+		s.value.Value = lower
+	}
+
+	return s.value.Value
+}
+
+// Value returns the latest SuperTrend line.
+func (s *SuperTrend) Value() float64 {
+	return s.value.Value
+}
+
+// Result returns the latest bands and trend.
+func (s *SuperTrend) Result() SuperTrendValue {
+	return s.value
+}
+
+// Ready returns true once the ATR is ready.
+func (s *SuperTrend) Ready() bool {
+	return s.started
+}
+
+// ATRSeries returns the average true range after every candle. Values are NaN
+// till the average is ready.
+func ATRSeries(data []kiteconnect.HistoricalData, period int) []float64 {
+	return Apply(NewATR(period), data)
+}
+
+// BollingerSeries returns the Bollinger bands after every candle. Values are
+// NaN till the bands are ready.
+func BollingerSeries(data []kiteconnect.HistoricalData, period int, k float64) []BollingerValue {
+	var (
+		b   = NewBollinger(period, k)
+		out = make([]BollingerValue, len(data))
+		nan = math.NaN()
+	)
+
+	for i, c := range data {
+		b.Update(c)
+		if !b.Ready() {
+			out[i] = BollingerValue{Middle: nan, Upper: nan, Lower: nan}
+			continue
+		}
+		out[i] = b.Result()
+	}
+
+	return out
+}
+
+// SuperTrendSeries returns the SuperTrend after every candle. Values are NaN
+// till the SuperTrend is ready.
+func SuperTrendSeries(data []kiteconnect.HistoricalData, period int, multiplier float64) []SuperTrendValue {
+	var (
+		s   = NewSuperTrend(period, multiplier)
+		out = make([]SuperTrendValue, len(data))
+		nan = math.NaN()
+	)
+
+	for i, c := range data {
+		s.Update(c)
+		if !s.Ready() {
+			out[i] = SuperTrendValue{Value: nan, Upper: nan, Lower: nan}
+			continue
+		}
+		out[i] = s.Result()
+	}
+
+	return out
+}
@@ -0,0 +1,71 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestATR(t *testing.T) {
+	t.Parallel()
+	exp := []float64{
+		0.5543, 0.5933, 0.5852, 0.5684, 0.6149, 0.6174, 0.6419, 0.6739, 0.6922,
+		0.7749, 0.781, 1.2088, 1.3024, 1.3801, 1.3665, 1.3361, 1.3163,
+	}
+	requireSeries(t, exp, 13, ATRSeries(fromHLC(atrHLC), 14))
+}
+
+func TestBollinger(t *testing.T) {
+	t.Parallel()
+	exp := []BollingerValue{
+		{22.7155, 24.1261, 21.3049}, {22.793, 24.2661, 21.3199}, {22.877, 24.3939, 21.3601},
+		{22.9555, 24.4617, 21.4493}, {23.0065, 24.4714, 21.5416}, {23.0525, 24.4676, 21.6374},
+		{23.1125, 24.4665, 21.7585}, {23.135, 24.4438, 21.8262}, {23.1685, 24.4371, 21.8999},
+		{23.1765, 24.4234, 21.9296}, {23.1705, 24.4355, 21.9055},
+	}
+
+	out := BollingerSeries(fromCloses(emaCloses), 20, 2)
+	require.Len(t, out, len(emaCloses))
+	for i, v := range out {
+		if i < 19 {
+			require.True(t, math.IsNaN(v.Middle))
+			continue
+		}
+		e := exp[i-19]
+		require.InDelta(t, e.Middle, v.Middle, 1e-4)
+		require.InDelta(t, e.Upper, v.Upper, 1e-4)
+		require.InDelta(t, e.Lower, v.Lower, 1e-4)
+	}
+}
+
+func TestSuperTrend(t *testing.T) {
+	t.Parallel()
+	exp := []struct {
+		value float64
+		up    bool
+	}{
+		{50.546, false}, {50.2174, false}, {49.8577, false}, {49.8577, false}, {49.4056, true},
+		{49.4056, true}, {49.7339, true}, {49.9035, true}, {50.4563, false}, {49.9427, false},
+		{49.3001, true}, {50.4569, false}, {50.4569, false}, {49.5454, false}, {48.9168, false},
+		{47.2232, true}, {43.9928, true}, {45.9235, true}, {46.2076, true}, {46.7574, true},
+		{46.7836, true},
+	}
+
+	out := SuperTrendSeries(fromHLC(atrHLC), 10, 1)
+	require.Len(t, out, len(atrHLC))
+	for i, v := range out {
+		if i < 9 {
+			require.True(t, math.IsNaN(v.Value))
+			continue
+		}
+		e := exp[i-9]
+		require.InDelta(t, e.value, v.Value, 1e-4, "value at %d", i)
+		require.Equal(t, e.up, v.Uptrend, "trend at %d", i)
+		if v.Uptrend {
+			require.Equal(t, v.Lower, v.Value)
+		} else {
+			require.Equal(t, v.Upper, v.Value)
+		}
+	}
+}
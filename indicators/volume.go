@@ -0,0 +1,60 @@
+package indicators
+
+import (
+	"time"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/candles"
+)
+
+// VWAP is the volume weighted average of the typical price of intraday
+// candles. It's anchored to the trading day and resets on the first candle
+// of every day in IST.
+type VWAP struct {
+	day    time.Time
+	pv     float64
+	volume float64
+	value  float64
+	ready  bool
+}
+
+// NewVWAP creates a day anchored volume weighted average price.
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+// Update adds the next candle. The typical price is used as is for candles
+// without volume till the first traded candle of the day.
+func (v *VWAP) Update(c kiteconnect.HistoricalData) float64 {
+	if day := candles.NSESession.Day(c.Date.Time); !day.Equal(v.day) {
+		v.day, v.pv, v.volume = day, 0, 0
+	}
+
+	tp := TypicalPrice(c)
+	v.pv += tp * float64(c.Volume)
+	v.volume += float64(c.Volume)
+	v.ready = true
+
+	if v.volume > 0 {
+		v.value = v.pv / v.volume
+	} else {
+		v.value = tp
+	}
+
+	return v.value
+}
+
+// Value returns the latest average price.
+func (v *VWAP) Value() float64 {
+	return v.value
+}
+
+// Ready returns true once a candle is added.
+func (v *VWAP) Ready() bool {
+	return v.ready
+}
+
+// VWAPSeries returns the volume weighted average price after every candle.
+func VWAPSeries(data []kiteconnect.HistoricalData) []float64 {
+	return Apply(NewVWAP(), data)
+}
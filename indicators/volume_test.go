@@ -0,0 +1,36 @@
+package indicators
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/candles"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func TestVWAP(t *testing.T) {
+	t.Parallel()
+	at := func(d, m int) models.Time {
+		return models.Time{Time: time.Date(2021, 7, d, 9, 15+m, 0, 0, candles.IST)}
+	}
+
+	data := []kiteconnect.HistoricalData{
+		{Date: at(5, 0), High: 12, Low: 9, Close: 9, Volume: 0},
+		{Date: at(5, 1), High: 12, Low: 9, Close: 12, Volume: 100},
+		{Date: at(5, 2), High: 15, Low: 12, Close: 15, Volume: 300},
+		// New day resets the average.
+		{Date: at(6, 0), High: 21, Low: 18, Close: 21, Volume: 50},
+	}
+
+	out := VWAPSeries(data)
+	require.InDeltaSlice(t, []float64{10, 11, 13.25, 20}, out, 1e-9)
+
+	// A late evening candle in UTC is on the next IST day.
+	v := NewVWAP()
+	v.Update(kiteconnect.HistoricalData{Date: models.Time{Time: time.Date(2021, 7, 5, 10, 0, 0, 0, time.UTC)}, High: 1, Low: 1, Close: 1, Volume: 10})
+	v.Update(kiteconnect.HistoricalData{Date: models.Time{Time: time.Date(2021, 7, 5, 19, 0, 0, 0, time.UTC)}, High: 3, Low: 3, Close: 3, Volume: 10})
+	require.Equal(t, 3.0, v.Value())
+	require.True(t, v.Ready())
+}
@@ -8,28 +8,28 @@ import (
 
 // OrderMarginParam represents an order in the Margin Calculator API
 type OrderMarginParam struct {
-	Exchange        string  `json:"exchange"`
-	Tradingsymbol   string  `json:"tradingsymbol"`
-	TransactionType string  `json:"transaction_type"`
-	Variety         string  `json:"variety"`
-	Product         string  `json:"product"`
-	OrderType       string  `json:"order_type"`
-	Quantity        float64 `json:"quantity"`
-	Price           float64 `json:"price,omitempty"`
-	TriggerPrice    float64 `json:"trigger_price,omitempty"`
+	Exchange        Exchange        `json:"exchange"`
+	Tradingsymbol   string          `json:"tradingsymbol"`
+	TransactionType TransactionType `json:"transaction_type"`
+	Variety         Variety         `json:"variety"`
+	Product         Product         `json:"product"`
+	OrderType       OrderType       `json:"order_type"`
+	Quantity        float64         `json:"quantity"`
+	Price           float64         `json:"price,omitempty"`
+	TriggerPrice    float64         `json:"trigger_price,omitempty"`
 }
 
 // OrderChargesParam represents an order in the Charges Calculator API
 type OrderChargesParam struct {
-	OrderID         string  `json:"order_id"`
-	Exchange        string  `json:"exchange"`
-	Tradingsymbol   string  `json:"tradingsymbol"`
-	TransactionType string  `json:"transaction_type"`
-	Variety         string  `json:"variety"`
-	Product         string  `json:"product"`
-	OrderType       string  `json:"order_type"`
-	Quantity        float64 `json:"quantity"`
-	AveragePrice    float64 `json:"average_price"`
+	OrderID         string          `json:"order_id"`
+	Exchange        Exchange        `json:"exchange"`
+	Tradingsymbol   string          `json:"tradingsymbol"`
+	TransactionType TransactionType `json:"transaction_type"`
+	Variety         Variety         `json:"variety"`
+	Product         Product         `json:"product"`
+	OrderType       OrderType       `json:"order_type"`
+	Quantity        float64         `json:"quantity"`
+	AveragePrice    float64         `json:"average_price"`
 }
 
 // PNL represents the PNL
@@ -40,9 +40,9 @@ type PNL struct {
 
 // OrdersMargins represents response from the Margin Calculator API.
 type OrderMargins struct {
-	Type          string `json:"type"`
-	TradingSymbol string `json:"tradingsymbol"`
-	Exchange      string `json:"exchange"`
+	Type          string   `json:"type"`
+	TradingSymbol string   `json:"tradingsymbol"`
+	Exchange      Exchange `json:"exchange"`
 
 	SPAN          float64 `json:"span"`
 	Exposure      float64 `json:"exposure"`
@@ -59,15 +59,15 @@ type OrderMargins struct {
 
 // OrderCharges represent an item's response from the Charges calculator API
 type OrderCharges struct {
-	Exchange        string  `json:"exchange"`
-	Tradingsymbol   string  `json:"tradingsymbol"`
-	TransactionType string  `json:"transaction_type"`
-	Variety         string  `json:"variety"`
-	Product         string  `json:"product"`
-	OrderType       string  `json:"order_type"`
-	Quantity        float64 `json:"quantity"`
-	Price           float64 `json:"price"`
-	Charges         Charges `json:"charges"`
+	Exchange        Exchange        `json:"exchange"`
+	Tradingsymbol   string          `json:"tradingsymbol"`
+	TransactionType TransactionType `json:"transaction_type"`
+	Variety         Variety         `json:"variety"`
+	Product         Product         `json:"product"`
+	OrderType       OrderType       `json:"order_type"`
+	Quantity        float64         `json:"quantity"`
+	Price           float64         `json:"price"`
+	Charges         Charges         `json:"charges"`
 }
 
 // Charges represents breakdown of various charges that are applied to an order
@@ -23,23 +23,23 @@ type Order struct {
 	OrderTimestamp          models.Time            `json:"order_timestamp"`
 	ExchangeUpdateTimestamp models.Time            `json:"exchange_update_timestamp"`
 	ExchangeTimestamp       models.Time            `json:"exchange_timestamp"`
-	Variety                 string                 `json:"variety"`
+	Variety                 Variety                `json:"variety"`
 	Modified                bool                   `json:"modified"`
 	Meta                    map[string]interface{} `json:"meta"`
 
-	Exchange        string `json:"exchange"`
-	TradingSymbol   string `json:"tradingsymbol"`
-	InstrumentToken uint32 `json:"instrument_token"`
+	Exchange        Exchange `json:"exchange"`
+	TradingSymbol   string   `json:"tradingsymbol"`
+	InstrumentToken uint32   `json:"instrument_token"`
 
-	OrderType         string  `json:"order_type"`
-	TransactionType   string  `json:"transaction_type"`
-	Validity          string  `json:"validity"`
-	ValidityTTL       int     `json:"validity_ttl"`
-	Product           string  `json:"product"`
-	Quantity          float64 `json:"quantity"`
-	DisclosedQuantity float64 `json:"disclosed_quantity"`
-	Price             float64 `json:"price"`
-	TriggerPrice      float64 `json:"trigger_price"`
+	OrderType         OrderType       `json:"order_type"`
+	TransactionType   TransactionType `json:"transaction_type"`
+	Validity          Validity        `json:"validity"`
+	ValidityTTL       int             `json:"validity_ttl"`
+	Product           Product         `json:"product"`
+	Quantity          float64         `json:"quantity"`
+	DisclosedQuantity float64         `json:"disclosed_quantity"`
+	Price             float64         `json:"price"`
+	TriggerPrice      float64         `json:"trigger_price"`
 
 	AveragePrice      float64 `json:"average_price"`
 	FilledQuantity    float64 `json:"filled_quantity"`
@@ -59,13 +59,13 @@ type Orders []Order
 
 // OrderParams represents parameters for placing an order.
 type OrderParams struct {
-	Exchange        string `url:"exchange,omitempty"`
-	Tradingsymbol   string `url:"tradingsymbol,omitempty"`
-	Validity        string `url:"validity,omitempty"`
-	ValidityTTL     int    `url:"validity_ttl,omitempty"`
-	Product         string `url:"product,omitempty"`
-	OrderType       string `url:"order_type,omitempty"`
-	TransactionType string `url:"transaction_type,omitempty"`
+	Exchange        Exchange        `url:"exchange,omitempty"`
+	Tradingsymbol   string          `url:"tradingsymbol,omitempty"`
+	Validity        Validity        `url:"validity,omitempty"`
+	ValidityTTL     int             `url:"validity_ttl,omitempty"`
+	Product         Product         `url:"product,omitempty"`
+	OrderType       OrderType       `url:"order_type,omitempty"`
+	TransactionType TransactionType `url:"transaction_type,omitempty"`
 
 	Quantity          int     `url:"quantity,omitempty"`
 	DisclosedQuantity int     `url:"disclosed_quantity,omitempty"`
@@ -161,7 +161,7 @@ func (c *Client) GetOrderTrades(OrderID string) ([]Trade, error) {
 }
 
 // PlaceOrder places an order.
-func (c *Client) PlaceOrder(variety string, orderParams OrderParams) (OrderResponse, error) {
+func (c *Client) PlaceOrder(variety Variety, orderParams OrderParams) (OrderResponse, error) {
 	var (
 		orderResponse OrderResponse
 		params        url.Values
@@ -177,7 +177,7 @@ func (c *Client) PlaceOrder(variety string, orderParams OrderParams) (OrderRespo
 }
 
 // ModifyOrder modifies an order.
-func (c *Client) ModifyOrder(variety string, orderID string, orderParams OrderParams) (OrderResponse, error) {
+func (c *Client) ModifyOrder(variety Variety, orderID string, orderParams OrderParams) (OrderResponse, error) {
 	var (
 		orderResponse OrderResponse
 		params        url.Values
@@ -193,7 +193,7 @@ func (c *Client) ModifyOrder(variety string, orderID string, orderParams OrderPa
 }
 
 // CancelOrder cancels/exits an order.
-func (c *Client) CancelOrder(variety string, orderID string, parentOrderID *string) (OrderResponse, error) {
+func (c *Client) CancelOrder(variety Variety, orderID string, parentOrderID *string) (OrderResponse, error) {
 	var (
 		orderResponse OrderResponse
 		params        url.Values
@@ -201,7 +201,7 @@ func (c *Client) CancelOrder(variety string, orderID string, parentOrderID *stri
 
 	if parentOrderID != nil {
 		// initialize the params map first
-		params := url.Values{}
+		params = url.Values{}
 		params.Add("parent_order_id", *parentOrderID)
 	}
 
@@ -210,6 +210,6 @@ func (c *Client) CancelOrder(variety string, orderID string, parentOrderID *stri
 }
 
 // ExitOrder is an alias for CancelOrder which is used to cancel/exit an order.
-func (c *Client) ExitOrder(variety string, orderID string, parentOrderID *string) (OrderResponse, error) {
+func (c *Client) ExitOrder(variety Variety, orderID string, parentOrderID *string) (OrderResponse, error) {
 	return c.CancelOrder(variety, orderID, parentOrderID)
 }
@@ -89,10 +89,10 @@ type HoldingSummary struct {
 
 // Position represents an individual position response.
 type Position struct {
-	Tradingsymbol   string `json:"tradingsymbol"`
-	Exchange        string `json:"exchange"`
-	InstrumentToken uint32 `json:"instrument_token"`
-	Product         string `json:"product"`
+	Tradingsymbol   string   `json:"tradingsymbol"`
+	Exchange        Exchange `json:"exchange"`
+	InstrumentToken uint32   `json:"instrument_token"`
+	Product         Product  `json:"product"`
 
 	Quantity          int     `json:"quantity"`
 	OvernightQuantity int     `json:"overnight_quantity"`
@@ -0,0 +1,63 @@
+package kiteconnect
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// orderRateLimit is the number of order requests allowed per second.
+const orderRateLimit = 10
+
+// rateLimiter is a token bucket which allows a number of requests per second
+// with bursts of up to its capacity. It's safe for concurrent use.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perSecond, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(perSecond),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks till a request is allowed or the context is done.
+// A nil limiter allows all requests.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		d := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package kiteconnect
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	// defaultSquareOffLimitBuffer is the default percentage by which the limit
+	// price of a marketable limit order is away from the last price.
+	defaultSquareOffLimitBuffer = 0.5
+	// defaultTickSize is the tick size used when the instrument isn't known.
+	defaultTickSize = 0.05
+)
+
+// SquareOffPricing represents how the orders squaring off positions are priced.
+type SquareOffPricing int
+
+const (
+	// SquareOffMarket squares off with MARKET orders.
+	SquareOffMarket SquareOffPricing = iota
+	// SquareOffLimit squares off with marketable LIMIT orders, priced at a
+	// buffer beyond the last price so that they fill immediately without the
+	// risk of filling at any price.
+	SquareOffLimit
+)
+
+// SquareOffParams represents the positions to square off and how.
+type SquareOffParams struct {
+	// Products, Exchanges and Tradingsymbols filter the positions. Empty
+	// fields match all positions.
+	Products       []Product
+	Exchanges      []Exchange
+	Tradingsymbols []string
+
+	Pricing SquareOffPricing
+	// MarketProtection is the market protection of MARKET orders, eg: MarketProtectionAuto.
+	MarketProtection float64
+	// LimitBuffer is the percentage by which the price of LIMIT orders is
+	// beyond the last price. Defaults to 0.5%.
+	LimitBuffer float64
+
+	// Autoslice lets the exchange split orders above the freeze quantity.
+	Autoslice bool
+	// FreezeQuantities are the largest quantities allowed in a single order by
+	// exchange:tradingsymbol. Positions above them are squared off with multiple
+	// orders, unless Autoslice is set.
+	FreezeQuantities map[string]int
+	// Instruments are used for the lot sizes and tick sizes of the positions.
+	Instruments Instruments
+
+	Tag string
+	// DryRun returns the orders which would square off the positions without placing them.
+	DryRun bool
+}
+
+// SquareOffOrder is an order squaring off (a part of) a position.
+type SquareOffOrder struct {
+	Position Position
+	Params   OrderParams
+	Response OrderResponse
+	Err      error
+}
+
+// SquareOffResult is the result of squaring off positions.
+type SquareOffResult struct {
+	Orders []SquareOffOrder
+}
+
+// Failed returns the orders which couldn't be placed.
+func (r SquareOffResult) Failed() []SquareOffOrder {
+	var out []SquareOffOrder
+	for _, o := range r.Orders {
+		if o.Err != nil {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// SquareOff squares off the open net positions which match the filters by
+// placing opposite orders of their quantities. Orders are placed concurrently
+// within the order rate limit and their errors are in the result, including the
+// context's error for the orders which weren't placed as it was done. The error
+// is returned only if the positions or prices couldn't be fetched or the context
+// is done.
+func (c *Client) SquareOff(ctx context.Context, p SquareOffParams) (SquareOffResult, error) {
+	var result SquareOffResult
+
+	positions, err := c.GetPositions()
+	if err != nil {
+		return result, err
+	}
+
+	var open []Position
+	for _, pos := range positions.Net {
+		if pos.Quantity != 0 && p.match(pos) {
+			open = append(open, pos)
+		}
+	}
+	if len(open) == 0 {
+		return result, nil
+	}
+
+	var ltp QuoteLTP
+	if p.Pricing == SquareOffLimit {
+		keys := make([]string, 0, len(open))
+		for _, pos := range open {
+			keys = append(keys, positionKey(pos))
+		}
+		if ltp, err = c.GetLTP(keys...); err != nil {
+			return result, err
+		}
+	}
+
+	instruments := map[string]Instrument{}
+	for _, i := range p.Instruments {
+		instruments[i.Exchange+":"+i.Tradingsymbol] = i
+	}
+
+	for _, pos := range open {
+		for _, params := range p.orders(pos, instruments[positionKey(pos)], ltp) {
+			result.Orders = append(result.Orders, SquareOffOrder{Position: pos, Params: params})
+		}
+	}
+
+	if p.DryRun {
+		return result, nil
+	}
+
+	attempted := make([]bool, len(result.Orders))
+	runBulk(ctx, len(result.Orders), func(i int) {
+		attempted[i] = true
+		o := &result.Orders[i]
+		if o.Err = c.orderLimiter.wait(ctx); o.Err != nil {
+			return
+		}
+		o.Response, o.Err = c.PlaceOrder(VarietyRegular, o.Params)
+	})
+
+	// Orders which weren't attempted as the context was done are failed too,
+	// so that their positions aren't taken to be closed.
+	for i, ok := range attempted {
+		if !ok {
+			result.Orders[i].Err = ctx.Err()
+		}
+	}
+
+	return result, ctx.Err()
+}
+
+// match returns true if the position matches the filters.
+func (p SquareOffParams) match(pos Position) bool {
+	f := OrderFilter{Exchanges: p.Exchanges, Products: p.Products, Tradingsymbols: p.Tradingsymbols}
+	return f.Match(Order{Exchange: pos.Exchange, Product: pos.Product, TradingSymbol: pos.Tradingsymbol})
+}
+
+// orders returns the orders which square off a position.
+func (p SquareOffParams) orders(pos Position, inst Instrument, ltp QuoteLTP) []OrderParams {
+	base := OrderParams{
+		Exchange:        pos.Exchange,
+		Tradingsymbol:   pos.Tradingsymbol,
+		Validity:        ValidityDay,
+		Product:         pos.Product,
+		OrderType:       OrderTypeMarket,
+		TransactionType: TransactionTypeSell,
+		Autoslice:       p.Autoslice,
+		Tag:             p.Tag,
+	}
+
+	qty := pos.Quantity
+	if qty < 0 {
+		qty = -qty
+		base.TransactionType = TransactionTypeBuy
+	}
+
+	if p.Pricing == SquareOffLimit {
+		last := pos.LastPrice
+		if q, ok := ltp[positionKey(pos)]; ok && q.LastPrice > 0 {
+			last = q.LastPrice
+		}
+
+		buffer := p.LimitBuffer
+		if buffer <= 0 {
+			buffer = defaultSquareOffLimitBuffer
+		}
+		tick := inst.TickSize
+		if tick <= 0 {
+			tick = defaultTickSize
+		}
+
+		base.OrderType = OrderTypeLimit
+		if base.TransactionType == TransactionTypeBuy {
+			base.Price = roundToTick(last*(1+buffer/100), tick, math.Ceil)
+		} else {
+			base.Price = roundToTick(last*(1-buffer/100), tick, math.Floor)
+		}
+	} else {
+		base.MarketProtection = p.MarketProtection
+	}
+
+	// Orders are sliced at the freeze quantity rounded down to the lot size,
+	// but never below a lot.
+	slice := p.FreezeQuantities[positionKey(pos)]
+	if lot := int(inst.LotSize); lot > 1 && slice > 0 {
+		slice -= slice % lot
+		if slice < lot {
+			slice = lot
+		}
+	}
+	if p.Autoslice || slice <= 0 {
+		slice = qty
+	}
+
+	var out []OrderParams
+	for qty > 0 {
+		o := base
+		o.Quantity = slice
+		if qty < slice {
+			o.Quantity = qty
+		}
+		qty -= o.Quantity
+		out = append(out, o)
+	}
+	return out
+}
+
+// positionKey returns the exchange:tradingsymbol of a position.
+func positionKey(pos Position) string {
+	return string(pos.Exchange) + ":" + pos.Tradingsymbol
+}
+
+// roundToTick rounds a price to a multiple of the tick size in the direction of round.
+func roundToTick(price, tick float64, round func(float64) float64) float64 {
+	// Prices which are a multiple of the tick apart from float errors are kept as they are.
+	steps := price / tick
+	if r := math.Round(steps); math.Abs(steps-r) < 1e-9 {
+		steps = r
+	}
+	return math.Round(round(steps)*tick*1e6) / 1e6
+}
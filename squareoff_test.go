@@ -0,0 +1,144 @@
+package kiteconnect
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func squareOffPositions() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"exchange": "NSE", "tradingsymbol": "INFY", "product": "MIS", "quantity": 10, "last_price": 1500},
+		{"exchange": "NFO", "tradingsymbol": "NIFTYFUT", "product": "NRML", "quantity": -4000, "last_price": 21000},
+		{"exchange": "NSE", "tradingsymbol": "SBIN", "product": "CNC", "quantity": 0, "last_price": 600},
+		{"exchange": "NSE", "tradingsymbol": "TCS", "product": "CNC", "quantity": 5, "last_price": 3500},
+	}
+}
+
+func squareOffSummary(r SquareOffResult) []string {
+	var out []string
+	for _, o := range r.Orders {
+		out = append(out, o.Params.TransactionType.String()+" "+o.Params.Tradingsymbol)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestSquareOff(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.positions = squareOffPositions()
+
+	res, err := c.SquareOff(context.Background(), SquareOffParams{
+		MarketProtection: MarketProtectionAuto,
+		Autoslice:        true,
+		Tag:              "kill",
+	})
+	require.NoError(t, err)
+	require.Empty(t, res.Failed())
+	require.Equal(t, []string{"BUY NIFTYFUT", "SELL INFY", "SELL TCS"}, squareOffSummary(res))
+	require.Len(t, s.placed, 3)
+
+	for _, o := range res.Orders {
+		require.NotEmpty(t, o.Response.OrderID)
+		require.Equal(t, OrderType(OrderTypeMarket), o.Params.OrderType)
+		require.Equal(t, float64(MarketProtectionAuto), o.Params.MarketProtection)
+		require.True(t, o.Params.Autoslice)
+		require.Equal(t, "kill", o.Params.Tag)
+		require.Equal(t, o.Position.Product, o.Params.Product)
+	}
+}
+
+func TestSquareOffDryRun(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.positions = squareOffPositions()
+	s.ltp = map[string]float64{"NFO:NIFTYFUT": 21010.4}
+
+	// Derivatives are sliced at the freeze quantity rounded down to the lot size
+	// and priced with the tick size of the instrument.
+	res, err := c.SquareOff(context.Background(), SquareOffParams{
+		Exchanges:        []Exchange{ExchangeNFO},
+		Pricing:          SquareOffLimit,
+		LimitBuffer:      1,
+		FreezeQuantities: map[string]int{"NFO:NIFTYFUT": 1800},
+		Instruments:      Instruments{{Exchange: "NFO", Tradingsymbol: "NIFTYFUT", LotSize: 25, TickSize: 0.1}},
+		DryRun:           true,
+	})
+	require.NoError(t, err)
+	require.Empty(t, s.placed)
+	require.Len(t, res.Orders, 3)
+
+	var qty []int
+	for _, o := range res.Orders {
+		qty = append(qty, o.Params.Quantity)
+		require.Equal(t, OrderType(OrderTypeLimit), o.Params.OrderType)
+		require.Equal(t, TransactionType(TransactionTypeBuy), o.Params.TransactionType)
+		require.Equal(t, 21220.6, o.Params.Price)
+	}
+	require.Equal(t, []int{1800, 1800, 400}, qty)
+
+	// Sells are priced below the last price of the position when there's no quote.
+	res, err = c.SquareOff(context.Background(), SquareOffParams{
+		Products: []Product{ProductCNC},
+		Pricing:  SquareOffLimit,
+		DryRun:   true,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Orders, 1)
+	require.Equal(t, "TCS", res.Orders[0].Params.Tradingsymbol)
+	require.Equal(t, 5, res.Orders[0].Params.Quantity)
+	require.Equal(t, 3482.5, res.Orders[0].Params.Price)
+}
+
+func TestSquareOffSlices(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.positions = squareOffPositions()
+
+	// Freeze quantities below the lot size are sliced at a lot.
+	res, err := c.SquareOff(context.Background(), SquareOffParams{
+		Exchanges:        []Exchange{ExchangeNFO},
+		FreezeQuantities: map[string]int{"NFO:NIFTYFUT": 10},
+		Instruments:      Instruments{{Exchange: "NFO", Tradingsymbol: "NIFTYFUT", LotSize: 25}},
+		DryRun:           true,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Orders, 160)
+	for _, o := range res.Orders {
+		require.Equal(t, 25, o.Params.Quantity)
+	}
+}
+
+func TestSquareOffCancelled(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.positions = squareOffPositions()
+
+	// Orders which weren't placed as the context was done are failed.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := c.SquareOff(ctx, SquareOffParams{})
+	require.Equal(t, context.Canceled, err)
+	require.Empty(t, s.placed)
+	require.Len(t, res.Failed(), 3)
+	for _, o := range res.Failed() {
+		require.Equal(t, context.Canceled, o.Err)
+	}
+}
+
+func TestRoundToTick(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 100.05, roundToTick(100.01, 0.05, math.Ceil))
+	require.Equal(t, 100.0, roundToTick(100.04, 0.05, math.Floor))
+	require.Equal(t, 100.1, roundToTick(100.1, 0.05, math.Ceil))
+	require.Equal(t, 0.3, roundToTick(0.3, 0.1, math.Floor))
+}
@@ -0,0 +1,114 @@
+package kiteticker
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the delay before a reconnect attempt.
+type Backoff interface {
+	// Delay returns the delay before the given reconnect attempt, which starts at 1.
+	Delay(attempt int) time.Duration
+}
+
+// BackoffFunc is a function which implements Backoff.
+type BackoffFunc func(attempt int) time.Duration
+
+// Delay implements Backoff.
+func (f BackoffFunc) Delay(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// ExponentialBackoff multiplies the delay on every attempt till the maximum,
+// optionally randomising it so that many clients don't reconnect at once.
+type ExponentialBackoff struct {
+	// Initial is the delay before the first attempt.
+	Initial time.Duration
+	// Max caps the delay. There's no cap if it's 0.
+	Max time.Duration
+	// Multiplier of the delay on every attempt. Defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomised, between 0 and 1.
+	// With a jitter of 0.2, a delay of 10s is randomised between 8s and 12s.
+	Jitter float64
+}
+
+// Delay implements Backoff.
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	mul := b.Multiplier
+	if mul <= 0 {
+		mul = 2
+	}
+
+	d := float64(b.Initial) * math.Pow(mul, float64(attempt-1))
+	if b.Max > 0 && (d > float64(b.Max) || math.IsInf(d, 0) || math.IsNaN(d)) {
+		d = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		j := math.Min(b.Jitter, 1)
+		d += d * j * (2*rand.Float64() - 1)
+	}
+
+	if d > math.MaxInt64 || math.IsNaN(d) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// defaultBackoff waits 2^attempt seconds.
+var defaultBackoff = ExponentialBackoff{Initial: 2 * time.Second, Multiplier: 2}
+
+// ConnectionState represents the state of a ticker's connection.
+type ConnectionState int
+
+const (
+	// StateDisconnected is the state before serving and while the
+	// connection is lost till the next attempt.
+	StateDisconnected ConnectionState = iota
+	// StateConnecting is the state while a connection is being made.
+	StateConnecting
+	// StateConnected is the state while connected.
+	StateConnected
+	// StateReconnecting is the state while waiting before a reconnect attempt.
+	StateReconnecting
+	// StateStopped is the state once the ticker stops serving.
+	StateStopped
+)
+
+// String returns the name of the state.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// sleep waits for the duration or till the context is cancelled, returning false if it is.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
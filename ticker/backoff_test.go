@@ -0,0 +1,146 @@
+package kiteticker
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		backoff  ExponentialBackoff
+		attempts []int
+		delays   []time.Duration
+	}{
+		{
+			name:     "default",
+			backoff:  defaultBackoff,
+			attempts: []int{1, 2, 3, 6},
+			delays:   []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 64 * time.Second},
+		},
+		{
+			name:     "capped",
+			backoff:  ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 3},
+			attempts: []int{0, 1, 2, 3, 1000},
+			delays:   []time.Duration{time.Second, time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			for i, a := range tc.attempts {
+				require.Equal(t, tc.delays[i], tc.backoff.Delay(a), "attempt %d", a)
+			}
+		})
+	}
+
+	b := ExponentialBackoff{Initial: 10 * time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		d := b.Delay(1)
+		require.True(t, d >= 8*time.Second && d <= 12*time.Second, "delay %v", d)
+	}
+
+	require.Equal(t, 3*time.Second, BackoffFunc(func(a int) time.Duration {
+		return time.Duration(a) * time.Second
+	}).Delay(3))
+}
+
+func TestSetReconnectMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	tk := New("", "")
+	require.Error(t, tk.SetReconnectMaxDelay(time.Second))
+	require.NoError(t, tk.SetReconnectMaxDelay(10*time.Second))
+	require.Error(t, tk.SetDataTimeout(0))
+	require.Error(t, tk.SetConnectionCheckInterval(-time.Second))
+}
+
+// stateRecorder records the state changes of a ticker.
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []ConnectionState
+}
+
+func (s *stateRecorder) record(prev, state ConnectionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = append(s.states, state)
+}
+
+func (s *stateRecorder) get() []ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ConnectionState(nil), s.states...)
+}
+
+func TestTickerDataTimeout(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	tk := New("api_key", "access_token")
+	tk.SetRootURL(srv.url())
+	tk.SetBackoff(BackoffFunc(func(int) time.Duration { return 10 * time.Millisecond }))
+	require.NoError(t, tk.SetDataTimeout(200*time.Millisecond))
+	require.NoError(t, tk.SetConnectionCheckInterval(50*time.Millisecond))
+
+	var states stateRecorder
+	tk.OnStateChange(states.record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := serve(ctx, tk)
+
+	// The server never sends anything so the ticker reconnects.
+	srv.waitConn(t)
+	srv.waitConn(t)
+	require.Eventually(t, func() bool {
+		return tk.State() == StateConnected
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	waitDone(t, done)
+	require.Equal(t, StateStopped, tk.State())
+
+	require.Equal(t, []ConnectionState{
+		StateConnecting, StateConnected, StateDisconnected,
+		StateReconnecting, StateConnecting, StateConnected,
+	}, states.get()[:6])
+	require.Equal(t, StateStopped, states.get()[len(states.get())-1])
+}
+
+func TestTickerStopDuringBackoff(t *testing.T) {
+	t.Parallel()
+
+	tk := New("api_key", "access_token")
+	tk.SetRootURL(url.URL{Scheme: "ws", Host: "127.0.0.1:1"})
+	tk.SetBackoff(BackoffFunc(func(int) time.Duration { return time.Hour }))
+	require.NoError(t, tk.SetReconnectMaxDelay(time.Hour))
+
+	reconnecting := make(chan struct{}, 1)
+	tk.OnReconnect(func(attempt int, delay time.Duration) {
+		require.Equal(t, time.Hour, delay)
+		reconnecting <- struct{}{}
+	})
+
+	done := serve(context.Background(), tk)
+
+	select {
+	case <-reconnecting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ticker didn't reconnect")
+	}
+	require.Equal(t, StateReconnecting, tk.State())
+
+	tk.Stop()
+	waitDone(t, done)
+	require.Equal(t, StateStopped, tk.State())
+}
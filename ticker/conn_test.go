@@ -0,0 +1,328 @@
+package kiteticker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// testServer is a websocket server which records the messages received
+// from the ticker and hands over every connection to the test.
+type testServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	msgs  []tickerInput
+	conns chan *websocket.Conn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{conns: make(chan *websocket.Conn, 10)}
+
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.conns <- conn
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var in tickerInput
+			if err := json.Unmarshal(msg, &in); err == nil {
+				s.mu.Lock()
+				s.msgs = append(s.msgs, in)
+				s.mu.Unlock()
+			}
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *testServer) url() url.URL {
+	u, _ := url.Parse(s.URL)
+	return url.URL{Scheme: "ws", Host: u.Host}
+}
+
+func (s *testServer) messages() []tickerInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]tickerInput{}, s.msgs...)
+}
+
+func (s *testServer) waitConn(t *testing.T) *websocket.Conn {
+	select {
+	case conn := <-s.conns:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connection")
+	}
+	return nil
+}
+
+// ltpPacket returns a message with an LTP packet of every token.
+func ltpPacket(price float64, tokens ...uint32) []byte {
+	ticks := make([]models.Tick, len(tokens))
+	for i, tk := range tokens {
+		ticks[i] = models.Tick{Mode: string(ModeLTP), InstrumentToken: tk, LastPrice: price}
+	}
+
+	b, _ := EncodeFrame(ticks...)
+	return b
+}
+
+// serve starts serving the ticker and returns a channel which is closed once it returns.
+func serve(ctx context.Context, tk *Ticker) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		tk.ServeWithContext(ctx)
+		close(done)
+	}()
+	return done
+}
+
+func waitDone(t *testing.T, done chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ticker to stop")
+	}
+}
+
+func TestTickerConcurrentUse(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	tk := New("key", "token")
+	tk.SetRootURL(srv.url())
+
+	var ticks int64
+	tk.OnTick(func(tick models.Tick) {
+		atomic.AddInt64(&ticks, 1)
+	})
+
+	connected := make(chan struct{})
+	tk.OnConnect(func() {
+		close(connected)
+	})
+
+	done := serve(context.Background(), tk)
+	conn := srv.waitConn(t)
+	<-connected
+
+	// Server streams ticks while subscriptions are changed concurrently.
+	go func() {
+		for i := 0; i < 200; i++ {
+			if err := conn.WriteMessage(websocket.BinaryMessage, ltpPacket(100, 1, 2)); err != nil {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				token := uint32(i*100 + j)
+				require.NoError(t, tk.Subscribe([]uint32{token}))
+				require.NoError(t, tk.SetMode(ModeFull, []uint32{token}))
+				if j%2 == 0 {
+					require.NoError(t, tk.Unsubscribe([]uint32{token}))
+				}
+				if j%10 == 0 {
+					require.NoError(t, tk.Resubscribe())
+					tk.OnTick(func(tick models.Tick) {
+						atomic.AddInt64(&ticks, 1)
+					})
+				}
+				tk.Subscriptions()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	subs := tk.Subscriptions()
+	require.Len(t, subs, 8*25)
+	for _, mode := range subs {
+		require.Equal(t, ModeFull, mode)
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&ticks) == 400
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Every message written by the ticker must reach the server intact.
+	require.Eventually(t, func() bool {
+		return len(srv.messages()) >= 8*50*2+8*25
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tk.Stop()
+	waitDone(t, done)
+}
+
+func TestTickerSubscriptionOrder(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	tk := New("key", "token")
+	tk.SetRootURL(srv.url())
+
+	connected := make(chan struct{})
+	tk.OnConnect(func() {
+		close(connected)
+	})
+
+	done := serve(context.Background(), tk)
+	srv.waitConn(t)
+	<-connected
+
+	// Replaying the messages gives the server's view of the subscriptions.
+	replay := func() map[uint32]Mode {
+		seen := map[uint32]Mode{}
+		for _, m := range srv.messages() {
+			switch m.Type {
+			case "subscribe":
+				seen[1] = ""
+			case "unsubscribe":
+				delete(seen, 1)
+			case "mode":
+				seen[1] = ModeQuote
+			}
+		}
+		return seen
+	}
+
+	// The same token is subscribed, unsubscribed and has its mode set
+	// concurrently in every round.
+	for i := 0; i < 100; i++ {
+		var (
+			wg    sync.WaitGroup
+			start = make(chan struct{})
+		)
+		for _, f := range []func([]uint32) error{
+			tk.Subscribe,
+			tk.Unsubscribe,
+			func(tokens []uint32) error { return tk.SetMode(ModeQuote, tokens) },
+		} {
+			wg.Add(1)
+			go func(f func([]uint32) error) {
+				defer wg.Done()
+				<-start
+				require.NoError(t, f([]uint32{1}))
+			}(f)
+		}
+		close(start)
+		wg.Wait()
+
+		require.Eventually(t, func() bool {
+			return len(srv.messages()) == 3*(i+1)
+		}, 5*time.Second, time.Millisecond)
+		require.Equal(t, replay(), tk.Subscriptions(), "round %d", i)
+	}
+
+	tk.Stop()
+	waitDone(t, done)
+}
+
+func TestTickerSubscribeBeforeConnect(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	tk := New("key", "token")
+	tk.SetRootURL(srv.url())
+
+	require.Equal(t, ErrNotConnected, tk.Close())
+	require.NoError(t, tk.Subscribe([]uint32{1, 2}))
+	require.NoError(t, tk.SetMode(ModeLTP, []uint32{2}))
+	require.Equal(t, map[uint32]Mode{1: "", 2: ModeLTP}, tk.Subscriptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := serve(ctx, tk)
+	srv.waitConn(t)
+
+	require.Eventually(t, func() bool {
+		return len(srv.messages()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	msgs := srv.messages()
+	require.Equal(t, "subscribe", msgs[0].Type)
+	require.ElementsMatch(t, []interface{}{1.0, 2.0}, msgs[0].Val)
+	require.Equal(t, "mode", msgs[1].Type)
+	require.Equal(t, []interface{}{"ltp", []interface{}{2.0}}, msgs[1].Val)
+
+	// Cancelling the context stops the ticker even without any data.
+	cancel()
+	waitDone(t, done)
+}
+
+func TestTickerReconnect(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	tk := New("key", "token")
+	tk.SetRootURL(srv.url())
+
+	var (
+		mu       sync.Mutex
+		attempts []int
+		connects int
+	)
+	tk.OnReconnect(func(attempt int, delay time.Duration) {
+		mu.Lock()
+		attempts = append(attempts, attempt)
+		mu.Unlock()
+	})
+	tk.OnConnect(func() {
+		mu.Lock()
+		connects++
+		mu.Unlock()
+	})
+
+	done := serve(context.Background(), tk)
+	conn := srv.waitConn(t)
+
+	require.NoError(t, tk.Subscribe([]uint32{1}))
+	require.NoError(t, tk.SetMode(ModeQuote, []uint32{1}))
+
+	// Server drops the connection.
+	require.Eventually(t, func() bool {
+		return len(srv.messages()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	conn.Close()
+
+	srv.waitConn(t)
+	require.Eventually(t, func() bool {
+		return len(srv.messages()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	msgs := srv.messages()
+	require.Equal(t, "subscribe", msgs[2].Type)
+	require.Equal(t, "mode", msgs[3].Type)
+
+	mu.Lock()
+	require.Equal(t, []int{1}, attempts)
+	require.Equal(t, 2, connects)
+	mu.Unlock()
+
+	tk.Stop()
+	waitDone(t, done)
+}
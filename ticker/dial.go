@@ -0,0 +1,92 @@
+package kiteticker
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+)
+
+// DialOptions represents the options for making the websocket connection.
+type DialOptions struct {
+	// Proxy returns the proxy for a request. Defaults to http.ProxyFromEnvironment.
+	// Use http.ProxyURL to route through a specific proxy.
+	Proxy func(*http.Request) (*url.URL, error)
+	// NetDialContext dials the TCP connection. Defaults to net.Dialer.
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig is used for the TLS connection, eg: to pin root CAs.
+	TLSConfig *tls.Config
+	// Header is sent with the handshake request.
+	Header http.Header
+	// ReadBufferSize and WriteBufferSize are the sizes of the I/O buffers.
+	// The websocket package's defaults are used if they are 0.
+	ReadBufferSize  int
+	WriteBufferSize int
+	// EnableCompression negotiates per message compression with the server.
+	EnableCompression bool
+}
+
+// SetDialOptions sets the options for making the websocket connection.
+// They replace the ones of an earlier SetDialOptions or SetDialer.
+func (t *Ticker) SetDialOptions(opt DialOptions) {
+	proxy := opt.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	d := &websocket.Dialer{
+		Proxy:             proxy,
+		NetDialContext:    opt.NetDialContext,
+		TLSClientConfig:   opt.TLSConfig,
+		ReadBufferSize:    opt.ReadBufferSize,
+		WriteBufferSize:   opt.WriteBufferSize,
+		EnableCompression: opt.EnableCompression,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dialer = d
+	t.header = opt.Header.Clone()
+}
+
+// SetDialer sets the dialer used to make the websocket connection. The dialer
+// is copied and isn't modified. The connect timeout is used as its handshake
+// timeout if it doesn't have one. Pass nil to use websocket.DefaultDialer.
+func (t *Ticker) SetDialer(d *websocket.Dialer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.header = nil
+	if d == nil {
+		t.dialer = nil
+		return
+	}
+
+	dc := *d
+	t.dialer = &dc
+}
+
+// dial makes the websocket connection.
+func (t *Ticker) dial(ctx context.Context, u string) (*websocket.Conn, error) {
+	t.mu.RLock()
+	var (
+		d      = websocket.DefaultDialer
+		header = t.header
+	)
+	if t.dialer != nil {
+		d = t.dialer
+	}
+
+	// Copy the dialer so that a shared one isn't modified.
+	dc := *d
+	if dc.HandshakeTimeout == 0 || t.dialer == nil {
+		dc.HandshakeTimeout = t.connectTimeout
+	}
+	t.mu.RUnlock()
+
+	conn, _, err := dc.DialContext(ctx, u, header)
+	return conn, err
+}
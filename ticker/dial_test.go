@@ -0,0 +1,158 @@
+package kiteticker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// newHeaderServer returns a websocket server which sends every handshake's headers on a channel.
+func newHeaderServer(t *testing.T, tlsServer bool) (*httptest.Server, chan http.Header) {
+	var (
+		headers  = make(chan http.Header, 10)
+		upgrader = websocket.Upgrader{EnableCompression: true}
+	)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		headers <- r.Header
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	var srv *httptest.Server
+	if tlsServer {
+		srv = httptest.NewTLSServer(h)
+	} else {
+		srv = httptest.NewServer(h)
+	}
+	t.Cleanup(srv.Close)
+
+	return srv, headers
+}
+
+func waitHeader(t *testing.T, headers chan http.Header) http.Header {
+	select {
+	case h := <-headers:
+		return h
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connection")
+	}
+	return nil
+}
+
+func TestTickerDialOptions(t *testing.T) {
+	t.Parallel()
+
+	srv, headers := newHeaderServer(t, true)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	// Connections are routed through a proxy which counts them.
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "", http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&proxied, 1)
+
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+		src, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			dst.Close()
+			return
+		}
+		go func() {
+			io.Copy(dst, src)
+			dst.Close()
+		}()
+		io.Copy(src, dst)
+		src.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	u, _ := url.Parse(srv.URL)
+	tk := New("api_key", "access_token")
+	tk.SetRootURL(url.URL{Scheme: "wss", Host: u.Host})
+	tk.SetDialOptions(DialOptions{
+		Proxy:             http.ProxyURL(proxyURL),
+		TLSConfig:         &tls.Config{RootCAs: pool},
+		Header:            http.Header{"X-Client": []string{"test"}},
+		ReadBufferSize:    8192,
+		EnableCompression: true,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := serve(ctx, tk)
+
+	h := waitHeader(t, headers)
+	require.Equal(t, "test", h.Get("X-Client"))
+	require.Contains(t, h.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+	require.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+
+	cancel()
+	waitDone(t, done)
+}
+
+func TestTickerSetDialer(t *testing.T) {
+	t.Parallel()
+
+	srv, headers := newHeaderServer(t, false)
+	u, _ := url.Parse(srv.URL)
+
+	// The dialer is used without being modified.
+	var dialed int32
+	d := &websocket.Dialer{
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dialed, 1)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+
+	tk := New("api_key", "access_token")
+	tk.SetRootURL(url.URL{Scheme: "ws", Host: u.Host})
+	tk.SetDialer(d)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := serve(ctx, tk)
+
+	waitHeader(t, headers)
+	require.Equal(t, int32(1), atomic.LoadInt32(&dialed))
+	require.Zero(t, d.HandshakeTimeout)
+
+	cancel()
+	waitDone(t, done)
+
+	// Shared default dialer isn't modified either.
+	require.Equal(t, 45*time.Second, websocket.DefaultDialer.HandshakeTimeout)
+}
@@ -0,0 +1,186 @@
+package kiteticker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Number of market depth entries on each side in full mode packets.
+const depthEntries = 5
+
+// EncodeTick encodes a tick into a binary packet of the tick's mode, which is
+// the inverse of parsing. Index packets are encoded for instruments of the indices
+// segment and prices are scaled as per the instrument's segment. Fields which
+// aren't part of the mode's packet are ignored.
+func EncodeTick(tick models.Tick) ([]byte, error) {
+	var (
+		seg  = tick.InstrumentToken & 0xFF
+		size int
+	)
+
+	switch Mode(tick.Mode) {
+	case ModeLTP:
+		size = modeLTPLength
+	case ModeQuote:
+		size = modeQuoteLength
+		if seg == Indices {
+			size = modeQuoteIndexPacketLength
+		}
+	case ModeFull:
+		size = modeFullLength
+		if seg == Indices {
+			size = modeFullIndexLength
+		}
+	default:
+		return nil, fmt.Errorf("invalid tick mode: %q", tick.Mode)
+	}
+
+	e := encoder{b: make([]byte, size), seg: seg}
+	e.uint32(0, tick.InstrumentToken)
+	e.price(4, tick.LastPrice)
+
+	switch size {
+	case modeQuoteIndexPacketLength, modeFullIndexLength:
+		e.price(8, tick.OHLC.High)
+		e.price(12, tick.OHLC.Low)
+		e.price(16, tick.OHLC.Open)
+		e.price(20, tick.OHLC.Close)
+		// Signed price change which isn't used while parsing.
+		e.change(24, tick.LastPrice-tick.OHLC.Close)
+
+		if size == modeFullIndexLength {
+			e.time(28, tick.Timestamp)
+		}
+
+	case modeQuoteLength, modeFullLength:
+		e.uint32(8, tick.LastTradedQuantity)
+		e.price(12, tick.AverageTradePrice)
+		e.uint32(16, tick.VolumeTraded)
+		e.uint32(20, tick.TotalBuyQuantity)
+		e.uint32(24, tick.TotalSellQuantity)
+		e.price(28, tick.OHLC.Open)
+		e.price(32, tick.OHLC.High)
+		e.price(36, tick.OHLC.Low)
+		e.price(40, tick.OHLC.Close)
+
+		if size == modeFullLength {
+			e.time(44, tick.LastTradeTime)
+			e.uint32(48, tick.OI)
+			e.uint32(52, tick.OIDayHigh)
+			e.uint32(56, tick.OIDayLow)
+			e.time(60, tick.Timestamp)
+
+			for i := 0; i < depthEntries; i++ {
+				e.depth(64+i*12, tick.Depth.Buy[i])
+				e.depth(124+i*12, tick.Depth.Sell[i])
+			}
+		}
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.b, nil
+}
+
+// EncodeFrame encodes ticks into a single websocket message of
+// multiple packets, each in the mode of its tick.
+func EncodeFrame(ticks ...models.Tick) ([]byte, error) {
+	if len(ticks) > math.MaxUint16 {
+		return nil, fmt.Errorf("too many ticks in a frame: %d", len(ticks))
+	}
+
+	b := make([]byte, 2, 2+len(ticks)*(2+modeFullLength))
+	binary.BigEndian.PutUint16(b, uint16(len(ticks)))
+
+	for _, tick := range ticks {
+		pkt, err := EncodeTick(tick)
+		if err != nil {
+			return nil, err
+		}
+
+		var l [2]byte
+		binary.BigEndian.PutUint16(l[:], uint16(len(pkt)))
+		b = append(b, l[:]...)
+		b = append(b, pkt...)
+	}
+
+	return b, nil
+}
+
+// encoder writes the fields of a packet, retaining the first error.
+type encoder struct {
+	b   []byte
+	seg uint32
+	err error
+}
+
+func (e *encoder) uint32(off int, v uint32) {
+	binary.BigEndian.PutUint32(e.b[off:], v)
+}
+
+// price writes a price scaled as per the segment, the inverse of convertPrice.
+func (e *encoder) price(off int, v float64) {
+	switch e.seg {
+	case NseCD:
+		v *= 10000000.0
+	case BseCD:
+		v *= 10000.0
+	default:
+		v *= 100.0
+	}
+
+	v = math.Round(v)
+	if v < 0 || v > math.MaxUint32 || math.IsNaN(v) {
+		if e.err == nil {
+			e.err = fmt.Errorf("price out of range at offset %d", off)
+		}
+		return
+	}
+
+	e.uint32(off, uint32(v))
+}
+
+// change writes a signed price change scaled as per the segment.
+func (e *encoder) change(off int, v float64) {
+	if v < 0 {
+		e.price(off, -v)
+		e.uint32(off, -binary.BigEndian.Uint32(e.b[off:]))
+		return
+	}
+	e.price(off, v)
+}
+
+// time writes a timestamp as unix seconds. Zero time is written as 0.
+func (e *encoder) time(off int, t models.Time) {
+	if t.IsZero() {
+		e.uint32(off, 0)
+		return
+	}
+
+	s := t.Unix()
+	if s < 0 || s > math.MaxUint32 {
+		if e.err == nil {
+			e.err = fmt.Errorf("timestamp out of range at offset %d", off)
+		}
+		return
+	}
+
+	e.uint32(off, uint32(s))
+}
+
+func (e *encoder) depth(off int, d models.DepthItem) {
+	if d.Orders > math.MaxUint16 {
+		if e.err == nil {
+			e.err = fmt.Errorf("depth orders out of range at offset %d", off)
+		}
+		return
+	}
+
+	e.uint32(off, d.Quantity)
+	e.price(off+4, d.Price)
+	binary.BigEndian.PutUint16(e.b[off+8:], uint16(d.Orders))
+}
@@ -0,0 +1,186 @@
+package kiteticker
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// randTick is a random tick with the fields that a packet of its mode carries.
+type randTick struct {
+	models.Tick
+}
+
+// Generate implements quick.Generator.
+func (randTick) Generate(r *rand.Rand, size int) reflect.Value {
+	var (
+		seg   = uint32(1 + r.Intn(Indices))
+		token = uint32(r.Intn(1<<24))<<8 | seg
+		modes = []Mode{ModeLTP, ModeQuote, ModeFull}
+		mode  = modes[r.Intn(len(modes))]
+	)
+
+	price := func() float64 {
+		return convertPrice(seg, float64(r.Uint32()))
+	}
+	ts := func() models.Time {
+		return models.Time{Time: time.Unix(int64(1+r.Int31()), 0)}
+	}
+
+	tick := models.Tick{
+		Mode:            string(mode),
+		InstrumentToken: token,
+		IsIndex:         seg == Indices,
+		IsTradable:      seg != Indices,
+		LastPrice:       price(),
+	}
+
+	if mode != ModeLTP {
+		tick.OHLC = models.OHLC{Open: price(), High: price(), Low: price(), Close: price()}
+
+		if seg == Indices {
+			tick.NetChange = tick.LastPrice - tick.OHLC.Close
+			if mode == ModeFull {
+				tick.Timestamp = ts()
+			}
+		} else {
+			tick.LastTradedQuantity = r.Uint32()
+			tick.AverageTradePrice = price()
+			tick.VolumeTraded = r.Uint32()
+			tick.TotalBuyQuantity = r.Uint32()
+			tick.TotalSellQuantity = r.Uint32()
+		}
+	}
+
+	if mode == ModeFull && seg != Indices {
+		tick.LastTradeTime = ts()
+		tick.Timestamp = ts()
+		tick.OI = r.Uint32()
+		tick.OIDayHigh = r.Uint32()
+		tick.OIDayLow = r.Uint32()
+		tick.NetChange = tick.LastPrice - tick.OHLC.Close
+
+		for i := 0; i < depthEntries; i++ {
+			tick.Depth.Buy[i] = models.DepthItem{Price: price(), Quantity: r.Uint32(), Orders: uint32(r.Intn(1 << 16))}
+			tick.Depth.Sell[i] = models.DepthItem{Price: price(), Quantity: r.Uint32(), Orders: uint32(r.Intn(1 << 16))}
+		}
+	}
+
+	return reflect.ValueOf(randTick{tick})
+}
+
+func TestEncodeTickRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	f := func(rt randTick) bool {
+		pkt, err := EncodeTick(rt.Tick)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+
+		tick, err := parsePacket(pkt)
+		if err != nil || !reflect.DeepEqual(rt.Tick, tick) {
+			t.Logf("expected %+v, got %+v", rt.Tick, tick)
+			return false
+		}
+
+		// Encoding the parsed tick gives the same packet.
+		again, err := EncodeTick(tick)
+		return err == nil && reflect.DeepEqual(pkt, again)
+	}
+
+	require.NoError(t, quick.Check(f, &quick.Config{MaxCount: 2000}))
+}
+
+func TestEncodeFrameRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	f := func(rts []randTick) bool {
+		ticks := make([]models.Tick, len(rts))
+		for i, rt := range rts {
+			ticks[i] = rt.Tick
+		}
+
+		frame, err := EncodeFrame(ticks...)
+		if err != nil {
+			return false
+		}
+
+		parsed, err := ParseFrame(nil, frame)
+		if err != nil {
+			return false
+		}
+		return len(ticks) == len(parsed) && (len(ticks) == 0 || reflect.DeepEqual(ticks, parsed))
+	}
+
+	require.NoError(t, quick.Check(f, nil))
+}
+
+func TestEncodeTickPackets(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		tick   models.Tick
+		length int
+	}{
+		{name: "ltp", tick: models.Tick{Mode: "ltp", InstrumentToken: 408065}, length: modeLTPLength},
+		{name: "quote", tick: models.Tick{Mode: "quote", InstrumentToken: 408065}, length: modeQuoteLength},
+		{name: "full", tick: models.Tick{Mode: "full", InstrumentToken: 408065}, length: modeFullLength},
+		{name: "index quote", tick: models.Tick{Mode: "quote", InstrumentToken: 256265}, length: modeQuoteIndexPacketLength},
+		{name: "index full", tick: models.Tick{Mode: "full", InstrumentToken: 256265}, length: modeFullIndexLength},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pkt, err := EncodeTick(tc.tick)
+			require.NoError(t, err)
+			require.Len(t, pkt, tc.length)
+		})
+	}
+
+	// Segment specific price scaling.
+	pkt, err := EncodeTick(models.Tick{Mode: "ltp", InstrumentToken: 412675, LastPrice: 74.1234567})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 6, 0x4c, 0x03, 0x2c, 0x2e, 0x57, 0x87}, pkt)
+
+	// Index price change is signed.
+	pkt, err = EncodeTick(models.Tick{Mode: "quote", InstrumentToken: 256265, LastPrice: 10, OHLC: models.OHLC{Close: 11}})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xff, 0xff, 0xff, 0x9c}, pkt[24:28])
+}
+
+func TestEncodeTickErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name string
+		tick models.Tick
+	}{
+		{name: "invalid mode", tick: models.Tick{Mode: "depth"}},
+		{name: "negative price", tick: models.Tick{Mode: "ltp", LastPrice: -1}},
+		{name: "price overflow", tick: models.Tick{Mode: "ltp", LastPrice: 5e7}},
+		{name: "timestamp", tick: models.Tick{Mode: "full", Timestamp: models.Time{Time: time.Unix(-1, 0)}}},
+		{name: "depth orders", tick: models.Tick{Mode: "full", Depth: models.Depth{Sell: [5]models.DepthItem{{Orders: 1 << 16}}}}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := EncodeTick(tc.tick)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := EncodeFrame(models.Tick{Mode: "ltp"}, models.Tick{})
+	require.Error(t, err)
+}
@@ -0,0 +1,330 @@
+package kiteticker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Prefix of the names of exported metrics.
+const metricsPrefix = "kiteticker_"
+
+// Metrics receives the health metrics of a ticker. Methods are called from
+// the websocket read loop so they must not block.
+type Metrics interface {
+	// MessageReceived is called for every websocket message including heartbeats.
+	MessageReceived(messageType int, size int)
+	// TicksReceived is called with the number of ticks parsed from a binary message.
+	TicksReceived(n int)
+	// ParseError is called for every packet which couldn't be parsed and for truncated messages.
+	ParseError()
+	// Connected is called whenever the ticker connects.
+	Connected()
+	// Reconnecting is called before every reconnect attempt.
+	Reconnecting(attempt int)
+	// TickLatency is called with the time between the exchange timestamp of a tick
+	// and its receipt. Only full mode ticks carry a timestamp, which is in seconds.
+	TickLatency(segment uint32, latency time.Duration)
+}
+
+// SetMetrics sets the metrics which receive the health of the connection.
+// Pass nil to stop reporting.
+func (t *Ticker) SetMetrics(m Metrics) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = m
+}
+
+func (t *Ticker) getMetrics() Metrics {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.metrics
+}
+
+// SetMetrics sets the metrics of every connection of the pool, which are
+// reported together.
+func (p *TickerPool) SetMetrics(m Metrics) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.metrics = m
+	for _, s := range p.shards {
+		s.ticker.SetMetrics(m)
+	}
+}
+
+// reportMessage reports the metrics of a message received at the given time.
+func reportMessage(m Metrics, at time.Time, mType int, size int, ticks []models.Tick, err error) {
+	m.MessageReceived(mType, size)
+	if mType != BinaryMessage {
+		return
+	}
+
+	m.TicksReceived(len(ticks))
+	for _, tick := range ticks {
+		// Packets without an exchange timestamp carry zero, which parses to
+		// the Unix epoch.
+		if tick.Timestamp.Unix() > 0 {
+			m.TickLatency(tick.InstrumentToken&0xFF, at.Sub(tick.Timestamp.Time))
+		}
+	}
+
+	if ferr, ok := err.(*FrameError); ok {
+		for range ferr.Packets {
+			m.ParseError()
+		}
+		if ferr.Truncated {
+			m.ParseError()
+		}
+	}
+}
+
+// Upper bounds in seconds of the buckets of the tick latency histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// segmentNames are the label values of segments in exported metrics.
+var segmentNames = map[uint32]string{
+	NseCM:   "nse_cm",
+	NseFO:   "nse_fo",
+	NseCD:   "nse_cd",
+	BseCM:   "bse_cm",
+	BseFO:   "bse_fo",
+	BseCD:   "bse_cd",
+	McxFO:   "mcx_fo",
+	McxSX:   "mcx_sx",
+	Indices: "indices",
+}
+
+// MetricsCollector is a Metrics which keeps counters, gauges and latency
+// histograms and exports them in the Prometheus text format. It's an
+// http.Handler which can be served on a /metrics endpoint and a single
+// collector can be shared by multiple tickers.
+type MetricsCollector struct {
+	frames      uint64
+	bytes       uint64
+	ticks       uint64
+	parseErrors uint64
+	connects    uint64
+	reconnects  uint64
+	lastMessage int64
+
+	mu        sync.Mutex
+	frameRate rate
+	tickRate  rate
+	latency   map[uint32]*histogram
+	now       func() time.Time
+}
+
+// NewMetricsCollector creates a metrics collector.
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		latency: map[uint32]*histogram{},
+		now:     time.Now,
+	}
+}
+
+// MessageReceived implements Metrics.
+func (c *MetricsCollector) MessageReceived(messageType int, size int) {
+	now := c.now()
+
+	atomic.AddUint64(&c.frames, 1)
+	atomic.AddUint64(&c.bytes, uint64(size))
+	atomic.StoreInt64(&c.lastMessage, now.UnixNano())
+
+	c.mu.Lock()
+	c.frameRate.add(now, 1)
+	c.mu.Unlock()
+}
+
+// TicksReceived implements Metrics.
+func (c *MetricsCollector) TicksReceived(n int) {
+	atomic.AddUint64(&c.ticks, uint64(n))
+
+	c.mu.Lock()
+	c.tickRate.add(c.now(), n)
+	c.mu.Unlock()
+}
+
+// ParseError implements Metrics.
+func (c *MetricsCollector) ParseError() {
+	atomic.AddUint64(&c.parseErrors, 1)
+}
+
+// Connected implements Metrics.
+func (c *MetricsCollector) Connected() {
+	atomic.AddUint64(&c.connects, 1)
+}
+
+// Reconnecting implements Metrics.
+func (c *MetricsCollector) Reconnecting(attempt int) {
+	atomic.AddUint64(&c.reconnects, 1)
+}
+
+// TickLatency implements Metrics.
+func (c *MetricsCollector) TickLatency(segment uint32, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.latency[segment]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		c.latency[segment] = h
+	}
+	h.observe(latency.Seconds())
+}
+
+// MetricsSnapshot represents the values of the metrics of a collector.
+type MetricsSnapshot struct {
+	Frames           uint64
+	Bytes            uint64
+	Ticks            uint64
+	ParseErrors      uint64
+	Connects         uint64
+	Reconnects       uint64
+	FramesPerSecond  float64
+	TicksPerSecond   float64
+	SinceLastMessage time.Duration
+}
+
+// Snapshot returns the current values of the metrics. Rates are of the last
+// complete second. SinceLastMessage is 0 if no message has been received.
+func (c *MetricsCollector) Snapshot() MetricsSnapshot {
+	now := c.now()
+
+	s := MetricsSnapshot{
+		Frames:      atomic.LoadUint64(&c.frames),
+		Bytes:       atomic.LoadUint64(&c.bytes),
+		Ticks:       atomic.LoadUint64(&c.ticks),
+		ParseErrors: atomic.LoadUint64(&c.parseErrors),
+		Connects:    atomic.LoadUint64(&c.connects),
+		Reconnects:  atomic.LoadUint64(&c.reconnects),
+	}
+	if last := atomic.LoadInt64(&c.lastMessage); last != 0 {
+		s.SinceLastMessage = now.Sub(time.Unix(0, last))
+	}
+
+	c.mu.Lock()
+	s.FramesPerSecond = c.frameRate.get(now)
+	s.TicksPerSecond = c.tickRate.get(now)
+	c.mu.Unlock()
+
+	return s
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (c *MetricsCollector) WritePrometheus(w io.Writer) error {
+	var (
+		s   = c.Snapshot()
+		bw  = bufio.NewWriter(w)
+		pfx = metricsPrefix
+	)
+
+	metric := func(name, typ, help string, v interface{}) {
+		fmt.Fprintf(bw, "# HELP %s%s %s\n# TYPE %s%s %s\n%s%s %v\n", pfx, name, help, pfx, name, typ, pfx, name, v)
+	}
+
+	metric("frames_total", "counter", "Websocket messages received.", s.Frames)
+	metric("bytes_total", "counter", "Bytes of websocket messages received.", s.Bytes)
+	metric("ticks_total", "counter", "Ticks received.", s.Ticks)
+	metric("parse_errors_total", "counter", "Packets which couldn't be parsed.", s.ParseErrors)
+	metric("connects_total", "counter", "Successful connections.", s.Connects)
+	metric("reconnects_total", "counter", "Reconnect attempts.", s.Reconnects)
+	metric("frames_per_second", "gauge", "Websocket messages received in the last second.", s.FramesPerSecond)
+	metric("ticks_per_second", "gauge", "Ticks received in the last second.", s.TicksPerSecond)
+	if s.SinceLastMessage > 0 {
+		metric("seconds_since_last_message", "gauge", "Time since the last websocket message.", s.SinceLastMessage.Seconds())
+	}
+
+	c.mu.Lock()
+	segs := make([]uint32, 0, len(c.latency))
+	for seg := range c.latency {
+		segs = append(segs, seg)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+
+	if len(segs) > 0 {
+		name := pfx + "tick_latency_seconds"
+		fmt.Fprintf(bw, "# HELP %s Time between the exchange timestamp of full mode ticks and their receipt.\n# TYPE %s histogram\n", name, name)
+
+		for _, seg := range segs {
+			var (
+				h     = c.latency[seg]
+				label = segmentNames[seg]
+				cum   uint64
+			)
+			if label == "" {
+				label = fmt.Sprint(seg)
+			}
+
+			for i, le := range latencyBuckets {
+				cum += h.counts[i]
+				fmt.Fprintf(bw, "%s_bucket{segment=%q,le=\"%g\"} %d\n", name, label, le, cum)
+			}
+			fmt.Fprintf(bw, "%s_bucket{segment=%q,le=\"+Inf\"} %d\n", name, label, h.count)
+			fmt.Fprintf(bw, "%s_sum{segment=%q} %g\n", name, label, h.sum)
+			fmt.Fprintf(bw, "%s_count{segment=%q} %d\n", name, label, h.count)
+		}
+	}
+	c.mu.Unlock()
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (c *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.WritePrometheus(w)
+}
+
+// rate counts events per second, reporting the count of the last complete second.
+type rate struct {
+	sec   int64
+	count int
+	last  int
+}
+
+func (r *rate) add(now time.Time, n int) {
+	r.roll(now.Unix())
+	r.count += n
+}
+
+func (r *rate) get(now time.Time) float64 {
+	r.roll(now.Unix())
+	return float64(r.last)
+}
+
+func (r *rate) roll(sec int64) {
+	switch {
+	case sec == r.sec:
+	case sec == r.sec+1:
+		r.sec, r.last, r.count = sec, r.count, 0
+	default:
+		r.sec, r.last, r.count = sec, 0, 0
+	}
+}
+
+// histogram is a histogram of latencyBuckets.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	h.count++
+	h.sum += v
+
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+			return
+		}
+	}
+}
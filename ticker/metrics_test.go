@@ -0,0 +1,136 @@
+package kiteticker
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	t.Parallel()
+
+	var (
+		now = time.Unix(1600000000, 0)
+		c   = NewMetricsCollector()
+	)
+	c.now = func() time.Time { return now }
+
+	c.MessageReceived(BinaryMessage, 100)
+	c.TicksReceived(3)
+	now = now.Add(500 * time.Millisecond)
+	c.MessageReceived(BinaryMessage, 50)
+	c.TicksReceived(2)
+	c.ParseError()
+	c.Connected()
+	c.Reconnecting(1)
+	c.TickLatency(NseCM, 20*time.Millisecond)
+	c.TickLatency(NseCM, 2*time.Second)
+	c.TickLatency(Indices, 30*time.Second)
+
+	// Rates are of the last complete second.
+	s := c.Snapshot()
+	require.Equal(t, 0.0, s.TicksPerSecond)
+
+	now = now.Add(time.Second)
+	s = c.Snapshot()
+	require.Equal(t, MetricsSnapshot{
+		Frames:           2,
+		Bytes:            150,
+		Ticks:            5,
+		ParseErrors:      1,
+		Connects:         1,
+		Reconnects:       1,
+		FramesPerSecond:  2,
+		TicksPerSecond:   5,
+		SinceLastMessage: time.Second,
+	}, s)
+
+	// Rates drop to zero without messages.
+	now = now.Add(time.Second)
+	require.Equal(t, 0.0, c.Snapshot().FramesPerSecond)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, "text/plain; version=0.0.4", rec.Header().Get("Content-Type"))
+
+	out := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE kiteticker_frames_total counter",
+		"kiteticker_frames_total 2",
+		"kiteticker_bytes_total 150",
+		"kiteticker_ticks_total 5",
+		"kiteticker_parse_errors_total 1",
+		"kiteticker_reconnects_total 1",
+		"# TYPE kiteticker_ticks_per_second gauge",
+		"kiteticker_seconds_since_last_message 2",
+		"# TYPE kiteticker_tick_latency_seconds histogram",
+		`kiteticker_tick_latency_seconds_bucket{segment="nse_cm",le="0.01"} 0`,
+		`kiteticker_tick_latency_seconds_bucket{segment="nse_cm",le="0.025"} 1`,
+		`kiteticker_tick_latency_seconds_bucket{segment="nse_cm",le="2.5"} 2`,
+		`kiteticker_tick_latency_seconds_bucket{segment="nse_cm",le="+Inf"} 2`,
+		`kiteticker_tick_latency_seconds_sum{segment="nse_cm"} 2.02`,
+		`kiteticker_tick_latency_seconds_count{segment="nse_cm"} 2`,
+		`kiteticker_tick_latency_seconds_bucket{segment="indices",le="10"} 0`,
+		`kiteticker_tick_latency_seconds_bucket{segment="indices",le="+Inf"} 1`,
+	} {
+		require.Contains(t, out, line+"\n")
+	}
+}
+
+func TestTickerMetrics(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	tk := New("api_key", "access_token")
+	tk.SetRootURL(srv.url())
+
+	c := NewMetricsCollector()
+	tk.SetMetrics(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := serve(ctx, tk)
+
+	conn := srv.waitConn(t)
+
+	full, err := EncodeFrame(models.Tick{
+		Mode:            "full",
+		InstrumentToken: 408065,
+		Timestamp:       models.Time{Time: time.Now().Add(-time.Second)},
+	})
+	require.NoError(t, err)
+
+	// A packet without an exchange timestamp has no latency.
+	untimed, err := EncodeFrame(models.Tick{
+		Mode:            "full",
+		InstrumentToken: 408065,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, conn.WriteMessage(BinaryMessage, ltpPacket(100, 408065, 738561)))
+	require.NoError(t, conn.WriteMessage(BinaryMessage, full))
+	require.NoError(t, conn.WriteMessage(BinaryMessage, untimed))
+	require.NoError(t, conn.WriteMessage(BinaryMessage, frameOf(make([]byte, 3))))
+	require.NoError(t, conn.WriteMessage(BinaryMessage, []byte{0}))
+
+	require.Eventually(t, func() bool {
+		return c.Snapshot().Frames == 5
+	}, time.Second, 10*time.Millisecond)
+
+	s := c.Snapshot()
+	require.Equal(t, uint64(4), s.Ticks)
+	require.Equal(t, uint64(1), s.ParseErrors)
+	require.Equal(t, uint64(1), s.Connects)
+
+	var out strings.Builder
+	require.NoError(t, c.WritePrometheus(&out))
+	require.Contains(t, out.String(), `kiteticker_tick_latency_seconds_count{segment="nse_cm"} 1`)
+
+	cancel()
+	waitDone(t, done)
+}
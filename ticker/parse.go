@@ -0,0 +1,259 @@
+package kiteticker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+var (
+	// ErrInvalidPacketLength is returned for packets whose length doesn't match any mode.
+	ErrInvalidPacketLength = errors.New("invalid packet length")
+	// ErrTruncatedFrame is returned when a frame ends before all the packets it declares.
+	ErrTruncatedFrame = errors.New("truncated frame")
+)
+
+// PacketError represents a packet of a frame which couldn't be parsed.
+type PacketError struct {
+	// Index of the packet in the frame.
+	Index int
+	// Offset of the packet from the start of the frame.
+	Offset int
+	Err    error
+}
+
+func (e PacketError) Error() string {
+	return fmt.Sprintf("packet %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e PacketError) Unwrap() error {
+	return e.Err
+}
+
+// FrameError represents the errors in parsing a frame. Packets other than
+// the ones listed are parsed.
+type FrameError struct {
+	// Packets are the packets which were skipped.
+	Packets []PacketError
+	// Truncated is set if the frame ends before all the packets it declares.
+	// The packets before the truncated one are parsed.
+	Truncated bool
+}
+
+func (e *FrameError) Error() string {
+	var msgs []string
+	for _, p := range e.Packets {
+		msgs = append(msgs, p.Error())
+	}
+	if e.Truncated {
+		msgs = append(msgs, ErrTruncatedFrame.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports if the frame is truncated when matched with ErrTruncatedFrame.
+func (e *FrameError) Is(target error) bool {
+	return target == ErrTruncatedFrame && e.Truncated
+}
+
+// ParseFrame parses a binary message of one or more packets, appending the
+// ticks to dst and returning the extended slice. Pass a slice of the previous
+// call, resliced to zero length, to parse without allocating. Every length is
+// validated and packets which can't be parsed are skipped and returned in a
+// *FrameError along with the ticks of the other packets. Messages shorter than
+// 2 bytes, like heartbeats, have no ticks.
+func ParseFrame(dst []models.Tick, frame []byte) ([]models.Tick, error) {
+	if len(frame) < 2 {
+		return dst, nil
+	}
+
+	var (
+		count = int(binary.BigEndian.Uint16(frame[0:2]))
+		off   = 2
+		ferr  *FrameError
+	)
+
+	for i := 0; i < count; i++ {
+		if off+2 > len(frame) {
+			ferr = frameError(ferr)
+			ferr.Truncated = true
+			break
+		}
+
+		size := int(binary.BigEndian.Uint16(frame[off : off+2]))
+		if off+2+size > len(frame) {
+			ferr = frameError(ferr)
+			ferr.Truncated = true
+			break
+		}
+
+		pkt := frame[off+2 : off+2+size]
+		dst = append(dst, models.Tick{})
+		if err := ParsePacket(pkt, &dst[len(dst)-1]); err != nil {
+			dst = dst[:len(dst)-1]
+			ferr = frameError(ferr)
+			ferr.Packets = append(ferr.Packets, PacketError{Index: i, Offset: off, Err: err})
+		}
+
+		off += 2 + size
+	}
+
+	if ferr != nil {
+		return dst, ferr
+	}
+	return dst, nil
+}
+
+func frameError(e *FrameError) *FrameError {
+	if e == nil {
+		return &FrameError{}
+	}
+	return e
+}
+
+// ParsePacket parses a single packet into tick, overwriting all its fields.
+// ErrInvalidPacketLength is returned if the length of the packet doesn't
+// match any of the modes.
+func ParsePacket(b []byte, tick *models.Tick) error {
+	switch len(b) {
+	case modeLTPLength, modeQuoteIndexPacketLength, modeFullIndexLength, modeQuoteLength, modeFullLength:
+	default:
+		return fmt.Errorf("%w: %d", ErrInvalidPacketLength, len(b))
+	}
+
+	var (
+		tk         = binary.BigEndian.Uint32(b[0:4])
+		seg        = tk & 0xFF
+		isIndex    = seg == Indices
+		isTradable = seg != Indices
+	)
+
+	// Mode LTP parsing
+	if len(b) == modeLTPLength {
+		*tick = models.Tick{
+			Mode:            string(ModeLTP),
+			InstrumentToken: tk,
+			IsTradable:      isTradable,
+			IsIndex:         isIndex,
+			LastPrice:       convertPrice(seg, float64(binary.BigEndian.Uint32(b[4:8]))),
+		}
+		return nil
+	}
+
+	// Parse index mode full and mode quote data
+	if len(b) == modeQuoteIndexPacketLength || len(b) == modeFullIndexLength {
+		var (
+			lastPrice  = convertPrice(seg, float64(binary.BigEndian.Uint32(b[4:8])))
+			closePrice = convertPrice(seg, float64(binary.BigEndian.Uint32(b[20:24])))
+		)
+
+		*tick = models.Tick{
+			Mode:            string(ModeQuote),
+			InstrumentToken: tk,
+			IsTradable:      isTradable,
+			IsIndex:         isIndex,
+			LastPrice:       lastPrice,
+			NetChange:       lastPrice - closePrice,
+			OHLC: models.OHLC{
+				High:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[8:12]))),
+				Low:   convertPrice(seg, float64(binary.BigEndian.Uint32(b[12:16]))),
+				Open:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[16:20]))),
+				Close: closePrice,
+			}}
+
+		// On mode full set timestamp
+		if len(b) == modeFullIndexLength {
+			tick.Mode = string(ModeFull)
+			tick.Timestamp = models.Time{Time: time.Unix(int64(binary.BigEndian.Uint32(b[28:32])), 0)}
+		}
+
+		return nil
+	}
+
+	// Parse mode quote.
+	var (
+		lastPrice  = convertPrice(seg, float64(binary.BigEndian.Uint32(b[4:8])))
+		closePrice = convertPrice(seg, float64(binary.BigEndian.Uint32(b[40:44])))
+	)
+
+	// Mode quote data.
+	*tick = models.Tick{
+		Mode:               string(ModeQuote),
+		InstrumentToken:    tk,
+		IsTradable:         isTradable,
+		IsIndex:            isIndex,
+		LastPrice:          lastPrice,
+		LastTradedQuantity: binary.BigEndian.Uint32(b[8:12]),
+		AverageTradePrice:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[12:16]))),
+		VolumeTraded:       binary.BigEndian.Uint32(b[16:20]),
+		TotalBuyQuantity:   binary.BigEndian.Uint32(b[20:24]),
+		TotalSellQuantity:  binary.BigEndian.Uint32(b[24:28]),
+		OHLC: models.OHLC{
+			Open:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[28:32]))),
+			High:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[32:36]))),
+			Low:   convertPrice(seg, float64(binary.BigEndian.Uint32(b[36:40]))),
+			Close: closePrice,
+		},
+	}
+
+	// Parse full mode.
+	if len(b) == modeFullLength {
+		tick.Mode = string(ModeFull)
+		tick.LastTradeTime = models.Time{Time: time.Unix(int64(binary.BigEndian.Uint32(b[44:48])), 0)}
+		tick.OI = binary.BigEndian.Uint32(b[48:52])
+		tick.OIDayHigh = binary.BigEndian.Uint32(b[52:56])
+		tick.OIDayLow = binary.BigEndian.Uint32(b[56:60])
+		tick.Timestamp = models.Time{Time: time.Unix(int64(binary.BigEndian.Uint32(b[60:64])), 0)}
+		tick.NetChange = lastPrice - closePrice
+
+		// Depth Information.
+		var (
+			buyPos  = 64
+			sellPos = 124
+		)
+
+		for i := 0; i < depthEntries; i++ {
+			tick.Depth.Buy[i] = models.DepthItem{
+				Quantity: binary.BigEndian.Uint32(b[buyPos : buyPos+4]),
+				Price:    convertPrice(seg, float64(binary.BigEndian.Uint32(b[buyPos+4:buyPos+8]))),
+				Orders:   uint32(binary.BigEndian.Uint16(b[buyPos+8 : buyPos+10])),
+			}
+
+			tick.Depth.Sell[i] = models.DepthItem{
+				Quantity: binary.BigEndian.Uint32(b[sellPos : sellPos+4]),
+				Price:    convertPrice(seg, float64(binary.BigEndian.Uint32(b[sellPos+4:sellPos+8]))),
+				Orders:   uint32(binary.BigEndian.Uint16(b[sellPos+8 : sellPos+10])),
+			}
+
+			buyPos += 12
+			sellPos += 12
+		}
+	}
+
+	return nil
+}
+
+// Parse parses a tick byte array into a tick struct.
+func parsePacket(b []byte) (models.Tick, error) {
+	var tick models.Tick
+	err := ParsePacket(b, &tick)
+	return tick, err
+}
+
+// convertPrice converts prices of stocks from paise to rupees
+// with varying decimals based on the segment.
+func convertPrice(seg uint32, val float64) float64 {
+	switch seg {
+	case NseCD:
+		return val / 10000000.0
+	case BseCD:
+		return val / 10000.0
+	default:
+		return val / 100.0
+	}
+}
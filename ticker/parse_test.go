@@ -0,0 +1,241 @@
+package kiteticker
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// frameOf builds a frame of raw packets with their length prefixes.
+func frameOf(pkts ...[]byte) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(len(pkts)))
+	for _, p := range pkts {
+		var l [2]byte
+		binary.BigEndian.PutUint16(l[:], uint16(len(p)))
+		b = append(b, l[:]...)
+		b = append(b, p...)
+	}
+	return b
+}
+
+func mustEncode(t testing.TB, tick models.Tick) []byte {
+	pkt, err := EncodeTick(tick)
+	require.NoError(t, err)
+	return pkt
+}
+
+func TestParseFrameMalformed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ltp  = mustEncode(t, models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1500})
+		full = mustEncode(t, models.Tick{Mode: "full", InstrumentToken: 738561, LastPrice: 2400})
+		good = frameOf(ltp, full)
+	)
+
+	tt := []struct {
+		name      string
+		frame     []byte
+		tokens    []uint32
+		bad       []int
+		truncated bool
+	}{
+		{name: "empty", frame: nil},
+		{name: "heartbeat", frame: []byte{0}},
+		{name: "no packets", frame: []byte{0, 0}},
+		{name: "valid", frame: good, tokens: []uint32{408065, 738561}},
+		{name: "missing length", frame: good[:len(good)-len(full)-2], tokens: []uint32{408065}, truncated: true},
+		{name: "short packet", frame: good[:len(good)-1], tokens: []uint32{408065}, truncated: true},
+		{name: "count too large", frame: append([]byte{0, 3}, good[2:]...), tokens: []uint32{408065, 738561}, truncated: true},
+		{name: "invalid length", frame: frameOf(ltp, make([]byte, 10), full), tokens: []uint32{408065, 738561}, bad: []int{1}},
+		{name: "zero length", frame: frameOf([]byte{}, ltp), tokens: []uint32{408065}, bad: []int{0}},
+		{name: "trailing bytes", frame: append(frameOf(ltp), 1, 2, 3), tokens: []uint32{408065}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ticks, err := ParseFrame(nil, tc.frame)
+
+			var tokens []uint32
+			for _, tick := range ticks {
+				tokens = append(tokens, tick.InstrumentToken)
+			}
+			require.Equal(t, tc.tokens, tokens)
+
+			if len(tc.bad) == 0 && !tc.truncated {
+				require.NoError(t, err)
+				return
+			}
+
+			var ferr *FrameError
+			require.True(t, errors.As(err, &ferr))
+			require.Equal(t, tc.truncated, ferr.Truncated)
+			require.Equal(t, tc.truncated, errors.Is(err, ErrTruncatedFrame))
+
+			var bad []int
+			for _, p := range ferr.Packets {
+				require.True(t, errors.Is(p, ErrInvalidPacketLength))
+				bad = append(bad, p.Index)
+			}
+			require.Equal(t, tc.bad, bad)
+		})
+	}
+}
+
+func TestParseFrameReuse(t *testing.T) {
+	t.Parallel()
+
+	frame, err := EncodeFrame(
+		models.Tick{Mode: "full", InstrumentToken: 408065, LastPrice: 1500},
+		models.Tick{Mode: "ltp", InstrumentToken: 738561, LastPrice: 2400},
+	)
+	require.NoError(t, err)
+
+	buf := make([]models.Tick, 0, 4)
+	ticks, err := ParseFrame(buf, frame)
+	require.NoError(t, err)
+	require.Len(t, ticks, 2)
+	require.Equal(t, &buf[:1][0], &ticks[0], "ticks are parsed into the buffer")
+
+	// Fields of the previous ticks don't leak into the next parse.
+	ticks, err = ParseFrame(ticks[:0], frameOf(mustEncode(t, models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1})))
+	require.NoError(t, err)
+	require.Equal(t, []models.Tick{{Mode: "ltp", InstrumentToken: 408065, IsTradable: true, LastPrice: 1}}, ticks)
+
+	// Ticks are appended after the existing ones.
+	ticks, err = ParseFrame(ticks, frame)
+	require.NoError(t, err)
+	require.Len(t, ticks, 3)
+}
+
+func TestParseFrameAllocs(t *testing.T) {
+	frame, err := EncodeFrame(
+		models.Tick{Mode: "full", InstrumentToken: 408065, LastPrice: 1500},
+		models.Tick{Mode: "quote", InstrumentToken: 256265, LastPrice: 17000},
+	)
+	require.NoError(t, err)
+
+	buf := make([]models.Tick, 0, 2)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = ParseFrame(buf[:0], frame)
+	})
+	require.Zero(t, allocs)
+}
+
+func TestHandleMessagePacketErrors(t *testing.T) {
+	t.Parallel()
+
+	var (
+		tk     = New("", "")
+		ticks  []uint32
+		errs   []error
+		ltp    = mustEncode(t, models.Tick{Mode: "ltp", InstrumentToken: 408065})
+		frame  = frameOf(ltp, make([]byte, 3), ltp)
+		cutoff = frame[:len(frame)-1]
+	)
+	tk.OnTick(func(tick models.Tick) { ticks = append(ticks, tick.InstrumentToken) })
+	tk.OnError(func(err error) { errs = append(errs, err) })
+
+	buf, err := tk.handleMessage(BinaryMessage, frame, nil)
+	require.Error(t, err)
+	require.Equal(t, []uint32{408065, 408065}, ticks)
+	require.Len(t, errs, 1)
+
+	tk.handleMessage(BinaryMessage, cutoff, buf[:0])
+	require.Equal(t, []uint32{408065, 408065, 408065}, ticks)
+	require.Len(t, errs, 3)
+}
+
+func FuzzParseFrame(f *testing.F) {
+	for _, ticks := range [][]models.Tick{
+		nil,
+		{{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1500}},
+		{{Mode: "quote", InstrumentToken: 256265, LastPrice: 17000}, {Mode: "full", InstrumentToken: 256265}},
+		{{Mode: "full", InstrumentToken: 412675, LastPrice: 74.25}, {Mode: "quote", InstrumentToken: 408065}},
+	} {
+		frame, err := EncodeFrame(ticks...)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(frame)
+		f.Add(frame[:len(frame)/2])
+	}
+	f.Add([]byte{0})
+	f.Add([]byte{0xff, 0xff, 0, 8})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		ticks, err := ParseFrame(nil, frame)
+
+		if ferr, ok := err.(*FrameError); ok {
+			require.NotEmpty(t, ferr.Error())
+		} else {
+			require.NoError(t, err)
+		}
+
+		// Every parsed tick encodes into a valid packet. Index packets of
+		// other segments are encoded differently so only the common fields
+		// are compared.
+		for _, tick := range ticks {
+			pkt, err := EncodeTick(tick)
+			require.NoError(t, err)
+
+			again, err := parsePacket(pkt)
+			require.NoError(t, err)
+			require.Equal(t, tick.Mode, again.Mode)
+			require.Equal(t, tick.InstrumentToken, again.InstrumentToken)
+			require.Equal(t, tick.LastPrice, again.LastPrice)
+			require.Equal(t, tick.OHLC, again.OHLC)
+		}
+	})
+}
+
+func benchFrame(b *testing.B, n int) []byte {
+	ticks := make([]models.Tick, n)
+	for i := range ticks {
+		ticks[i] = models.Tick{Mode: "full", InstrumentToken: uint32(i)<<8 | NseCM, LastPrice: float64(i)}
+	}
+
+	frame, err := EncodeFrame(ticks...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return frame
+}
+
+func BenchmarkParseFrame(b *testing.B) {
+	frame := benchFrame(b, 100)
+	b.SetBytes(int64(len(frame)))
+	b.ReportAllocs()
+
+	var buf []models.Tick
+	for i := 0; i < b.N; i++ {
+		buf, _ = ParseFrame(buf[:0], frame)
+	}
+}
+
+func BenchmarkParseFrameAlloc(b *testing.B) {
+	frame := benchFrame(b, 100)
+	b.SetBytes(int64(len(frame)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ParseFrame(nil, frame)
+	}
+}
+
+func BenchmarkParsePacket(b *testing.B) {
+	pkt := mustEncode(b, models.Tick{Mode: "full", InstrumentToken: 408065, LastPrice: 1500})
+	b.ReportAllocs()
+
+	var tick models.Tick
+	for i := 0; i < b.N; i++ {
+		ParsePacket(pkt, &tick)
+	}
+}
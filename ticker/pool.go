@@ -0,0 +1,606 @@
+package kiteticker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+const (
+	// MaxTokensPerConnection is the maximum number of instruments which
+	// can be subscribed on a single ticker connection.
+	MaxTokensPerConnection = 3000
+	// Default maximum number of connections of a pool.
+	defaultPoolConnections = 3
+)
+
+// ErrPoolFull is returned when the tokens can't be subscribed as all the
+// connections of a pool are at their limit.
+var ErrPoolFull = errors.New("ticker pool is full")
+
+// PoolOptions represents the options of a ticker pool.
+type PoolOptions struct {
+	// MaxConnections is the maximum number of connections. Defaults to 3.
+	MaxConnections int
+	// TokensPerConnection is the maximum number of tokens subscribed on a
+	// connection. Defaults to MaxTokensPerConnection.
+	TokensPerConnection int
+	// Configure is called with every new connection's ticker before it's
+	// started and can be used to change its settings. The pool overrides the
+	// OnTick, OnConnect, OnError, OnReconnect and OnNoReconnect callbacks set
+	// here, along with OnOrderUpdate of the first connection and the metrics if
+	// the pool has them. It must not change the pool's subscriptions.
+	Configure func(t *Ticker)
+}
+
+// poolCallbacks represents callbacks available in ticker pool.
+type poolCallbacks struct {
+	onTick        func(models.Tick)
+	onOrderUpdate func(kiteconnect.Order)
+	onConnect     func(int)
+	onError       func(int, error)
+	onReconnect   func(int, int, time.Duration)
+	onNoReconnect func(int, int)
+}
+
+// TickerPool shards subscriptions across multiple ticker connections and
+// merges their ticks and order updates. Each connection reconnects on its
+// own. Connections are identified by their shard number, with the first one
+// being 0, which remains the same for the lifetime of the connection.
+// Tokens are only moved between connections when unsubscribing leaves them
+// fitting in fewer connections. Connections added by Subscribe get just the
+// new tokens, the existing ones aren't spread onto them.
+type TickerPool struct {
+	apiKey      string
+	accessToken string
+	opt         PoolOptions
+
+	mu sync.RWMutex
+	// subMu is held across a change to the tokens of the connections and the
+	// calls which send it to them, so that they're made in the same order.
+	subMu     sync.Mutex
+	callbacks poolCallbacks
+	shards    []*poolShard
+	tokens    map[uint32]*poolShard
+	modes     map[uint32]Mode
+	nextID    int
+
+	// Set while serving to start new shards.
+	ctx context.Context
+	wg  sync.WaitGroup
+
+	cancel    context.CancelFunc
+	streams   streamSet
+	router    *Router
+	snapshots *SnapshotStore
+	metrics   Metrics
+}
+
+// poolShard is a single connection of the pool.
+type poolShard struct {
+	id     int
+	ticker *Ticker
+	tokens map[uint32]bool
+	cancel context.CancelFunc
+}
+
+// NewPool creates a new ticker pool.
+func NewPool(apiKey string, accessToken string, opt PoolOptions) *TickerPool {
+	if opt.MaxConnections <= 0 {
+		opt.MaxConnections = defaultPoolConnections
+	}
+	if opt.TokensPerConnection <= 0 || opt.TokensPerConnection > MaxTokensPerConnection {
+		opt.TokensPerConnection = MaxTokensPerConnection
+	}
+
+	p := &TickerPool{
+		apiKey:      apiKey,
+		accessToken: accessToken,
+		opt:         opt,
+		tokens:      map[uint32]*poolShard{},
+		modes:       map[uint32]Mode{},
+	}
+
+	// The first connection is always present to receive order updates.
+	s := p.newShard()
+	p.mu.Lock()
+	p.addShard(s)
+	p.mu.Unlock()
+
+	return p
+}
+
+// OnTick callback.
+func (p *TickerPool) OnTick(f func(tick models.Tick)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks.onTick = f
+}
+
+// OnOrderUpdate callback. As every connection receives all the order
+// updates, only the ones received by the first connection are delivered.
+func (p *TickerPool) OnOrderUpdate(f func(order kiteconnect.Order)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks.onOrderUpdate = f
+}
+
+// OnConnect callback.
+func (p *TickerPool) OnConnect(f func(shard int)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks.onConnect = f
+}
+
+// OnError callback.
+func (p *TickerPool) OnError(f func(shard int, err error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks.onError = f
+}
+
+// OnReconnect callback.
+func (p *TickerPool) OnReconnect(f func(shard int, attempt int, delay time.Duration)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks.onReconnect = f
+}
+
+// OnNoReconnect callback.
+func (p *TickerPool) OnNoReconnect(f func(shard int, attempt int)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks.onNoReconnect = f
+}
+
+// NewStream creates a new stream which receives the merged ticks, order
+// updates and errors of all the connections. Streams are closed when the pool
+// stops serving.
+func (p *TickerPool) NewStream(opt StreamOptions) *Stream {
+	return p.streams.add(opt)
+}
+
+// Serve starts all the connections of the pool. Since its blocking its
+// recommended to use it in a go routine.
+func (p *TickerPool) Serve() {
+	p.ServeWithContext(context.Background())
+}
+
+// ServeWithContext starts all the connections of the pool and blocks till the
+// context is cancelled or the pool is stopped. Connections added later due to
+// new subscriptions are started right away.
+func (p *TickerPool) ServeWithContext(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p.mu.Lock()
+	p.ctx, p.cancel = ctx, cancel
+	for _, s := range p.shards {
+		p.startShard(s)
+	}
+	p.mu.Unlock()
+
+	<-ctx.Done()
+
+	p.mu.Lock()
+	p.ctx = nil
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	p.streams.closeAll()
+}
+
+// Stop stops all the connections of the pool.
+func (p *TickerPool) Stop() {
+	p.mu.RLock()
+	cancel := p.cancel
+	p.mu.RUnlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Subscribe subscribes the tokens, spreading them across connections. New
+// connections are added when the existing ones are full. ErrPoolFull is returned,
+// without subscribing any of the tokens, if they don't fit in the pool.
+func (p *TickerPool) Subscribe(tokens []uint32) error {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	p.mu.Lock()
+	var (
+		fresh []uint32
+		seen  = map[uint32]bool{}
+	)
+	for _, tk := range tokens {
+		if _, ok := p.tokens[tk]; !ok && !seen[tk] {
+			fresh = append(fresh, tk)
+			seen[tk] = true
+		}
+	}
+
+	room := 0
+	for _, s := range p.shards {
+		room += p.opt.TokensPerConnection - len(s.tokens)
+	}
+	if len(fresh) > room+(p.opt.MaxConnections-len(p.shards))*p.opt.TokensPerConnection {
+		p.mu.Unlock()
+		return ErrPoolFull
+	}
+
+	need := 0
+	if len(fresh) > room {
+		need = (len(fresh) - room + p.opt.TokensPerConnection - 1) / p.opt.TokensPerConnection
+	}
+	p.mu.Unlock()
+
+	// New connections are configured without the lock as Configure may call
+	// the pool. The subscriptions can't change meanwhile as subMu is held.
+	var spare []*poolShard
+	for len(spare) < need {
+		spare = append(spare, p.newShard())
+	}
+
+	p.mu.Lock()
+
+	// Assign each token to the least loaded connection with room, adding
+	// connections only when all the existing ones are full.
+	batches := map[*poolShard][]uint32{}
+	for _, tk := range fresh {
+		s := p.leastLoaded()
+		if s == nil {
+			s, spare = spare[0], spare[1:]
+			p.addShard(s)
+		}
+		s.tokens[tk] = true
+		p.tokens[tk] = s
+		batches[s] = append(batches[s], tk)
+	}
+
+	// Subscribing the already subscribed tokens again resets their mode
+	// like it does on a single connection.
+	for _, tk := range tokens {
+		if !seen[tk] {
+			if s, ok := p.tokens[tk]; ok {
+				batches[s] = append(batches[s], tk)
+			}
+		}
+	}
+	for _, tk := range tokens {
+		delete(p.modes, tk)
+	}
+	p.mu.Unlock()
+
+	for s, tks := range batches {
+		if err := s.ticker.Subscribe(tks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unsubscribe unsubscribes the tokens. Connections left with fewer tokens
+// are merged and the surplus connections are closed.
+func (p *TickerPool) Unsubscribe(tokens []uint32) error {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	p.mu.Lock()
+	batches := map[*poolShard][]uint32{}
+	for _, tk := range tokens {
+		s, ok := p.tokens[tk]
+		if !ok {
+			continue
+		}
+		delete(s.tokens, tk)
+		delete(p.tokens, tk)
+		delete(p.modes, tk)
+		batches[s] = append(batches[s], tk)
+	}
+	p.mu.Unlock()
+
+	for s, tks := range batches {
+		if err := s.ticker.Unsubscribe(tks); err != nil {
+			return err
+		}
+	}
+
+	return p.rebalance()
+}
+
+// SetMode changes mode for the given tokens on their connections.
+// Tokens which aren't subscribed are ignored.
+func (p *TickerPool) SetMode(mode Mode, tokens []uint32) error {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	p.mu.Lock()
+	batches := map[*poolShard][]uint32{}
+	for _, tk := range tokens {
+		if s, ok := p.tokens[tk]; ok {
+			p.modes[tk] = mode
+			batches[s] = append(batches[s], tk)
+		}
+	}
+	p.mu.Unlock()
+
+	for s, tks := range batches {
+		if err := s.ticker.SetMode(mode, tks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Subscriptions returns the subscribed tokens along with their modes.
+// Tokens subscribed without setting a mode have an empty mode.
+func (p *TickerPool) Subscriptions() map[uint32]Mode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make(map[uint32]Mode, len(p.tokens))
+	for tk := range p.tokens {
+		out[tk] = p.modes[tk]
+	}
+	return out
+}
+
+// Shards returns the tokens subscribed on every connection by its shard number.
+func (p *TickerPool) Shards() map[int][]uint32 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make(map[int][]uint32, len(p.shards))
+	for _, s := range p.shards {
+		tks := make([]uint32, 0, len(s.tokens))
+		for tk := range s.tokens {
+			tks = append(tks, tk)
+		}
+		sort.Slice(tks, func(i, j int) bool { return tks[i] < tks[j] })
+		out[s.id] = tks
+	}
+	return out
+}
+
+// rebalance closes the surplus connections by moving their tokens to the
+// other connections when the subscriptions fit in fewer connections. It must
+// be called with subMu held.
+func (p *TickerPool) rebalance() error {
+	p.mu.Lock()
+
+	var (
+		moves   = map[*poolShard][]uint32{}
+		removed []*poolShard
+	)
+	for len(p.shards) > 1 {
+		need := (len(p.tokens) + p.opt.TokensPerConnection - 1) / p.opt.TokensPerConnection
+		if need < 1 {
+			need = 1
+		}
+		if len(p.shards) <= need {
+			break
+		}
+
+		// Remove the least loaded connection other than the first one.
+		victim := p.shards[1]
+		for _, s := range p.shards[2:] {
+			if len(s.tokens) < len(victim.tokens) {
+				victim = s
+			}
+		}
+		p.removeShard(victim)
+		removed = append(removed, victim)
+
+		for tk := range victim.tokens {
+			s := p.leastLoaded()
+			s.tokens[tk] = true
+			p.tokens[tk] = s
+			moves[s] = append(moves[s], tk)
+		}
+	}
+
+	modes := map[*poolShard]map[Mode][]uint32{}
+	for s, tks := range moves {
+		for _, tk := range tks {
+			if mo, ok := p.modes[tk]; ok {
+				if modes[s] == nil {
+					modes[s] = map[Mode][]uint32{}
+				}
+				modes[s][mo] = append(modes[s][mo], tk)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	// Subscribe on the new connections before closing the old ones.
+	for s, tks := range moves {
+		if err := s.ticker.Subscribe(tks); err != nil {
+			return err
+		}
+		for mo, mtks := range modes[s] {
+			if err := s.ticker.SetMode(mo, mtks); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s := range removed {
+		if s.cancel != nil {
+			s.cancel()
+		}
+	}
+
+	return nil
+}
+
+// leastLoaded returns the connection with the fewest tokens which has room
+// for more. It must be called with the lock held.
+func (p *TickerPool) leastLoaded() *poolShard {
+	var out *poolShard
+	for _, s := range p.shards {
+		if len(s.tokens) >= p.opt.TokensPerConnection {
+			continue
+		}
+		if out == nil || len(s.tokens) < len(out.tokens) {
+			out = s
+		}
+	}
+	return out
+}
+
+// newShard creates a connection which isn't added to the pool yet. It must be
+// called without the lock held as the Configure callback may call the pool.
+func (p *TickerPool) newShard() *poolShard {
+	s := &poolShard{
+		ticker: New(p.apiKey, p.accessToken),
+		tokens: map[uint32]bool{},
+	}
+
+	if p.opt.Configure != nil {
+		p.opt.Configure(s.ticker)
+	}
+
+	// The shard number is set when the connection is added, before it's started.
+	t := s.ticker
+	t.OnTick(p.triggerTick)
+	t.OnConnect(func() {
+		p.triggerConnect(s.id)
+	})
+	t.OnError(func(err error) {
+		p.triggerError(s.id, err)
+	})
+	t.OnReconnect(func(attempt int, delay time.Duration) {
+		p.triggerReconnect(s.id, attempt, delay)
+	})
+	t.OnNoReconnect(func(attempt int) {
+		p.triggerNoReconnect(s.id, attempt)
+	})
+
+	return s
+}
+
+// addShard adds a connection created by newShard and starts it if the pool is
+// serving. It must be called with the lock held.
+func (p *TickerPool) addShard(s *poolShard) {
+	s.id = p.nextID
+	p.nextID++
+
+	if p.metrics != nil {
+		s.ticker.SetMetrics(p.metrics)
+	}
+	if s.id == 0 {
+		s.ticker.OnOrderUpdate(p.triggerOrderUpdate)
+	}
+
+	p.shards = append(p.shards, s)
+	if p.ctx != nil {
+		p.startShard(s)
+	}
+}
+
+// removeShard removes a connection from the pool. It must be called with the lock held.
+func (p *TickerPool) removeShard(s *poolShard) {
+	for i, sh := range p.shards {
+		if sh == s {
+			p.shards = append(p.shards[:i], p.shards[i+1:]...)
+			return
+		}
+	}
+}
+
+// startShard starts serving a connection. It must be called with the lock held.
+func (p *TickerPool) startShard(s *poolShard) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		s.ticker.ServeWithContext(ctx)
+	}()
+}
+
+func (p *TickerPool) triggerTick(tick models.Tick) {
+	p.mu.RLock()
+	f := p.callbacks.onTick
+	r := p.router
+	snaps := p.snapshots
+	p.mu.RUnlock()
+
+	// The snapshot is updated before the callbacks so that they see the tick in it.
+	if snaps != nil {
+		snaps.Update(tick)
+	}
+
+	if f != nil {
+		f(tick)
+	}
+
+	if r != nil {
+		r.Dispatch(tick)
+	}
+
+	p.streams.pushTick(tick)
+}
+
+func (p *TickerPool) triggerOrderUpdate(order kiteconnect.Order) {
+	p.mu.RLock()
+	f := p.callbacks.onOrderUpdate
+	p.mu.RUnlock()
+
+	if f != nil {
+		f(order)
+	}
+
+	p.streams.pushOrder(order)
+}
+
+func (p *TickerPool) triggerConnect(shard int) {
+	p.mu.RLock()
+	f := p.callbacks.onConnect
+	p.mu.RUnlock()
+
+	if f != nil {
+		f(shard)
+	}
+}
+
+func (p *TickerPool) triggerError(shard int, err error) {
+	p.mu.RLock()
+	f := p.callbacks.onError
+	p.mu.RUnlock()
+
+	if f != nil {
+		f(shard, err)
+	}
+
+	p.streams.pushError(err)
+}
+
+func (p *TickerPool) triggerReconnect(shard int, attempt int, delay time.Duration) {
+	p.mu.RLock()
+	f := p.callbacks.onReconnect
+	p.mu.RUnlock()
+
+	if f != nil {
+		f(shard, attempt, delay)
+	}
+}
+
+func (p *TickerPool) triggerNoReconnect(shard int, attempt int) {
+	p.mu.RLock()
+	f := p.callbacks.onNoReconnect
+	p.mu.RUnlock()
+
+	if f != nil {
+		f(shard, attempt)
+	}
+}
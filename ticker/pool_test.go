@@ -0,0 +1,186 @@
+package kiteticker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func TestPoolSharding(t *testing.T) {
+	t.Parallel()
+
+	p := NewPool("key", "token", PoolOptions{MaxConnections: 3, TokensPerConnection: 2})
+
+	// Connections are added only when the existing ones are full.
+	require.NoError(t, p.Subscribe([]uint32{1, 2, 3}))
+	require.Equal(t, map[int][]uint32{0: {1, 2}, 1: {3}}, p.Shards())
+
+	// Already subscribed tokens stay on their connection.
+	require.NoError(t, p.Subscribe([]uint32{1, 4, 5}))
+	require.Equal(t, map[int][]uint32{0: {1, 2}, 1: {3, 4}, 2: {5}}, p.Shards())
+
+	require.Equal(t, ErrPoolFull, p.Subscribe([]uint32{6, 7}))
+	require.Len(t, p.Subscriptions(), 5)
+
+	require.NoError(t, p.SetMode(ModeFull, []uint32{2, 4, 9}))
+	require.Equal(t, map[uint32]Mode{1: "", 2: ModeFull, 3: "", 4: ModeFull, 5: ""}, p.Subscriptions())
+
+	// Tokens fit in two connections after unsubscribing, so the least loaded
+	// connection is closed and its tokens are moved.
+	require.NoError(t, p.Unsubscribe([]uint32{3}))
+	require.Equal(t, map[int][]uint32{0: {1, 2}, 2: {4, 5}}, p.Shards())
+	require.Equal(t, map[uint32]Mode{4: ModeFull, 5: ""}, p.shards[1].ticker.Subscriptions())
+
+	require.NoError(t, p.Unsubscribe([]uint32{1, 2, 5}))
+	require.Equal(t, map[int][]uint32{0: {4}}, p.Shards())
+	require.Equal(t, map[uint32]Mode{4: ModeFull}, p.shards[0].ticker.Subscriptions())
+
+	// New connections get new shard numbers.
+	require.NoError(t, p.Subscribe([]uint32{6, 7}))
+	require.Equal(t, map[int][]uint32{0: {4, 6}, 3: {7}}, p.Shards())
+}
+
+func TestPoolConcurrentSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	p := NewPool("key", "token", PoolOptions{MaxConnections: 8, TokensPerConnection: 5})
+
+	// Subscribing and unsubscribing concurrently adds and closes connections.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tks := []uint32{uint32(i*10 + j%5), uint32(i*10 + j%5 + 5)}
+				require.NoError(t, p.Subscribe(tks))
+				require.NoError(t, p.SetMode(ModeQuote, tks[:1]))
+				if j%3 == 0 {
+					require.NoError(t, p.Unsubscribe(tks))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Every connection's ticker has exactly the tokens the pool assigned to it.
+	subs := p.Subscriptions()
+	for _, s := range p.shards {
+		want := map[uint32]Mode{}
+		for tk := range s.tokens {
+			want[tk] = subs[tk]
+		}
+		require.Equal(t, want, s.ticker.Subscriptions(), "shard %d", s.id)
+	}
+}
+
+func TestPoolConfigure(t *testing.T) {
+	t.Parallel()
+
+	// Configure can call the pool as it's called without the pool's lock.
+	var (
+		p       *TickerPool
+		configs int
+	)
+	p = NewPool("key", "token", PoolOptions{
+		TokensPerConnection: 1,
+		Configure: func(t *Ticker) {
+			configs++
+			if p != nil {
+				p.SetMetrics(nil)
+				p.Shards()
+			}
+		},
+	})
+
+	require.NoError(t, p.Subscribe([]uint32{1, 2, 3}))
+	require.Equal(t, map[int][]uint32{0: {1}, 1: {2}, 2: {3}}, p.Shards())
+	require.Equal(t, 3, configs)
+}
+
+func TestPoolServe(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	p := NewPool("key", "token", PoolOptions{
+		TokensPerConnection: 2,
+		Configure: func(t *Ticker) {
+			t.SetRootURL(srv.url())
+		},
+	})
+
+	var (
+		mu     sync.Mutex
+		ticks  = map[uint32]int{}
+		orders []string
+	)
+	p.OnTick(func(tick models.Tick) {
+		mu.Lock()
+		ticks[tick.InstrumentToken]++
+		mu.Unlock()
+	})
+	p.OnOrderUpdate(func(order kiteconnect.Order) {
+		mu.Lock()
+		orders = append(orders, order.OrderID)
+		mu.Unlock()
+	})
+	s := p.NewStream(StreamOptions{})
+
+	require.NoError(t, p.Subscribe([]uint32{1, 2, 3}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.ServeWithContext(ctx)
+		close(done)
+	}()
+
+	// Every connection receives all the order updates and its own ticks.
+	conns := []*websocket.Conn{srv.waitConn(t), srv.waitConn(t)}
+	for _, conn := range conns {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "order", "data": {"order_id": "1"}}`)))
+	}
+	require.NoError(t, conns[0].WriteMessage(websocket.BinaryMessage, ltpPacket(10, 1, 3)))
+	require.NoError(t, conns[1].WriteMessage(websocket.BinaryMessage, ltpPacket(10, 2)))
+
+	// Subscribing more tokens while serving starts a new connection.
+	require.NoError(t, p.Subscribe([]uint32{4, 5}))
+	conn := srv.waitConn(t)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, ltpPacket(10, 5)))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ticks) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pool to stop")
+	}
+
+	mu.Lock()
+	require.Equal(t, []string{"1"}, orders)
+	mu.Unlock()
+
+	var (
+		streamed int
+		updates  int
+	)
+	for range s.Ticks() {
+		streamed++
+	}
+	for range s.OrderUpdates() {
+		updates++
+	}
+	require.Equal(t, 4, streamed)
+	require.Equal(t, 1, updates)
+}
@@ -0,0 +1,266 @@
+package kiteticker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Recordings are a sequence of records, each starting with a kind byte.
+// A header record, written whenever a recorder is created, is followed by a
+// magic and a version. Frame records have the websocket message type as the kind,
+// followed by the varint nanoseconds since the previous frame (or the unix
+// time for the first frame after a header), the uvarint length and the payload.
+const (
+	recordHeader  byte = 0
+	recordVersion byte = 1
+)
+
+var recordMagic = []byte("KTR")
+
+// ErrInvalidRecording is returned when a recording can't be read.
+var ErrInvalidRecording = errors.New("invalid ticker recording")
+
+// Frame represents a websocket message received by the ticker.
+type Frame struct {
+	Time time.Time
+	// Type is TextMessage or BinaryMessage.
+	Type int
+	Data []byte
+}
+
+// Recorder writes the websocket messages received by a ticker to a compact
+// append-only recording which can be replayed later. It's safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+	last   int64
+	first  bool
+}
+
+// NewRecorder creates a recorder which writes to w. Writes are buffered
+// and Flush or Close must be called once done.
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	r := &Recorder{w: bufio.NewWriter(w), first: true}
+
+	r.w.WriteByte(recordHeader)
+	r.w.Write(recordMagic)
+	if err := r.w.WriteByte(recordVersion); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// OpenRecorder creates a recorder which appends to the file at path,
+// creating it if required. Close closes the file.
+func OpenRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewRecorder(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+
+	return r, nil
+}
+
+// Record records a message received now.
+func (r *Recorder) Record(messageType int, message []byte) error {
+	return r.RecordAt(time.Now(), messageType, message)
+}
+
+// RecordAt records a message received at the given time.
+func (r *Recorder) RecordAt(t time.Time, messageType int, message []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("can't record message type %d", messageType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := t.UnixNano()
+	delta := ts - r.last
+	if r.first {
+		delta, r.first = ts, false
+	}
+	r.last = ts
+
+	var buf [2 * binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], delta)
+	n += binary.PutUvarint(buf[n:], uint64(len(message)))
+
+	r.w.WriteByte(byte(messageType))
+	r.w.Write(buf[:n])
+	_, err := r.w.Write(message)
+	return err
+}
+
+// Flush writes the buffered records.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.w.Flush()
+}
+
+// Close flushes the buffered records and closes the file if the recorder was opened with OpenRecorder.
+func (r *Recorder) Close() error {
+	err := r.Flush()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// SetRecorder sets the recorder to which every message received is written.
+// Pass nil to stop recording.
+func (t *Ticker) SetRecorder(r *Recorder) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recorder = r
+}
+
+func (t *Ticker) getRecorder() *Recorder {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.recorder
+}
+
+// FrameReader reads the frames of a recording.
+type FrameReader struct {
+	r      *bufio.Reader
+	last   int64
+	header bool
+	first  bool
+}
+
+// NewFrameReader creates a reader for the recording in r.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next frame. io.EOF is returned at the end of the recording.
+func (f *FrameReader) Next() (Frame, error) {
+	for {
+		kind, err := f.r.ReadByte()
+		if err != nil {
+			return Frame{}, err
+		}
+
+		if kind == recordHeader {
+			var hdr [4]byte
+			if _, err := io.ReadFull(f.r, hdr[:]); err != nil {
+				return Frame{}, ErrInvalidRecording
+			}
+			if !bytes.Equal(hdr[:3], recordMagic) || hdr[3] != recordVersion {
+				return Frame{}, ErrInvalidRecording
+			}
+			f.header, f.first = true, true
+			continue
+		}
+
+		if !f.header || (int(kind) != TextMessage && int(kind) != BinaryMessage) {
+			return Frame{}, ErrInvalidRecording
+		}
+
+		delta, err := binary.ReadVarint(f.r)
+		if err != nil {
+			return Frame{}, ErrInvalidRecording
+		}
+		size, err := binary.ReadUvarint(f.r)
+		if err != nil || size > 1<<30 {
+			return Frame{}, ErrInvalidRecording
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(f.r, data); err != nil {
+			return Frame{}, ErrInvalidRecording
+		}
+
+		if f.first {
+			f.last, f.first = delta, false
+		} else {
+			f.last += delta
+		}
+
+		return Frame{Time: time.Unix(0, f.last), Type: int(kind), Data: data}, nil
+	}
+}
+
+// ReplayOptions represents the options for replaying a recording.
+type ReplayOptions struct {
+	// Speed of replay relative to the recorded timing, 1 being real speed
+	// and 10 ten times faster. 0 replays as fast as possible.
+	Speed float64
+}
+
+// Replay feeds the frames of a recording to the ticker, triggering the same
+// callbacks and stream deliveries as they did when they were received.
+// The ticker doesn't have to be connected. It blocks till the recording ends
+// or the context is cancelled, after which the ticker's streams are closed.
+func (t *Ticker) Replay(ctx context.Context, r io.Reader, opt ReplayOptions) error {
+	defer t.streams.closeAll()
+
+	var (
+		fr    = NewFrameReader(r)
+		ticks []models.Tick
+		start time.Time
+		first time.Time
+		timer *time.Timer
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		f, err := fr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if opt.Speed > 0 {
+			if start.IsZero() {
+				start, first = time.Now(), f.Time
+			}
+
+			at := start.Add(time.Duration(float64(f.Time.Sub(first)) / opt.Speed))
+			if wait := time.Until(at); wait > 0 {
+				if timer == nil {
+					timer = time.NewTimer(wait)
+					defer timer.Stop()
+				} else {
+					timer.Reset(wait)
+				}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+
+		ticks, _ = t.handleMessage(f.Type, f.Data, ticks[:0])
+	}
+}
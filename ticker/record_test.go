@@ -0,0 +1,174 @@
+package kiteticker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+const orderMessage = `{"type": "order", "data": {"order_id": "1", "status": "COMPLETE"}}`
+
+// collector collects the callbacks triggered by a ticker.
+type collector struct {
+	mu     sync.Mutex
+	ticks  []models.Tick
+	orders []kiteconnect.Order
+}
+
+func (c *collector) attach(t *Ticker) {
+	t.OnTick(func(tick models.Tick) {
+		c.mu.Lock()
+		c.ticks = append(c.ticks, tick)
+		c.mu.Unlock()
+	})
+	t.OnOrderUpdate(func(order kiteconnect.Order) {
+		c.mu.Lock()
+		c.orders = append(c.orders, order)
+		c.mu.Unlock()
+	})
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.ticks) + len(c.orders)
+}
+
+func TestRecordFrames(t *testing.T) {
+	t.Parallel()
+
+	var (
+		buf bytes.Buffer
+		t0  = time.Unix(1625461887, 5)
+	)
+	rec, err := NewRecorder(&buf)
+	require.NoError(t, err)
+	require.NoError(t, rec.RecordAt(t0, BinaryMessage, ltpPacket(10, 1)))
+	require.NoError(t, rec.RecordAt(t0.Add(time.Second), TextMessage, []byte(orderMessage)))
+	require.Error(t, rec.RecordAt(t0, PingMessage, nil))
+	require.NoError(t, rec.Close())
+
+	// Appending starts with a new header.
+	rec, err = NewRecorder(&buf)
+	require.NoError(t, err)
+	require.NoError(t, rec.RecordAt(t0.Add(-time.Hour), BinaryMessage, nil))
+	require.NoError(t, rec.Close())
+
+	fr := NewFrameReader(bytes.NewReader(buf.Bytes()))
+	var frames []Frame
+	for {
+		f, err := fr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		frames = append(frames, f)
+	}
+
+	require.Len(t, frames, 3)
+	require.True(t, t0.Equal(frames[0].Time))
+	require.Equal(t, BinaryMessage, frames[0].Type)
+	require.Equal(t, ltpPacket(10, 1), frames[0].Data)
+	require.True(t, t0.Add(time.Second).Equal(frames[1].Time))
+	require.Equal(t, orderMessage, string(frames[1].Data))
+	require.True(t, t0.Add(-time.Hour).Equal(frames[2].Time))
+	require.Empty(t, frames[2].Data)
+
+	// Truncated and corrupt recordings.
+	_, err = NewFrameReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3])).Next()
+	require.NoError(t, err)
+	fr = NewFrameReader(bytes.NewReader(buf.Bytes()[:12]))
+	_, err = fr.Next()
+	require.Equal(t, ErrInvalidRecording, err)
+	_, err = NewFrameReader(bytes.NewReader([]byte{BinaryMessage, 0, 0})).Next()
+	require.Equal(t, ErrInvalidRecording, err)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	path := filepath.Join(t.TempDir(), "ticks.rec")
+	rec, err := OpenRecorder(path)
+	require.NoError(t, err)
+
+	var live collector
+	tk := New("key", "token")
+	tk.SetRootURL(srv.url())
+	tk.SetRecorder(rec)
+	live.attach(tk)
+
+	done := serve(context.Background(), tk)
+	conn := srv.waitConn(t)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, ltpPacket(10.5, 1, 2)))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(orderMessage)))
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, ltpPacket(11, 1)))
+
+	require.Eventually(t, func() bool {
+		return live.count() == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	tk.Stop()
+	waitDone(t, done)
+	require.NoError(t, rec.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	// Replay produces identical callbacks.
+	var replayed collector
+	rt := New("", "")
+	replayed.attach(rt)
+	s := rt.NewStream(StreamOptions{})
+	require.NoError(t, rt.Replay(context.Background(), bytes.NewReader(data), ReplayOptions{}))
+
+	require.Equal(t, live.ticks, replayed.ticks)
+	require.Equal(t, live.orders, replayed.orders)
+
+	var streamed []models.Tick
+	for tick := range s.Ticks() {
+		streamed = append(streamed, tick)
+	}
+	require.Equal(t, live.ticks, streamed)
+}
+
+func TestReplaySpeed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		buf bytes.Buffer
+		t0  = time.Now()
+	)
+	rec, err := NewRecorder(&buf)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rec.RecordAt(t0.Add(time.Duration(i)*time.Second), BinaryMessage, ltpPacket(10, 1)))
+	}
+	require.NoError(t, rec.Close())
+
+	var c collector
+	tk := New("", "")
+	c.attach(tk)
+
+	// 2 seconds of data at 40x takes 50ms.
+	start := time.Now()
+	require.NoError(t, tk.Replay(context.Background(), bytes.NewReader(buf.Bytes()), ReplayOptions{Speed: 40}))
+	require.True(t, time.Since(start) >= 50*time.Millisecond)
+	require.Equal(t, 3, c.count())
+
+	// Cancelling stops the replay.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = tk.Replay(ctx, bytes.NewReader(buf.Bytes()), ReplayOptions{Speed: 1})
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, 4, c.count())
+}
@@ -0,0 +1,323 @@
+package kiteticker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// ErrUnknownInstrument is returned when an exchange:symbol isn't in the router's instruments.
+var ErrUnknownInstrument = errors.New("unknown instrument")
+
+// Subscriber subscribes to instruments on the websocket. It's implemented by
+// Ticker and TickerPool.
+type Subscriber interface {
+	Subscribe(tokens []uint32) error
+	Unsubscribe(tokens []uint32) error
+	SetMode(mode Mode, tokens []uint32) error
+}
+
+// Router delivers ticks to the handlers registered for their instruments and
+// manages the subscriptions of those instruments. An instrument is subscribed
+// when the first handler for it is registered and unsubscribed when the last
+// one is closed. It's subscribed in the most detailed mode requested by its
+// handlers, so a handler may receive ticks of a more detailed mode than it asked for.
+//
+// Instruments routed by a router shouldn't be subscribed or unsubscribed
+// directly on the ticker. It's safe for concurrent use and handlers may be
+// registered or closed from within a handler.
+type Router struct {
+	sub Subscriber
+
+	mu      sync.RWMutex
+	routes  map[uint32][]*Route
+	modes   map[uint32]Mode
+	symbols map[string]uint32
+}
+
+// Route is a handler registered on a router.
+type Route struct {
+	router  *Router
+	tokens  []uint32
+	mode    Mode
+	handler func(models.Tick)
+	closed  int32
+}
+
+// NewRouter creates a router which subscribes to instruments using sub.
+// Ticks must be passed to Dispatch to be delivered, which Ticker.Router and
+// TickerPool.Router do.
+func NewRouter(sub Subscriber) *Router {
+	return &Router{
+		sub:     sub,
+		routes:  map[uint32][]*Route{},
+		modes:   map[uint32]Mode{},
+		symbols: map[string]uint32{},
+	}
+}
+
+// Router returns the router of the ticker, which is delivered all the ticks received.
+func (t *Ticker) Router() *Router {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.router == nil {
+		t.router = NewRouter(t)
+	}
+	return t.router
+}
+
+// Router returns the router of the pool, which is delivered all the ticks received.
+func (p *TickerPool) Router() *Router {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.router == nil {
+		p.router = NewRouter(p)
+	}
+	return p.router
+}
+
+// SetInstruments sets the instruments used to look up exchange:symbol pairs,
+// replacing the previous ones.
+func (r *Router) SetInstruments(instruments kiteconnect.Instruments) {
+	symbols := make(map[string]uint32, len(instruments))
+	for _, i := range instruments {
+		symbols[i.Exchange+":"+i.Tradingsymbol] = uint32(i.InstrumentToken)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.symbols = symbols
+}
+
+// LoadInstruments fetches the instrument master and sets it as the router's instruments.
+func (r *Router) LoadInstruments(c *kiteconnect.Client) error {
+	instruments, err := c.GetInstruments()
+	if err != nil {
+		return err
+	}
+
+	r.SetInstruments(instruments)
+	return nil
+}
+
+// Token returns the instrument token of an exchange:symbol pair, eg: NSE:INFY.
+func (r *Router) Token(symbol string) (uint32, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tk, ok := r.symbols[symbol]
+	return tk, ok
+}
+
+// Handle registers a handler for the ticks of the given instruments, subscribing
+// to them in at least the given mode.
+func (r *Router) Handle(tokens []uint32, mode Mode, handler func(tick models.Tick)) (*Route, error) {
+	if modeRank(mode) == 0 {
+		return nil, fmt.Errorf("invalid mode: %q", mode)
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("no instruments to handle")
+	}
+
+	// Drop duplicates so that the route is counted once per instrument.
+	var (
+		uniq = make([]uint32, 0, len(tokens))
+		seen = make(map[uint32]bool, len(tokens))
+	)
+	for _, tk := range tokens {
+		if !seen[tk] {
+			seen[tk] = true
+			uniq = append(uniq, tk)
+		}
+	}
+
+	rt := &Route{router: r, tokens: uniq, mode: mode, handler: handler}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var added []uint32
+	for _, tk := range rt.tokens {
+		if len(r.routes[tk]) == 0 {
+			added = append(added, tk)
+		}
+		// The full slice expression makes append copy rather than
+		// modifying the routes being dispatched.
+		routes := r.routes[tk]
+		r.routes[tk] = append(routes[:len(routes):len(routes)], rt)
+	}
+
+	if len(added) > 0 {
+		if err := r.sub.Subscribe(added); err != nil {
+			r.remove(rt)
+			return nil, err
+		}
+	}
+
+	if err := r.sync(rt.tokens); err != nil {
+		r.remove(rt)
+		r.sub.Unsubscribe(added)
+		for _, tk := range added {
+			delete(r.modes, tk)
+		}
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+// HandleSymbols registers a handler for the ticks of the given exchange:symbol
+// pairs, eg: NSE:INFY, which are looked up in the router's instruments.
+func (r *Router) HandleSymbols(symbols []string, mode Mode, handler func(tick models.Tick)) (*Route, error) {
+	tokens := make([]uint32, 0, len(symbols))
+	for _, s := range symbols {
+		tk, ok := r.Token(s)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownInstrument, s)
+		}
+		tokens = append(tokens, tk)
+	}
+
+	return r.Handle(tokens, mode, handler)
+}
+
+// Dispatch delivers a tick to the handlers of its instrument.
+func (r *Router) Dispatch(tick models.Tick) {
+	r.mu.RLock()
+	routes := r.routes[tick.InstrumentToken]
+	r.mu.RUnlock()
+
+	// Routes are never modified in place so they can be iterated without the lock.
+	for _, rt := range routes {
+		if atomic.LoadInt32(&rt.closed) == 0 {
+			rt.handler(tick)
+		}
+	}
+}
+
+// Routes returns the number of handlers registered for every routed instrument.
+func (r *Router) Routes() map[uint32]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[uint32]int, len(r.routes))
+	for tk, routes := range r.routes {
+		out[tk] = len(routes)
+	}
+	return out
+}
+
+// Tokens returns the instruments of the route.
+func (rt *Route) Tokens() []uint32 {
+	return append([]uint32(nil), rt.tokens...)
+}
+
+// Close unregisters the handler, unsubscribing from the instruments which have
+// no other handlers and downgrading the mode of the others if required. The
+// handler isn't called once Close returns, unless it's already running.
+func (rt *Route) Close() error {
+	if !atomic.CompareAndSwapInt32(&rt.closed, 0, 1) {
+		return nil
+	}
+
+	r := rt.router
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(rt)
+
+	var (
+		removed []uint32
+		rest    []uint32
+	)
+	for _, tk := range rt.tokens {
+		if len(r.routes[tk]) == 0 {
+			removed = append(removed, tk)
+			delete(r.modes, tk)
+		} else {
+			rest = append(rest, tk)
+		}
+	}
+
+	if len(removed) > 0 {
+		if err := r.sub.Unsubscribe(removed); err != nil {
+			return err
+		}
+	}
+
+	return r.sync(rest)
+}
+
+// remove removes a route from its instruments. Slices are copied so that
+// concurrent dispatches see either the old or the new routes.
+func (r *Router) remove(rt *Route) {
+	for _, tk := range rt.tokens {
+		var (
+			old    = r.routes[tk]
+			routes = make([]*Route, 0, len(old))
+		)
+		for _, o := range old {
+			if o != rt {
+				routes = append(routes, o)
+			}
+		}
+
+		if len(routes) == 0 {
+			delete(r.routes, tk)
+		} else {
+			r.routes[tk] = routes
+		}
+	}
+}
+
+// sync sets the mode of the instruments to the most detailed one requested by their routes.
+func (r *Router) sync(tokens []uint32) error {
+	changes := map[Mode][]uint32{}
+	for _, tk := range tokens {
+		var mode Mode
+		for _, rt := range r.routes[tk] {
+			if modeRank(rt.mode) > modeRank(mode) {
+				mode = rt.mode
+			}
+		}
+
+		if mode != r.modes[tk] {
+			changes[mode] = append(changes[mode], tk)
+		}
+	}
+
+	// Apply in a fixed order of modes.
+	for _, mode := range []Mode{ModeLTP, ModeQuote, ModeFull} {
+		if len(changes[mode]) == 0 {
+			continue
+		}
+		if err := r.sub.SetMode(mode, changes[mode]); err != nil {
+			return err
+		}
+		for _, tk := range changes[mode] {
+			r.modes[tk] = mode
+		}
+	}
+
+	return nil
+}
+
+// modeRank orders the modes by the detail of their ticks. Invalid modes are 0.
+func modeRank(m Mode) int {
+	switch m {
+	case ModeLTP:
+		return 1
+	case ModeQuote:
+		return 2
+	case ModeFull:
+		return 3
+	default:
+		return 0
+	}
+}
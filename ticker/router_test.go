@@ -0,0 +1,211 @@
+package kiteticker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// fakeSubscriber records the subscription calls of a router.
+type fakeSubscriber struct {
+	mu    sync.Mutex
+	calls []string
+	err   error
+}
+
+func (f *fakeSubscriber) record(call string, tokens []uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tks := append([]uint32(nil), tokens...)
+	sort.Slice(tks, func(i, j int) bool { return tks[i] < tks[j] })
+	f.calls = append(f.calls, fmt.Sprint(call, tks))
+	return f.err
+}
+
+func (f *fakeSubscriber) Subscribe(tokens []uint32) error {
+	return f.record("subscribe", tokens)
+}
+
+func (f *fakeSubscriber) Unsubscribe(tokens []uint32) error {
+	return f.record("unsubscribe", tokens)
+}
+
+func (f *fakeSubscriber) SetMode(mode Mode, tokens []uint32) error {
+	return f.record(string(mode), tokens)
+}
+
+func (f *fakeSubscriber) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.calls
+	f.calls = nil
+	return c
+}
+
+func TestRouterRefCount(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sub = &fakeSubscriber{}
+		r   = NewRouter(sub)
+	)
+
+	a, err := r.Handle([]uint32{1, 2, 2}, ModeLTP, func(models.Tick) {})
+	require.NoError(t, err)
+	require.Equal(t, []string{"subscribe[1 2]", "ltp[1 2]"}, sub.take())
+
+	// Mode is upgraded for the shared instrument only.
+	b, err := r.Handle([]uint32{2, 3}, ModeFull, func(models.Tick) {})
+	require.NoError(t, err)
+	require.Equal(t, []string{"subscribe[3]", "full[2 3]"}, sub.take())
+
+	c, err := r.Handle([]uint32{3}, ModeQuote, func(models.Tick) {})
+	require.NoError(t, err)
+	require.Empty(t, sub.take())
+	require.Equal(t, map[uint32]int{1: 1, 2: 2, 3: 2}, r.Routes())
+
+	// Mode is downgraded to the remaining handlers.
+	require.NoError(t, b.Close())
+	require.Equal(t, []string{"ltp[2]", "quote[3]"}, sub.take())
+
+	require.NoError(t, a.Close())
+	require.Equal(t, []string{"unsubscribe[1 2]"}, sub.take())
+
+	// Closing again does nothing.
+	require.NoError(t, a.Close())
+	require.Empty(t, sub.take())
+
+	require.NoError(t, c.Close())
+	require.Equal(t, []string{"unsubscribe[3]"}, sub.take())
+	require.Empty(t, r.Routes())
+}
+
+func TestRouterDispatch(t *testing.T) {
+	t.Parallel()
+
+	var (
+		r   = NewRouter(&fakeSubscriber{})
+		got = map[string][]uint32{}
+	)
+	handler := func(name string) func(models.Tick) {
+		return func(tick models.Tick) { got[name] = append(got[name], tick.InstrumentToken) }
+	}
+
+	_, err := r.Handle([]uint32{1, 2}, ModeQuote, handler("a"))
+	require.NoError(t, err)
+	b, err := r.Handle([]uint32{2}, ModeQuote, handler("b"))
+	require.NoError(t, err)
+
+	// A handler which closes itself on the first tick.
+	var once *Route
+	once, err = r.Handle([]uint32{2}, ModeQuote, func(tick models.Tick) {
+		got["once"] = append(got["once"], tick.InstrumentToken)
+		require.NoError(t, once.Close())
+	})
+	require.NoError(t, err)
+
+	for _, tk := range []uint32{1, 2, 3, 2} {
+		r.Dispatch(models.Tick{InstrumentToken: tk})
+	}
+	require.NoError(t, b.Close())
+	r.Dispatch(models.Tick{InstrumentToken: 2})
+
+	require.Equal(t, map[string][]uint32{
+		"a":    {1, 2, 2, 2},
+		"b":    {2, 2},
+		"once": {2},
+	}, got)
+}
+
+func TestRouterSymbols(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sub = &fakeSubscriber{}
+		r   = NewRouter(sub)
+	)
+	r.SetInstruments(kiteconnect.Instruments{
+		{InstrumentToken: 408065, Tradingsymbol: "INFY", Exchange: "NSE"},
+		{InstrumentToken: 128053508, Tradingsymbol: "INFY", Exchange: "BSE"},
+	})
+
+	tk, ok := r.Token("BSE:INFY")
+	require.True(t, ok)
+	require.Equal(t, uint32(128053508), tk)
+
+	_, err := r.HandleSymbols([]string{"NSE:INFY"}, ModeFull, func(models.Tick) {})
+	require.NoError(t, err)
+	require.Equal(t, []string{"subscribe[408065]", "full[408065]"}, sub.take())
+
+	_, err = r.HandleSymbols([]string{"NSE:INFY", "NSE:TCS"}, ModeFull, func(models.Tick) {})
+	require.True(t, errors.Is(err, ErrUnknownInstrument))
+	require.Empty(t, sub.take())
+}
+
+func TestRouterErrors(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sub = &fakeSubscriber{err: errors.New("failed")}
+		r   = NewRouter(sub)
+	)
+
+	_, err := r.Handle([]uint32{1}, "depth", func(models.Tick) {})
+	require.Error(t, err)
+	_, err = r.Handle(nil, ModeLTP, func(models.Tick) {})
+	require.Error(t, err)
+
+	// The route is dropped if subscribing fails.
+	_, err = r.Handle([]uint32{1}, ModeLTP, func(models.Tick) {})
+	require.Error(t, err)
+	require.Empty(t, r.Routes())
+}
+
+func TestTickerRouter(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	tk := New("api_key", "access_token")
+	tk.SetRootURL(srv.url())
+
+	var (
+		mu  sync.Mutex
+		got []uint32
+	)
+	rt, err := tk.Router().Handle([]uint32{408065}, ModeLTP, func(tick models.Tick) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tick.InstrumentToken)
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := serve(ctx, tk)
+
+	conn := srv.waitConn(t)
+	require.NoError(t, conn.WriteMessage(BinaryMessage, ltpPacket(100, 408065, 738561)))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, map[uint32]Mode{408065: ModeLTP}, tk.Subscriptions())
+
+	require.NoError(t, rt.Close())
+	require.Empty(t, tk.Subscriptions())
+
+	cancel()
+	waitDone(t, done)
+}
@@ -0,0 +1,210 @@
+package kiteticker
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// QuoteFetcher fetches the quotes of instruments. It's implemented by kiteconnect.Client.
+type QuoteFetcher interface {
+	GetQuote(instruments ...string) (kiteconnect.Quote, error)
+}
+
+// Snapshot is the latest known state of an instrument.
+type Snapshot struct {
+	Tick models.Tick
+	// Updated is the time at which the latest tick or quote was received.
+	Updated time.Time
+}
+
+// Age returns the time since the snapshot was updated.
+func (s Snapshot) Age() time.Duration {
+	return time.Since(s.Updated)
+}
+
+// SnapshotStore keeps the latest state of every instrument received by a
+// ticker. Ticks of a less detailed mode are merged into the earlier state,
+// so an LTP tick updates only the last price of an instrument whose full tick
+// was received before. It's safe for concurrent use.
+type SnapshotStore struct {
+	quotes QuoteFetcher
+
+	mu    sync.RWMutex
+	snaps map[uint32]Snapshot
+}
+
+// NewSnapshotStore creates a snapshot store. Instruments which have never
+// ticked are fetched using quotes, which may be nil to disable fetching.
+func NewSnapshotStore(quotes QuoteFetcher) *SnapshotStore {
+	return &SnapshotStore{
+		quotes: quotes,
+		snaps:  map[uint32]Snapshot{},
+	}
+}
+
+// SetSnapshotStore sets the store which is updated with every tick received.
+// Pass nil to stop updating it.
+func (t *Ticker) SetSnapshotStore(s *SnapshotStore) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshots = s
+}
+
+// SetSnapshotStore sets the store which is updated with every tick received
+// by any connection of the pool. Pass nil to stop updating it.
+func (p *TickerPool) SetSnapshotStore(s *SnapshotStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots = s
+}
+
+// Update merges a tick into the instrument's snapshot.
+func (s *SnapshotStore) Update(tick models.Tick) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.snaps[tick.InstrumentToken]
+	if ok {
+		tick = mergeTick(old.Tick, tick)
+	}
+	s.snaps[tick.InstrumentToken] = Snapshot{Tick: tick, Updated: now}
+}
+
+// Get returns the snapshot of an instrument if it has ticked or been fetched.
+func (s *SnapshotStore) Get(token uint32) (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap, ok := s.snaps[token]
+	return snap, ok
+}
+
+// LastPrice returns the last known price of an instrument.
+func (s *SnapshotStore) LastPrice(token uint32) (float64, bool) {
+	snap, ok := s.Get(token)
+	return snap.Tick.LastPrice, ok
+}
+
+// All returns the snapshots of all the instruments.
+func (s *SnapshotStore) All() map[uint32]Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[uint32]Snapshot, len(s.snaps))
+	for tk, snap := range s.snaps {
+		out[tk] = snap
+	}
+	return out
+}
+
+// Delete removes the snapshots of instruments, eg: after unsubscribing them.
+func (s *SnapshotStore) Delete(tokens ...uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tk := range tokens {
+		delete(s.snaps, tk)
+	}
+}
+
+// Fetch returns the snapshots of instruments, fetching the quotes of the ones
+// which have never ticked in a single request. Fetched quotes are stored as
+// full mode ticks. Instruments without a quote are omitted.
+func (s *SnapshotStore) Fetch(tokens ...uint32) (map[uint32]Snapshot, error) {
+	var (
+		out     = make(map[uint32]Snapshot, len(tokens))
+		missing []string
+	)
+
+	s.mu.RLock()
+	for _, tk := range tokens {
+		if snap, ok := s.snaps[tk]; ok {
+			out[tk] = snap
+		} else {
+			missing = append(missing, strconv.FormatUint(uint64(tk), 10))
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(missing) == 0 || s.quotes == nil {
+		return out, nil
+	}
+
+	quotes, err := s.quotes.GetQuote(missing...)
+	if err != nil {
+		return out, err
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range quotes {
+		tick := models.Tick{
+			Mode:               string(ModeFull),
+			InstrumentToken:    uint32(q.InstrumentToken),
+			Timestamp:          q.Timestamp,
+			LastTradeTime:      q.LastTradeTime,
+			LastPrice:          q.LastPrice,
+			LastTradedQuantity: uint32(q.LastQuantity),
+			TotalBuyQuantity:   uint32(q.BuyQuantity),
+			TotalSellQuantity:  uint32(q.SellQuantity),
+			VolumeTraded:       uint32(q.Volume),
+			AverageTradePrice:  q.AveragePrice,
+			OI:                 uint32(q.OI),
+			OIDayHigh:          uint32(q.OIDayHigh),
+			OIDayLow:           uint32(q.OIDayLow),
+			NetChange:          q.NetChange,
+			OHLC:               q.OHLC,
+			Depth:              q.Depth,
+		}
+		seg := tick.InstrumentToken & 0xFF
+		tick.IsIndex, tick.IsTradable = seg == Indices, seg != Indices
+
+		// A tick received while fetching is more recent.
+		snap, ok := s.snaps[tick.InstrumentToken]
+		if !ok {
+			snap = Snapshot{Tick: tick, Updated: now}
+			s.snaps[tick.InstrumentToken] = snap
+		}
+		out[tick.InstrumentToken] = snap
+	}
+
+	return out, nil
+}
+
+// mergeTick merges a tick into the earlier tick of the instrument. Ticks of
+// the same or a more detailed mode replace the earlier tick while the others
+// update only the fields their packets carry, retaining the earlier mode.
+func mergeTick(old, tick models.Tick) models.Tick {
+	if modeRank(Mode(tick.Mode)) >= modeRank(Mode(old.Mode)) {
+		return tick
+	}
+
+	merged := old
+	merged.LastPrice = tick.LastPrice
+
+	if Mode(tick.Mode) == ModeQuote {
+		merged.OHLC = tick.OHLC
+		if !tick.IsIndex {
+			merged.LastTradedQuantity = tick.LastTradedQuantity
+			merged.AverageTradePrice = tick.AverageTradePrice
+			merged.VolumeTraded = tick.VolumeTraded
+			merged.TotalBuyQuantity = tick.TotalBuyQuantity
+			merged.TotalSellQuantity = tick.TotalSellQuantity
+		}
+	}
+
+	if merged.OHLC.Close != 0 {
+		merged.NetChange = merged.LastPrice - merged.OHLC.Close
+	}
+
+	return merged
+}
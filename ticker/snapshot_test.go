@@ -0,0 +1,179 @@
+package kiteticker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// fakeQuotes returns quotes at a fixed price and records the instruments requested.
+type fakeQuotes struct {
+	mu       sync.Mutex
+	requests [][]string
+	err      error
+}
+
+func (f *fakeQuotes) GetQuote(instruments ...string) (kiteconnect.Quote, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, instruments)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	q := kiteconnect.Quote{}
+	for _, i := range instruments {
+		if i == "738561" {
+			e := q[i]
+			e.InstrumentToken = 738561
+			e.LastPrice = 2400
+			e.Volume = 10
+			e.OHLC.Close = 2350
+			q[i] = e
+		}
+	}
+	return q, nil
+}
+
+func TestSnapshotMerge(t *testing.T) {
+	t.Parallel()
+
+	full := models.Tick{
+		Mode:              "full",
+		InstrumentToken:   408065,
+		IsTradable:        true,
+		LastPrice:         1500,
+		VolumeTraded:      100,
+		OI:                50,
+		AverageTradePrice: 1490,
+		OHLC:              models.OHLC{Open: 1480, High: 1510, Low: 1470, Close: 1450},
+		Depth:             models.Depth{Buy: [5]models.DepthItem{{Price: 1499.95, Quantity: 10}}},
+	}
+
+	tt := []struct {
+		name     string
+		old, new models.Tick
+		expected func(t models.Tick) models.Tick
+	}{
+		{
+			name: "ltp over full",
+			old:  full,
+			new:  models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1520},
+			expected: func(t models.Tick) models.Tick {
+				t.LastPrice, t.NetChange = 1520, 70
+				return t
+			},
+		},
+		{
+			name: "quote over full",
+			old:  full,
+			new:  models.Tick{Mode: "quote", InstrumentToken: 408065, LastPrice: 1520, VolumeTraded: 120, AverageTradePrice: 1495, OHLC: models.OHLC{High: 1520, Close: 1450}},
+			expected: func(t models.Tick) models.Tick {
+				t.LastPrice, t.NetChange, t.VolumeTraded, t.AverageTradePrice = 1520, 70, 120, 1495
+				t.OHLC = models.OHLC{High: 1520, Close: 1450}
+				return t
+			},
+		},
+		{
+			name: "full over ltp",
+			old:  models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1520},
+			new:  full,
+			expected: func(models.Tick) models.Tick {
+				return full
+			},
+		},
+		{
+			name: "ltp over ltp",
+			old:  models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1520},
+			new:  models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1530},
+			expected: func(models.Tick) models.Tick {
+				return models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1530}
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewSnapshotStore(nil)
+			s.Update(tc.old)
+			s.Update(tc.new)
+
+			snap, ok := s.Get(408065)
+			require.True(t, ok)
+			require.Equal(t, tc.expected(tc.old), snap.Tick)
+			require.True(t, snap.Age() >= 0 && snap.Age() < time.Second)
+		})
+	}
+}
+
+func TestSnapshotFetch(t *testing.T) {
+	t.Parallel()
+
+	var (
+		quotes = &fakeQuotes{}
+		s      = NewSnapshotStore(quotes)
+	)
+	s.Update(models.Tick{Mode: "ltp", InstrumentToken: 408065, LastPrice: 1500})
+
+	snaps, err := s.Fetch(408065, 738561, 1)
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Equal(t, 1500.0, snaps[408065].Tick.LastPrice)
+	require.Equal(t, models.Tick{
+		Mode:            "full",
+		InstrumentToken: 738561,
+		IsTradable:      true,
+		LastPrice:       2400,
+		VolumeTraded:    10,
+		OHLC:            models.OHLC{Close: 2350},
+	}, snaps[738561].Tick)
+
+	// Only instruments which have never ticked are requested.
+	sort.Strings(quotes.requests[0])
+	require.Equal(t, [][]string{{"1", "738561"}}, quotes.requests)
+
+	// Fetched quotes are stored.
+	price, ok := s.LastPrice(738561)
+	require.True(t, ok)
+	require.Equal(t, 2400.0, price)
+
+	_, err = s.Fetch(408065, 738561)
+	require.NoError(t, err)
+	require.Len(t, quotes.requests, 1)
+
+	s.Delete(738561)
+	require.Len(t, s.All(), 1)
+
+	quotes.err = errors.New("failed")
+	_, err = s.Fetch(738561)
+	require.Error(t, err)
+}
+
+func TestTickerSnapshotStore(t *testing.T) {
+	t.Parallel()
+
+	var (
+		tk = New("", "")
+		s  = NewSnapshotStore(nil)
+	)
+	tk.SetSnapshotStore(s)
+
+	// Callbacks see the tick in the store.
+	var price float64
+	tk.OnTick(func(tick models.Tick) {
+		price, _ = s.LastPrice(tick.InstrumentToken)
+	})
+	tk.handleMessage(BinaryMessage, ltpPacket(100, 408065), nil)
+
+	require.Equal(t, 100.0, price)
+}
@@ -0,0 +1,352 @@
+package kiteticker
+
+import (
+	"sync"
+	"sync/atomic"
+
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// OverflowPolicy represents what happens to a tick when a stream's buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest buffered tick to make room for the new one.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest discards the new tick.
+	OverflowDropNewest
+	// OverflowBlock waits till there's room in the buffer. This stalls the
+	// websocket read loop just like a slow OnTick callback.
+	OverflowBlock
+	// OverflowCoalesce keeps only the latest undelivered tick of every
+	// instrument, replacing older ones, so that a slow consumer always gets
+	// the latest state of every instrument.
+	OverflowCoalesce
+)
+
+// Default buffer size of stream channels.
+const defaultStreamBuffer = 1024
+
+// StreamOptions represents the options for a tick stream.
+type StreamOptions struct {
+	// Buffer is the capacity of the channels. Defaults to 1024.
+	Buffer   int
+	Overflow OverflowPolicy
+}
+
+// Stream delivers ticks, order updates and errors of a ticker over channels.
+// Unlike callbacks, which are invoked synchronously in the websocket read loop,
+// a slow consumer of a stream doesn't stall the ticker unless OverflowBlock is used.
+//
+// Order updates are never dropped and are queued till they are consumed.
+// Errors are dropped if the errors channel is full.
+type Stream struct {
+	opt StreamOptions
+	set *streamSet
+
+	ticks  chan models.Tick
+	orders chan kiteconnect.Order
+	errs   chan error
+
+	// Guards the queues below.
+	mu      sync.Mutex
+	latest  map[uint32]models.Tick
+	tokens  []uint32
+	orderQ  []kiteconnect.Order
+	tickSig chan struct{}
+	ordSig  chan struct{}
+
+	// Senders hold a read lock so that channels aren't closed while sending.
+	sendMu sync.RWMutex
+	closed bool
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+
+	dropped uint64
+}
+
+// NewStream creates a new stream which receives all the ticks, order updates and
+// errors of the ticker from now on. Multiple streams can be created and each
+// receives a copy. Streams are closed when the ticker stops serving.
+func (t *Ticker) NewStream(opt StreamOptions) *Stream {
+	return t.streams.add(opt)
+}
+
+// streamSet is a set of streams which receive the same data.
+type streamSet struct {
+	mu      sync.RWMutex
+	streams []*Stream
+}
+
+// add creates a new stream in the set.
+func (ss *streamSet) add(opt StreamOptions) *Stream {
+	if opt.Buffer <= 0 {
+		opt.Buffer = defaultStreamBuffer
+	}
+
+	s := &Stream{
+		opt:     opt,
+		set:     ss,
+		ticks:   make(chan models.Tick, opt.Buffer),
+		orders:  make(chan kiteconnect.Order, opt.Buffer),
+		errs:    make(chan error, opt.Buffer),
+		latest:  map[uint32]models.Tick{},
+		tickSig: make(chan struct{}, 1),
+		ordSig:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.pumpOrders()
+
+	if opt.Overflow == OverflowCoalesce {
+		s.wg.Add(1)
+		go s.pumpTicks()
+	}
+
+	ss.mu.Lock()
+	ss.streams = append(ss.streams, s)
+	ss.mu.Unlock()
+
+	return s
+}
+
+// Ticks returns the channel of ticks.
+func (s *Stream) Ticks() <-chan models.Tick {
+	return s.ticks
+}
+
+// OrderUpdates returns the channel of order updates.
+func (s *Stream) OrderUpdates() <-chan kiteconnect.Order {
+	return s.orders
+}
+
+// Errors returns the channel of errors.
+func (s *Stream) Errors() <-chan error {
+	return s.errs
+}
+
+// Dropped returns the number of ticks dropped or replaced by a newer tick
+// of the same instrument due to the buffer being full.
+func (s *Stream) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close detaches the stream from the ticker and closes its channels.
+func (s *Stream) Close() {
+	s.once.Do(func() {
+		s.set.remove(s)
+
+		close(s.done)
+
+		// Wait for in-flight sends to return.
+		s.sendMu.Lock()
+		s.closed = true
+		s.sendMu.Unlock()
+
+		s.wg.Wait()
+		close(s.ticks)
+		close(s.orders)
+		close(s.errs)
+	})
+}
+
+// pushTick delivers a tick as per the overflow policy.
+func (s *Stream) pushTick(tick models.Tick) {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	switch s.opt.Overflow {
+	case OverflowBlock:
+		select {
+		case s.ticks <- tick:
+		case <-s.done:
+		}
+
+	case OverflowDropNewest:
+		select {
+		case s.ticks <- tick:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+
+	case OverflowCoalesce:
+		s.mu.Lock()
+		if _, ok := s.latest[tick.InstrumentToken]; ok {
+			atomic.AddUint64(&s.dropped, 1)
+		} else {
+			s.tokens = append(s.tokens, tick.InstrumentToken)
+		}
+		s.latest[tick.InstrumentToken] = tick
+		s.mu.Unlock()
+		signal(s.tickSig)
+
+	default:
+		for {
+			select {
+			case s.ticks <- tick:
+				return
+			default:
+			}
+
+			// Make room by discarding the oldest tick. The consumer may
+			// have read it in the meantime in which case nothing is dropped.
+			select {
+			case <-s.ticks:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	}
+}
+
+// pushOrder queues an order update.
+func (s *Stream) pushOrder(order kiteconnect.Order) {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	s.mu.Lock()
+	s.orderQ = append(s.orderQ, order)
+	s.mu.Unlock()
+	signal(s.ordSig)
+}
+
+// pushError delivers an error if there's room in the buffer.
+func (s *Stream) pushError(err error) {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+// pumpTicks delivers coalesced ticks in the order the instruments were queued.
+func (s *Stream) pumpTicks() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.tickSig:
+		case <-s.done:
+			return
+		}
+
+		for {
+			s.mu.Lock()
+			if len(s.tokens) == 0 {
+				s.mu.Unlock()
+				break
+			}
+			token := s.tokens[0]
+			s.tokens = s.tokens[1:]
+			tick := s.latest[token]
+			delete(s.latest, token)
+			s.mu.Unlock()
+
+			select {
+			case s.ticks <- tick:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// pumpOrders delivers queued order updates.
+func (s *Stream) pumpOrders() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.ordSig:
+		case <-s.done:
+			return
+		}
+
+		for {
+			s.mu.Lock()
+			if len(s.orderQ) == 0 {
+				s.mu.Unlock()
+				break
+			}
+			order := s.orderQ[0]
+			s.orderQ = s.orderQ[1:]
+			s.mu.Unlock()
+
+			select {
+			case s.orders <- order:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// signal notifies a waiting pump without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// remove detaches a stream from the set.
+func (ss *streamSet) remove(s *Stream) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Copy instead of removing in place as the old slice may be in use by triggers.
+	streams := make([]*Stream, 0, len(ss.streams))
+	for _, st := range ss.streams {
+		if st != s {
+			streams = append(streams, st)
+		}
+	}
+	ss.streams = streams
+}
+
+// closeAll closes all the streams in the set.
+func (ss *streamSet) closeAll() {
+	for _, s := range ss.get() {
+		s.Close()
+	}
+}
+
+// get returns the current streams.
+func (ss *streamSet) get() []*Stream {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.streams
+}
+
+// pushTick delivers a tick to all the streams.
+func (ss *streamSet) pushTick(tick models.Tick) {
+	for _, s := range ss.get() {
+		s.pushTick(tick)
+	}
+}
+
+// pushOrder delivers an order update to all the streams.
+func (ss *streamSet) pushOrder(order kiteconnect.Order) {
+	for _, s := range ss.get() {
+		s.pushOrder(order)
+	}
+}
+
+// pushError delivers an error to all the streams.
+func (ss *streamSet) pushError(err error) {
+	for _, s := range ss.get() {
+		s.pushError(err)
+	}
+}
@@ -0,0 +1,174 @@
+package kiteticker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func drainTicks(s *Stream) []models.Tick {
+	var out []models.Tick
+	for {
+		select {
+		case tick := <-s.Ticks():
+			out = append(out, tick)
+		default:
+			return out
+		}
+	}
+}
+
+func TestStreamOverflow(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name    string
+		policy  OverflowPolicy
+		prices  []float64
+		dropped uint64
+	}{
+		{name: "drop oldest", policy: OverflowDropOldest, prices: []float64{3, 4}, dropped: 2},
+		{name: "drop newest", policy: OverflowDropNewest, prices: []float64{1, 2}, dropped: 2},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tk := New("", "")
+			s := tk.NewStream(StreamOptions{Buffer: 2, Overflow: tc.policy})
+			defer s.Close()
+
+			for i := 1; i <= 4; i++ {
+				tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: float64(i)})
+			}
+
+			var prices []float64
+			for _, tick := range drainTicks(s) {
+				prices = append(prices, tick.LastPrice)
+			}
+			require.Equal(t, tc.prices, prices)
+			require.Equal(t, tc.dropped, s.Dropped())
+		})
+	}
+}
+
+func TestStreamCoalesce(t *testing.T) {
+	t.Parallel()
+	tk := New("", "")
+	s := tk.NewStream(StreamOptions{Buffer: 1, Overflow: OverflowCoalesce})
+	defer s.Close()
+
+	tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: 1})
+	// Wait for the first tick to fill the buffer so that the rest are coalesced.
+	require.Eventually(t, func() bool { return len(s.ticks) == 1 }, time.Second, time.Millisecond)
+
+	for i := 2; i <= 5; i++ {
+		tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: float64(i)})
+		tk.triggerTick(models.Tick{InstrumentToken: 2, LastPrice: float64(i * 10)})
+	}
+
+	// Read till the latest tick of both the instruments is received.
+	var (
+		got    []models.Tick
+		latest = map[uint32]float64{}
+	)
+	for latest[1] != 5 || latest[2] != 50 {
+		select {
+		case tick := <-s.Ticks():
+			got = append(got, tick)
+			latest[tick.InstrumentToken] = tick.LastPrice
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for ticks")
+		}
+	}
+
+	require.Equal(t, 1.0, got[0].LastPrice)
+	require.Empty(t, drainTicks(s))
+	require.Equal(t, uint64(9), uint64(len(got))+s.Dropped())
+	require.True(t, s.Dropped() > 0)
+}
+
+func TestStreamBlock(t *testing.T) {
+	t.Parallel()
+	tk := New("", "")
+	s := tk.NewStream(StreamOptions{Buffer: 1, Overflow: OverflowBlock})
+
+	tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: 1})
+
+	done := make(chan struct{})
+	go func() {
+		tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: 2})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("tick wasn't blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.Equal(t, 1.0, (<-s.Ticks()).LastPrice)
+	<-done
+	require.Equal(t, 2.0, (<-s.Ticks()).LastPrice)
+
+	// Close unblocks pending sends.
+	tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: 3})
+	go tk.triggerTick(models.Tick{InstrumentToken: 1, LastPrice: 4})
+	s.Close()
+	require.Zero(t, s.Dropped())
+}
+
+func TestStreamOrdersAndErrors(t *testing.T) {
+	t.Parallel()
+	tk := New("", "")
+	s := tk.NewStream(StreamOptions{Buffer: 1})
+
+	// Order updates aren't dropped even if the buffer is full.
+	for _, id := range []string{"1", "2", "3"} {
+		tk.triggerOrderUpdate(kiteconnect.Order{OrderID: id})
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		select {
+		case o := <-s.OrderUpdates():
+			require.Equal(t, id, o.OrderID)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for order update")
+		}
+	}
+
+	tk.triggerError(errors.New("first"))
+	tk.triggerError(errors.New("second"))
+	require.EqualError(t, <-s.Errors(), "first")
+
+	s.Close()
+	_, ok := <-s.Ticks()
+	require.False(t, ok)
+	_, ok = <-s.OrderUpdates()
+	require.False(t, ok)
+
+	// Closed streams are detached.
+	require.Empty(t, tk.streams.get())
+	tk.triggerTick(models.Tick{})
+}
+
+func TestStreamFanOut(t *testing.T) {
+	t.Parallel()
+	tk := New("", "")
+	a := tk.NewStream(StreamOptions{})
+	b := tk.NewStream(StreamOptions{})
+
+	tk.triggerTick(models.Tick{InstrumentToken: 1})
+	require.Len(t, drainTicks(a), 1)
+	require.Len(t, drainTicks(b), 1)
+
+	tk.streams.closeAll()
+	_, ok := <-a.Ticks()
+	require.False(t, ok)
+	_, ok = <-b.Ticks()
+	require.False(t, ok)
+}
@@ -1,12 +1,12 @@
-// Package kiteticker provides kite ticker access using callbacks.
+// Package kiteticker provides kite ticker access using callbacks or channels.
 package kiteticker
 
 import (
 	"context"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"net/http"
 	"net/url"
 	"sync"
 	"sync/atomic"
@@ -22,8 +22,19 @@ type Mode string
 
 // Ticker is a Kite connect ticker instance.
 type Ticker struct {
+	// Conn is the current connection which is replaced on every reconnect.
+	// Use the methods of Ticker instead of writing to it directly.
 	Conn *websocket.Conn
 
+	// mu guards the connection, settings, callbacks and subscriptions which
+	// are accessed by both the serve loop and the user's goroutines.
+	mu sync.RWMutex
+	// writeMu serialises writes to the connection.
+	writeMu sync.Mutex
+	// subMu is held across a change to the subscriptions and the message
+	// which sends it, so that the server sees the changes in the same order.
+	subMu sync.Mutex
+
 	apiKey      string
 	accessToken string
 
@@ -34,11 +45,24 @@ type Ticker struct {
 	reconnectMaxRetries int
 	reconnectMaxDelay   time.Duration
 	connectTimeout      time.Duration
+	backoff             Backoff
+	dialer              *websocket.Dialer
+	header              http.Header
+	checkInterval       time.Duration
+	dataTimeout         time.Duration
+
+	state ConnectionState
 
 	reconnectAttempt int
 
 	subscribedTokens map[uint32]Mode
 
+	streams   streamSet
+	recorder  *Recorder
+	router    *Router
+	snapshots *SnapshotStore
+	metrics   Metrics
+
 	cancel context.CancelFunc
 }
 
@@ -47,12 +71,12 @@ type Ticker struct {
 type atomicTime struct {
 	v atomic.Value
 }
-	
+
 // Get returns the current timestamp.
 func (b *atomicTime) Get() time.Time {
 	return b.v.Load().(time.Time)
 }
-	 
+
 // Set sets the current timestamp.
 func (b *atomicTime) Set(value time.Time) {
 	b.v.Store(value)
@@ -64,6 +88,7 @@ type callbacks struct {
 	onMessage     func(int, []byte)
 	onNoReconnect func(int)
 	onReconnect   func(int, time.Duration)
+	onStateChange func(ConnectionState, ConnectionState)
 	onConnect     func()
 	onClose       func(int, string)
 	onError       func(error)
@@ -142,16 +167,19 @@ const (
 	defaultReconnectMaxDelay time.Duration = 60000 * time.Millisecond
 	// Connect timeout for initial server handshake.
 	defaultConnectTimeout time.Duration = 7000 * time.Millisecond
-	// Interval in which the connection check is performed periodically.
-	connectionCheckInterval time.Duration = 2000 * time.Millisecond
-	// Interval which is used to determine if the connection is still active. If last ping time exceeds this then
+	// Default interval in which the connection check is performed periodically.
+	defaultConnectionCheckInterval time.Duration = 2000 * time.Millisecond
+	// Default interval which is used to determine if the connection is still active. If last ping time exceeds this then
 	// connection is considered as dead and reconnection is initiated.
-	dataTimeoutInterval time.Duration = 5000 * time.Millisecond
+	defaultDataTimeout time.Duration = 5000 * time.Millisecond
 )
 
 var (
 	// Default ticker url.
 	tickerURL = url.URL{Scheme: "wss", Host: "ws.kite.trade"}
+
+	// ErrNotConnected is returned when a message can't be sent as the ticker isn't connected.
+	ErrNotConnected = errors.New("ticker isn't connected")
 )
 
 // New creates a new ticker instance.
@@ -164,6 +192,9 @@ func New(apiKey string, accessToken string) *Ticker {
 		reconnectMaxDelay:   defaultReconnectMaxDelay,
 		reconnectMaxRetries: defaultReconnectMaxAttempts,
 		connectTimeout:      defaultConnectTimeout,
+		backoff:             defaultBackoff,
+		checkInterval:       defaultConnectionCheckInterval,
+		dataTimeout:         defaultDataTimeout,
 		subscribedTokens:    map[uint32]Mode{},
 	}
 
@@ -172,76 +203,156 @@ func New(apiKey string, accessToken string) *Ticker {
 
 // SetRootURL sets ticker root url.
 func (t *Ticker) SetRootURL(u url.URL) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.url = u
 }
 
 // SetAccessToken set access token.
 func (t *Ticker) SetAccessToken(aToken string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.accessToken = aToken
 }
 
 // SetConnectTimeout sets default timeout for initial connect handshake
 func (t *Ticker) SetConnectTimeout(val time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.connectTimeout = val
 }
 
 // SetAutoReconnect enable/disable auto reconnect.
 func (t *Ticker) SetAutoReconnect(val bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.autoReconnect = val
 }
 
 // SetReconnectMaxDelay sets maximum auto reconnect delay.
 func (t *Ticker) SetReconnectMaxDelay(val time.Duration) error {
-	if val > reconnectMinDelay {
-		return fmt.Errorf("ReconnectMaxDelay can't be less than %fms", reconnectMinDelay.Seconds()*1000)
+	if val < reconnectMinDelay {
+		return fmt.Errorf("ReconnectMaxDelay can't be less than %v", reconnectMinDelay)
 	}
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.reconnectMaxDelay = val
 	return nil
 }
 
 // SetReconnectMaxRetries sets maximum reconnect attempts.
 func (t *Ticker) SetReconnectMaxRetries(val int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.reconnectMaxRetries = val
 }
 
+// SetBackoff sets the policy for the delay before reconnect attempts.
+// Delays are capped at the reconnect max delay. Defaults to 2^attempt seconds.
+func (t *Ticker) SetBackoff(b Backoff) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if b == nil {
+		b = defaultBackoff
+	}
+	t.backoff = b
+}
+
+// SetDataTimeout sets the duration without any message, including heartbeats,
+// after which the connection is considered dead and a reconnect is initiated.
+func (t *Ticker) SetDataTimeout(val time.Duration) error {
+	if val <= 0 {
+		return errors.New("DataTimeout must be positive")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dataTimeout = val
+	return nil
+}
+
+// SetConnectionCheckInterval sets the interval in which the data timeout is checked.
+func (t *Ticker) SetConnectionCheckInterval(val time.Duration) error {
+	if val <= 0 {
+		return errors.New("ConnectionCheckInterval must be positive")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.checkInterval = val
+	return nil
+}
+
+// State returns the state of the connection.
+func (t *Ticker) State() ConnectionState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state
+}
+
 // OnConnect callback.
 func (t *Ticker) OnConnect(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onConnect = f
 }
 
 // OnError callback.
 func (t *Ticker) OnError(f func(err error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onError = f
 }
 
 // OnClose callback.
 func (t *Ticker) OnClose(f func(code int, reason string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onClose = f
 }
 
 // OnMessage callback.
 func (t *Ticker) OnMessage(f func(messageType int, message []byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onMessage = f
 }
 
 // OnReconnect callback.
 func (t *Ticker) OnReconnect(f func(attempt int, delay time.Duration)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onReconnect = f
 }
 
 // OnNoReconnect callback.
 func (t *Ticker) OnNoReconnect(f func(attempt int)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onNoReconnect = f
 }
 
+// OnStateChange callback which is called with the previous and the new state
+// whenever the state of the connection changes.
+func (t *Ticker) OnStateChange(f func(prev ConnectionState, state ConnectionState)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.callbacks.onStateChange = f
+}
+
 // OnTick callback.
 func (t *Ticker) OnTick(f func(tick models.Tick)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onTick = f
 }
 
 // OnOrderUpdate callback.
 func (t *Ticker) OnOrderUpdate(f func(order kiteconnect.Order)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.callbacks.onOrderUpdate = f
 }
 
@@ -256,98 +367,161 @@ func (t *Ticker) Serve() {
 // routine.
 func (t *Ticker) ServeWithContext(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t.mu.Lock()
 	t.cancel = cancel
+	t.mu.Unlock()
+
+	// Close the streams once the ticker stops serving.
+	defer t.streams.closeAll()
+	defer t.setState(StateStopped)
+
+	// Close the connection when its done.
+	defer t.setConn(nil)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			// If reconnect attempt exceeds max then close the loop
-			if t.reconnectAttempt > t.reconnectMaxRetries {
-				t.triggerNoReconnect(t.reconnectAttempt)
+		}
+
+		t.mu.RLock()
+		var (
+			attempt       = t.reconnectAttempt
+			maxRetries    = t.reconnectMaxRetries
+			maxDelay      = t.reconnectMaxDelay
+			backoff       = t.backoff
+			autoReconnect = t.autoReconnect
+			u             = t.url
+			apiKey        = t.apiKey
+			accessToken   = t.accessToken
+		)
+		t.mu.RUnlock()
+
+		// If reconnect attempt exceeds max then close the loop
+		if attempt > maxRetries {
+			t.triggerNoReconnect(attempt)
+			return
+		}
+
+		// If its a reconnect then wait as per the backoff policy.
+		if attempt > 0 {
+			nextDelay := backoff.Delay(attempt)
+			if nextDelay > maxDelay || nextDelay < 0 {
+				nextDelay = maxDelay
+			}
+
+			t.setState(StateReconnecting)
+			t.triggerReconnect(attempt, nextDelay)
+			if m := t.getMetrics(); m != nil {
+				m.Reconnecting(attempt)
+			}
+
+			// Stop returns without waiting for the delay.
+			if !sleep(ctx, nextDelay) {
 				return
 			}
 
-			// If its a reconnect then wait exponentially based on reconnect attempt
-			if t.reconnectAttempt > 0 {
-				nextDelay := time.Duration(math.Pow(2, float64(t.reconnectAttempt))) * time.Second
-				if nextDelay > t.reconnectMaxDelay || nextDelay <= 0 {
-					nextDelay = t.reconnectMaxDelay
-				}
+			// Close the previous connection if exists
+			t.setConn(nil)
+		}
 
-				t.triggerReconnect(t.reconnectAttempt, nextDelay)
+		t.setState(StateConnecting)
 
-				time.Sleep(nextDelay)
+		// Prepare ticker URL with required params.
+		q := u.Query()
+		q.Set("api_key", apiKey)
+		q.Set("access_token", accessToken)
+		u.RawQuery = q.Encode()
 
-				// Close the previous connection if exists
-				if t.Conn != nil {
-					t.Conn.Close()
-				}
+		conn, err := t.dial(ctx, u.String())
+		if err != nil {
+			// Dialing is aborted when stopped.
+			if ctx.Err() != nil {
+				return
 			}
 
-			// Prepare ticker URL with required params.
-			q := t.url.Query()
-			q.Set("api_key", t.apiKey)
-			q.Set("access_token", t.accessToken)
-			t.url.RawQuery = q.Encode()
-
-			// create a dialer
-			d := websocket.DefaultDialer
-			d.HandshakeTimeout = t.connectTimeout
-			conn, _, err := d.Dial(t.url.String(), nil)
-			if err != nil {
-				t.triggerError(err)
+			t.triggerError(err)
+			t.setState(StateDisconnected)
 
-				// If auto reconnect is enabled then try reconneting else return error
-				if t.autoReconnect {
-					t.reconnectAttempt++
-					continue
-				}
+			// If auto reconnect is enabled then try reconneting else return error
+			if autoReconnect {
+				t.incrReconnectAttempt()
+				continue
 			}
+			return
+		}
 
-			// Close the connection when its done.
-			defer func() {
-				if t.Conn != nil {
-					t.Conn.Close()
-				}
-			}()
+		// Set current time as last ping time
+		t.lastPingTime.Set(time.Now())
 
-			// Assign the current connection to the instance.
-			t.Conn = conn
+		// Set on close handler
+		conn.SetCloseHandler(t.handleClose)
 
-			// Trigger connect callback.
-			t.triggerConnect()
+		// Assign the current connection to the instance.
+		t.setConn(conn)
+
+		// Subscriptions made before connecting or stored from the previous
+		// connection are sent once connected.
+		t.mu.RLock()
+		pending := len(t.subscribedTokens) > 0
+		t.mu.RUnlock()
+
+		t.setState(StateConnected)
+		if m := t.getMetrics(); m != nil {
+			m.Connected()
+		}
 
-			// Resubscribe to stored tokens
-			if t.reconnectAttempt > 0 {
-				t.Resubscribe()
+		// Trigger connect callback.
+		t.triggerConnect()
+
+		// Resubscribe to stored tokens
+		if pending {
+			if err := t.Resubscribe(); err != nil {
+				t.triggerError(err)
 			}
+		}
 
-			// Reset auto reconnect vars
-			t.reconnectAttempt = 0
+		// Reset auto reconnect vars
+		t.mu.Lock()
+		t.reconnectAttempt = 0
+		t.mu.Unlock()
 
-			// Set current time as last ping time
-			t.lastPingTime.Set(time.Now())
+		// connCtx is cancelled when the connection has to be closed.
+		connCtx, connCancel := context.WithCancel(ctx)
 
-			// Set on close handler
-			t.Conn.SetCloseHandler(t.handleClose)
+		var wg sync.WaitGroup
 
-			var wg sync.WaitGroup
+		// Receive ticker data in a go routine.
+		wg.Add(1)
+		go t.readMessage(connCtx, connCancel, conn, &wg)
 
-			// Receive ticker data in a go routine.
+		// Run watcher to check last ping time and reconnect if required
+		if autoReconnect {
 			wg.Add(1)
-			go t.readMessage(ctx, &wg)
+			go t.checkConnection(connCtx, connCancel, &wg)
+		}
 
-			// Run watcher to check last ping time and reconnect if required
-			if t.autoReconnect {
-				wg.Add(1)
-				go t.checkConnection(ctx, &wg)
-			}
+		// Close the connection to unblock the reader once cancelled.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-connCtx.Done()
+			conn.Close()
+		}()
+
+		// Wait for go routines to finish before doing next reconnect
+		wg.Wait()
+		t.setState(StateDisconnected)
 
-			// Wait for go routines to finish before doing next reconnect
-			wg.Wait()
+		if ctx.Err() != nil || !autoReconnect {
+			return
 		}
+
+		// Increase reconnect attempt for next reconnection
+		t.incrReconnectAttempt()
 	}
 }
 
@@ -356,79 +530,166 @@ func (t *Ticker) handleClose(code int, reason string) error {
 	return nil
 }
 
+// getConn returns the current connection.
+func (t *Ticker) getConn() *websocket.Conn {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.Conn
+}
+
+// setConn swaps the current connection, closing the previous one.
+func (t *Ticker) setConn(conn *websocket.Conn) {
+	t.mu.Lock()
+	prev := t.Conn
+	t.Conn = conn
+	t.mu.Unlock()
+
+	if prev != nil && prev != conn {
+		prev.Close()
+	}
+}
+
+// setState sets the state of the connection, triggering the callback if it changed.
+func (t *Ticker) setState(state ConnectionState) {
+	t.mu.Lock()
+	prev := t.state
+	t.state = state
+	f := t.callbacks.onStateChange
+	t.mu.Unlock()
+
+	if f != nil && prev != state {
+		f(prev, state)
+	}
+}
+
+// incrReconnectAttempt increases the reconnect attempt.
+func (t *Ticker) incrReconnectAttempt() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reconnectAttempt++
+}
 
 // Trigger callback methods
 func (t *Ticker) triggerError(err error) {
-	if t.callbacks.onError != nil {
-		t.callbacks.onError(err)
+	t.mu.RLock()
+	f := t.callbacks.onError
+	t.mu.RUnlock()
+
+	if f != nil {
+		f(err)
 	}
+
+	t.streams.pushError(err)
 }
 
 func (t *Ticker) triggerClose(code int, reason string) {
-	if t.callbacks.onClose != nil {
-		t.callbacks.onClose(code, reason)
+	t.mu.RLock()
+	f := t.callbacks.onClose
+	t.mu.RUnlock()
+
+	if f != nil {
+		f(code, reason)
 	}
 }
 
 func (t *Ticker) triggerConnect() {
-	if t.callbacks.onConnect != nil {
-		t.callbacks.onConnect()
+	t.mu.RLock()
+	f := t.callbacks.onConnect
+	t.mu.RUnlock()
+
+	if f != nil {
+		f()
 	}
 }
 
 func (t *Ticker) triggerReconnect(attempt int, delay time.Duration) {
-	if t.callbacks.onReconnect != nil {
-		t.callbacks.onReconnect(attempt, delay)
+	t.mu.RLock()
+	f := t.callbacks.onReconnect
+	t.mu.RUnlock()
+
+	if f != nil {
+		f(attempt, delay)
 	}
 }
 
 func (t *Ticker) triggerNoReconnect(attempt int) {
-	if t.callbacks.onNoReconnect != nil {
-		t.callbacks.onNoReconnect(attempt)
+	t.mu.RLock()
+	f := t.callbacks.onNoReconnect
+	t.mu.RUnlock()
+
+	if f != nil {
+		f(attempt)
 	}
 }
 
-
 func (t *Ticker) triggerMessage(messageType int, message []byte) {
-	if t.callbacks.onMessage != nil {
-		t.callbacks.onMessage(messageType, message)
+	t.mu.RLock()
+	f := t.callbacks.onMessage
+	t.mu.RUnlock()
+
+	if f != nil {
+		f(messageType, message)
 	}
 }
 
 func (t *Ticker) triggerTick(tick models.Tick) {
-	if t.callbacks.onTick != nil {
-		t.callbacks.onTick(tick)
+	t.mu.RLock()
+	f := t.callbacks.onTick
+	r := t.router
+	snaps := t.snapshots
+	t.mu.RUnlock()
+
+	// The snapshot is updated before the callbacks so that they see the tick in it.
+	if snaps != nil {
+		snaps.Update(tick)
+	}
+
+	if f != nil {
+		f(tick)
 	}
+
+	if r != nil {
+		r.Dispatch(tick)
+	}
+
+	t.streams.pushTick(tick)
 }
 
 func (t *Ticker) triggerOrderUpdate(order kiteconnect.Order) {
-	if t.callbacks.onOrderUpdate != nil {
-		t.callbacks.onOrderUpdate(order)
+	t.mu.RLock()
+	f := t.callbacks.onOrderUpdate
+	t.mu.RUnlock()
+
+	if f != nil {
+		f(order)
 	}
+
+	t.streams.pushOrder(order)
 }
 
 // Periodically check for last ping time and initiate reconnect if applicable.
-func (t *Ticker) checkConnection(ctx context.Context, wg *sync.WaitGroup) {
+func (t *Ticker) checkConnection(ctx context.Context, cancel context.CancelFunc, wg *sync.WaitGroup) {
 	defer wg.Done()
+
+	t.mu.RLock()
+	var (
+		interval = t.checkInterval
+		timeout  = t.dataTimeout
+	)
+	t.mu.RUnlock()
+
+	tk := time.NewTicker(interval)
+	defer tk.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			// Sleep before doing next check
-			time.Sleep(connectionCheckInterval)
-
+		case <-tk.C:
 			// If last ping time is greater then timeout interval then close the
 			// existing connection and reconnect
-			if time.Since(t.lastPingTime.Get()) > dataTimeoutInterval {
-				// Close the current connection without waiting for close frame
-				if t.Conn != nil {
-					t.Conn.Close()
-				}
-
-				// Increase reconnect attempt for next reconnection
-				t.reconnectAttempt++
-				// Mark it as done in wait group
+			if time.Since(t.lastPingTime.Get()) > timeout {
+				cancel()
 				return
 			}
 		}
@@ -436,75 +697,140 @@ func (t *Ticker) checkConnection(ctx context.Context, wg *sync.WaitGroup) {
 }
 
 // readMessage reads the data in a loop.
-func (t *Ticker) readMessage(ctx context.Context, wg *sync.WaitGroup) {
+func (t *Ticker) readMessage(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, wg *sync.WaitGroup) {
 	defer wg.Done()
+	defer cancel()
+
+	// Ticks of every message are parsed into the same buffer.
+	var ticks []models.Tick
+
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			mType, msg, err := t.Conn.ReadMessage()
-			if err != nil {
+		mType, msg, err := conn.ReadMessage()
+		now := time.Now()
+		if err != nil {
+			// Errors due to the connection being closed on purpose aren't reported.
+			if ctx.Err() == nil {
 				t.triggerError(fmt.Errorf("Error reading data: %v", err))
-				return
 			}
+			return
+		}
 
-			// Update last ping time to check for connection
-			t.lastPingTime.Set(time.Now())
-
-			// Trigger message.
-			t.triggerMessage(mType, msg)
-
-			// If binary message then parse and send tick.
-			if mType == websocket.BinaryMessage {
-				ticks, err := t.parseBinary(msg)
-				if err != nil {
-					t.triggerError(fmt.Errorf("Error parsing data received: %v", err))
-				}
-
-				// Trigger individual tick.
-				for _, tick := range ticks {
-					t.triggerTick(tick)
-				}
-			} else if mType == websocket.TextMessage {
-				t.processTextMessage(msg)
+		// Update last ping time to check for connection
+		t.lastPingTime.Set(now)
+
+		// Record the message before it's processed.
+		if rec := t.getRecorder(); rec != nil {
+			if err := rec.Record(mType, msg); err != nil {
+				t.triggerError(fmt.Errorf("Error recording data: %v", err))
 			}
 		}
+
+		ticks, err = t.handleMessage(mType, msg, ticks[:0])
+
+		if m := t.getMetrics(); m != nil {
+			reportMessage(m, now, mType, len(msg), ticks, err)
+		}
 	}
 }
 
+// handleMessage triggers the callbacks for a message received from the server.
+// Ticks are parsed into buf which is returned for reuse along with the
+// error of parsing them.
+func (t *Ticker) handleMessage(mType int, msg []byte, buf []models.Tick) ([]models.Tick, error) {
+	// Trigger message.
+	t.triggerMessage(mType, msg)
+
+	// If binary message then parse and send tick.
+	if mType == websocket.BinaryMessage {
+		ticks, err := ParseFrame(buf, msg)
+
+		// Bad packets are skipped and reported individually.
+		if ferr, ok := err.(*FrameError); ok {
+			for _, perr := range ferr.Packets {
+				t.triggerError(fmt.Errorf("Error parsing data received: %v", perr))
+			}
+			if ferr.Truncated {
+				t.triggerError(fmt.Errorf("Error parsing data received: %v", ErrTruncatedFrame))
+			}
+		}
+
+		// Trigger individual tick.
+		for _, tick := range ticks {
+			t.triggerTick(tick)
+		}
+
+		return ticks, err
+	} else if mType == websocket.TextMessage {
+		t.processTextMessage(msg)
+	}
+
+	return buf, nil
+}
+
+// writeMessage writes a message to the current connection. Writes
+// are serialised as a websocket connection supports one writer at a time.
+func (t *Ticker) writeMessage(messageType int, data []byte) error {
+	conn := t.getConn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return conn.WriteMessage(messageType, data)
+}
+
+// send sends an input message to the server. Nothing is sent if the ticker
+// isn't connected as the stored subscriptions are sent on connect.
+func (t *Ticker) send(input tickerInput) error {
+	out, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	if err := t.writeMessage(websocket.TextMessage, out); err != ErrNotConnected {
+		return err
+	}
+	return nil
+}
+
 // Close tries to close the connection gracefully. If the server doesn't close it
 func (t *Ticker) Close() error {
-	return t.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return t.writeMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 }
 
 // Stop the ticker instance and all the goroutines it has spawned.
 func (t *Ticker) Stop() {
-	if t.cancel != nil {
-		t.cancel()
+	t.mu.RLock()
+	cancel := t.cancel
+	t.mu.RUnlock()
+
+	if cancel != nil {
+		cancel()
 	}
 }
 
-// Subscribe subscribes tick for the given list of tokens.
+// Subscribe subscribes tick for the given list of tokens. If the ticker isn't
+// connected yet, tokens are subscribed once it connects.
 func (t *Ticker) Subscribe(tokens []uint32) error {
 	if len(tokens) == 0 {
 		return nil
 	}
 
-	out, err := json.Marshal(tickerInput{
-		Type: "subscribe",
-		Val:  tokens,
-	})
-	if err != nil {
-		return err
-	}
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
 
 	// Store tokens to current subscriptions
+	t.mu.Lock()
 	for _, ts := range tokens {
 		t.subscribedTokens[ts] = modeEmpty
 	}
+	t.mu.Unlock()
 
-	return t.Conn.WriteMessage(websocket.TextMessage, out)
+	return t.send(tickerInput{
+		Type: "subscribe",
+		Val:  tokens,
+	})
 }
 
 // Unsubscribe unsubscribes tick for the given list of tokens.
@@ -513,20 +839,20 @@ func (t *Ticker) Unsubscribe(tokens []uint32) error {
 		return nil
 	}
 
-	out, err := json.Marshal(tickerInput{
-		Type: "unsubscribe",
-		Val:  tokens,
-	})
-	if err != nil {
-		return err
-	}
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
 
 	// Remove tokens from current subscriptions
+	t.mu.Lock()
 	for _, ts := range tokens {
 		delete(t.subscribedTokens, ts)
 	}
+	t.mu.Unlock()
 
-	return t.Conn.WriteMessage(websocket.TextMessage, out)
+	return t.send(tickerInput{
+		Type: "unsubscribe",
+		Val:  tokens,
+	})
 }
 
 // SetMode changes mode for given list of tokens and mode.
@@ -535,20 +861,36 @@ func (t *Ticker) SetMode(mode Mode, tokens []uint32) error {
 		return nil
 	}
 
-	out, err := json.Marshal(tickerInput{
-		Type: "mode",
-		Val:  []interface{}{mode, tokens},
-	})
-	if err != nil {
-		return err
-	}
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
 
 	// Set mode in current subscriptions stored
+	t.mu.Lock()
 	for _, ts := range tokens {
 		t.subscribedTokens[ts] = mode
 	}
+	t.mu.Unlock()
+
+	return t.send(tickerInput{
+		Type: "mode",
+		Val:  []interface{}{mode, tokens},
+	})
+}
 
-	return t.Conn.WriteMessage(websocket.TextMessage, out)
+// Subscriptions returns the stored subscriptions along with their modes.
+// Tokens subscribed without setting a mode have an empty mode.
+func (t *Ticker) Subscriptions() map[uint32]Mode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make(map[uint32]Mode, len(t.subscribedTokens))
+	for to, mo := range t.subscribedTokens {
+		if mo == modeEmpty {
+			mo = ""
+		}
+		out[to] = mo
+	}
+	return out
 }
 
 // Resubscribe resubscribes to the current stored subscriptions
@@ -560,19 +902,22 @@ func (t *Ticker) Resubscribe() error {
 		ModeLTP:   []uint32{},
 	}
 
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+
 	// Make a map of mode and corresponding tokens
+	t.mu.RLock()
 	for to, mo := range t.subscribedTokens {
 		tokens = append(tokens, to)
 		if mo != modeEmpty {
 			modes[mo] = append(modes[mo], to)
 		}
 	}
-
-	fmt.Println("Subscribe again: ", tokens, t.subscribedTokens)
+	t.mu.RUnlock()
 
 	// Subscribe to tokens
 	if len(tokens) > 0 {
-		if err := t.Subscribe(tokens); err != nil {
+		if err := t.send(tickerInput{Type: "subscribe", Val: tokens}); err != nil {
 			return err
 		}
 	}
@@ -580,7 +925,7 @@ func (t *Ticker) Resubscribe() error {
 	// Set mode to tokens
 	for mo, tos := range modes {
 		if len(tos) > 0 {
-			if err := t.SetMode(mo, tos); err != nil {
+			if err := t.send(tickerInput{Type: "mode", Val: []interface{}{mo, tos}}); err != nil {
 				return err
 			}
 		}
@@ -613,167 +958,3 @@ func (t *Ticker) processTextMessage(inp []byte) {
 		t.triggerOrderUpdate(order.Data)
 	}
 }
-
-// parseBinary parses the packets to ticks.
-func (t *Ticker) parseBinary(inp []byte) ([]models.Tick, error) {
-	pkts := t.splitPackets(inp)
-	var ticks []models.Tick
-
-	for _, pkt := range pkts {
-		tick, err := parsePacket(pkt)
-		if err != nil {
-			return nil, err
-		}
-
-		ticks = append(ticks, tick)
-	}
-
-	return ticks, nil
-}
-
-// splitPackets splits packet dump to individual tick packet.
-func (t *Ticker) splitPackets(inp []byte) [][]byte {
-	var pkts [][]byte
-	if len(inp) < 2 {
-		return pkts
-	}
-
-	pktLen := binary.BigEndian.Uint16(inp[0:2])
-
-	j := 2
-	for i := 0; i < int(pktLen); i++ {
-		pLen := binary.BigEndian.Uint16(inp[j : j+2])
-		pkts = append(pkts, inp[j+2:j+2+int(pLen)])
-		j = j + 2 + int(pLen)
-	}
-
-	return pkts
-}
-
-// Parse parses a tick byte array into a tick struct.
-func parsePacket(b []byte) (models.Tick, error) {
-	var (
-		tk         = binary.BigEndian.Uint32(b[0:4])
-		seg        = tk & 0xFF
-		isIndex    = seg == Indices
-		isTradable = seg != Indices
-	)
-
-	// Mode LTP parsing
-	if len(b) == modeLTPLength {
-		return models.Tick{
-			Mode:            string(ModeLTP),
-			InstrumentToken: tk,
-			IsTradable:      isTradable,
-			IsIndex:         isIndex,
-			LastPrice:       convertPrice(seg, float64(binary.BigEndian.Uint32(b[4:8]))),
-		}, nil
-	}
-
-	// Parse index mode full and mode quote data
-	if len(b) == modeQuoteIndexPacketLength || len(b) == modeFullIndexLength {
-		var (
-			lastPrice  = convertPrice(seg, float64(binary.BigEndian.Uint32(b[4:8])))
-			closePrice = convertPrice(seg, float64(binary.BigEndian.Uint32(b[20:24])))
-		)
-
-		tick := models.Tick{
-			Mode:            string(ModeQuote),
-			InstrumentToken: tk,
-			IsTradable:      isTradable,
-			IsIndex:         isIndex,
-			LastPrice:       lastPrice,
-			NetChange:       lastPrice - closePrice,
-			OHLC: models.OHLC{
-				High:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[8:12]))),
-				Low:   convertPrice(seg, float64(binary.BigEndian.Uint32(b[12:16]))),
-				Open:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[16:20]))),
-				Close: closePrice,
-			}}
-
-		// On mode full set timestamp
-		if len(b) == modeFullIndexLength {
-			tick.Mode = string(ModeFull)
-			tick.Timestamp = models.Time{Time: time.Unix(int64(binary.BigEndian.Uint32(b[28:32])), 0)}
-		}
-
-		return tick, nil
-	}
-
-	// Parse mode quote.
-	var (
-		lastPrice  = convertPrice(seg, float64(binary.BigEndian.Uint32(b[4:8])))
-		closePrice = convertPrice(seg, float64(binary.BigEndian.Uint32(b[40:44])))
-	)
-
-	// Mode quote data.
-	tick := models.Tick{
-		Mode:               string(ModeQuote),
-		InstrumentToken:    tk,
-		IsTradable:         isTradable,
-		IsIndex:            isIndex,
-		LastPrice:          lastPrice,
-		LastTradedQuantity: binary.BigEndian.Uint32(b[8:12]),
-		AverageTradePrice:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[12:16]))),
-		VolumeTraded:       binary.BigEndian.Uint32(b[16:20]),
-		TotalBuyQuantity:   binary.BigEndian.Uint32(b[20:24]),
-		TotalSellQuantity:  binary.BigEndian.Uint32(b[24:28]),
-		OHLC: models.OHLC{
-			Open:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[28:32]))),
-			High:  convertPrice(seg, float64(binary.BigEndian.Uint32(b[32:36]))),
-			Low:   convertPrice(seg, float64(binary.BigEndian.Uint32(b[36:40]))),
-			Close: closePrice,
-		},
-	}
-
-	// Parse full mode.
-	if len(b) == modeFullLength {
-		tick.Mode = string(ModeFull)
-		tick.LastTradeTime = models.Time{Time: time.Unix(int64(binary.BigEndian.Uint32(b[44:48])), 0)}
-		tick.OI = binary.BigEndian.Uint32(b[48:52])
-		tick.OIDayHigh = binary.BigEndian.Uint32(b[52:56])
-		tick.OIDayLow = binary.BigEndian.Uint32(b[56:60])
-		tick.Timestamp = models.Time{Time: time.Unix(int64(binary.BigEndian.Uint32(b[60:64])), 0)}
-		tick.NetChange = lastPrice - closePrice
-
-		// Depth Information.
-		var (
-			buyPos     = 64
-			sellPos    = 124
-			depthItems = (sellPos - buyPos) / 12
-		)
-
-		for i := 0; i < depthItems; i++ {
-			tick.Depth.Buy[i] = models.DepthItem{
-				Quantity: binary.BigEndian.Uint32(b[buyPos : buyPos+4]),
-				Price:    convertPrice(seg, float64(binary.BigEndian.Uint32(b[buyPos+4:buyPos+8]))),
-				Orders:   uint32(binary.BigEndian.Uint16(b[buyPos+8 : buyPos+10])),
-			}
-
-			tick.Depth.Sell[i] = models.DepthItem{
-				Quantity: binary.BigEndian.Uint32(b[sellPos : sellPos+4]),
-				Price:    convertPrice(seg, float64(binary.BigEndian.Uint32(b[sellPos+4:sellPos+8]))),
-				Orders:   uint32(binary.BigEndian.Uint16(b[sellPos+8 : sellPos+10])),
-			}
-
-			buyPos += 12
-			sellPos += 12
-		}
-	}
-
-	return tick, nil
-}
-
-// convertPrice converts prices of stocks from paise to rupees
-// with varying decimals based on the segment.
-func convertPrice(seg uint32, val float64) float64 {
-	switch seg {
-	case NseCD:
-		return val / 10000000.0
-	case BseCD:
-		return val / 10000.0
-	default:
-		return val / 100.0
-	}
-}
-
@@ -0,0 +1,146 @@
+package tickertest
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// Default relative standard deviation of a random walk step.
+const defaultStep = 0.001
+
+// Feed generates the ticks sent by a server.
+type Feed interface {
+	// Next returns the next batch of ticks and false once the feed is exhausted.
+	Next() ([]models.Tick, bool)
+}
+
+// script is a feed of predefined batches.
+type script struct {
+	batches [][]models.Tick
+}
+
+// Script returns a feed which sends the batches of ticks in order.
+func Script(batches ...[]models.Tick) Feed {
+	return &script{batches: batches}
+}
+
+func (s *script) Next() ([]models.Tick, bool) {
+	if len(s.batches) == 0 {
+		return nil, false
+	}
+
+	b := s.batches[0]
+	s.batches = s.batches[1:]
+	return b, true
+}
+
+// RandomWalk is an endless feed in which the price of every instrument moves
+// randomly with normally distributed returns. Prices are rounded to 0.05 and
+// the day's OHLC, volume, timestamps and market depth are updated on every
+// step. Walks with the same seed generate the same prices.
+type RandomWalk struct {
+	// Step is the standard deviation of the return of each step. Defaults to 0.1%.
+	Step float64
+	// Now returns the timestamp of ticks. Defaults to time.Now.
+	Now func() time.Time
+
+	rnd    *rand.Rand
+	tokens []uint32
+	ticks  map[uint32]*models.Tick
+}
+
+// NewRandomWalk creates a random walk starting at the given prices of instruments.
+func NewRandomWalk(seed int64, prices map[uint32]float64) *RandomWalk {
+	w := &RandomWalk{
+		Step:  defaultStep,
+		Now:   time.Now,
+		rnd:   rand.New(rand.NewSource(seed)),
+		ticks: map[uint32]*models.Tick{},
+	}
+
+	for tk, p := range prices {
+		w.tokens = append(w.tokens, tk)
+		w.ticks[tk] = &models.Tick{
+			InstrumentToken: tk,
+			LastPrice:       p,
+			OHLC:            models.OHLC{Open: p, High: p, Low: p, Close: p},
+		}
+	}
+	sort.Slice(w.tokens, func(i, j int) bool { return w.tokens[i] < w.tokens[j] })
+
+	return w
+}
+
+// Next moves the price of every instrument by one step.
+func (w *RandomWalk) Next() ([]models.Tick, bool) {
+	var (
+		now = models.Time{Time: w.Now().Truncate(time.Second)}
+		out = make([]models.Tick, 0, len(w.tokens))
+	)
+
+	for _, tk := range w.tokens {
+		t := w.ticks[tk]
+
+		price := roundTick(t.LastPrice * (1 + w.rnd.NormFloat64()*w.Step))
+		if price <= 0 {
+			price = 0.05
+		}
+		qty := uint32(1 + w.rnd.Intn(100))
+
+		t.LastPrice = price
+		t.LastTradedQuantity = qty
+		t.AverageTradePrice = roundTick((t.AverageTradePrice*float64(t.VolumeTraded) + price*float64(qty)) / float64(t.VolumeTraded+qty))
+		t.VolumeTraded += qty
+		t.OHLC.High = math.Max(t.OHLC.High, price)
+		t.OHLC.Low = math.Min(t.OHLC.Low, price)
+		t.NetChange = price - t.OHLC.Close
+		t.Timestamp, t.LastTradeTime = now, now
+
+		t.TotalBuyQuantity, t.TotalSellQuantity = 0, 0
+		for i := 0; i < 5; i++ {
+			var (
+				bq = uint32(1 + w.rnd.Intn(1000))
+				sq = uint32(1 + w.rnd.Intn(1000))
+			)
+			t.Depth.Buy[i] = models.DepthItem{Price: math.Max(0, roundTick(price-0.05*float64(i+1))), Quantity: bq, Orders: 1 + bq/100}
+			t.Depth.Sell[i] = models.DepthItem{Price: roundTick(price + 0.05*float64(i+1)), Quantity: sq, Orders: 1 + sq/100}
+			t.TotalBuyQuantity += bq
+			t.TotalSellQuantity += sq
+		}
+
+		out = append(out, *t)
+	}
+
+	return out, true
+}
+
+// roundTick rounds a price to the tick size of 0.05.
+func roundTick(p float64) float64 {
+	return math.Round(p*20) / 20
+}
+
+// Play sends the batches of ticks of the feed at the given interval till the
+// feed is exhausted or the context is cancelled.
+func (s *Server) Play(ctx context.Context, feed Feed, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		ticks, ok := feed.Next()
+		if !ok {
+			return nil
+		}
+		s.SendTicks(ticks...)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// Package tickertest provides an in-process fake Kite ticker server for
+// testing ticker consumers without a live connection.
+//
+//	srv := tickertest.NewServer(tickertest.Options{})
+//	defer srv.Close()
+//
+//	t := kiteticker.New("api_key", "access_token")
+//	t.SetRootURL(srv.URL())
+package tickertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+	kiteticker "github.com/zerodha/gokiteconnect/v4/ticker"
+)
+
+const (
+	// Default interval of heartbeats sent by the server.
+	defaultHeartbeat = time.Second
+	// Format of timestamps in order updates.
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// ist is the timezone of timestamps in order updates.
+var ist = time.FixedZone("IST", 19800)
+
+// Options represents the options of a server.
+type Options struct {
+	// APIKey and AccessToken, if set, are matched with the ones in the
+	// connection request which is rejected with 403 if they don't match.
+	APIKey      string
+	AccessToken string
+
+	// Heartbeat is the interval at which 1 byte heartbeats are sent like
+	// the Kite ticker does. Defaults to a second and negative disables it.
+	Heartbeat time.Duration
+}
+
+// Message represents a message received from a client.
+type Message struct {
+	Type  string
+	Mode  kiteticker.Mode
+	Token []uint32
+}
+
+// Server is a fake ticker server. It keeps the subscriptions of each
+// connection and sends ticks in the mode subscribed on that connection.
+// It's safe for concurrent use.
+type Server struct {
+	opt Options
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	conns    map[*conn]bool
+	received []Message
+	stalled  bool
+	connWait chan struct{}
+	done     chan struct{}
+}
+
+// conn is a client connection.
+type conn struct {
+	ws *websocket.Conn
+	// Guards writes and the subscriptions.
+	mu   sync.Mutex
+	subs map[uint32]kiteticker.Mode
+}
+
+// NewServer starts a new server.
+func NewServer(opt Options) *Server {
+	if opt.Heartbeat == 0 {
+		opt.Heartbeat = defaultHeartbeat
+	}
+
+	s := &Server{
+		opt:      opt,
+		conns:    map[*conn]bool{},
+		connWait: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	if opt.Heartbeat > 0 {
+		go s.heartbeat()
+	}
+
+	return s
+}
+
+// URL returns the url to be set as the ticker's root url.
+func (s *Server) URL() url.URL {
+	u, _ := url.Parse(s.srv.URL)
+	return url.URL{Scheme: "ws", Host: u.Host}
+}
+
+// Close closes all the connections and shuts down the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.mu.Unlock()
+
+	s.Disconnect()
+	s.srv.Close()
+}
+
+// Connections returns the number of open connections.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// WaitConnections waits till there are at least n open connections and
+// returns false if that doesn't happen within the timeout.
+func (s *Server) WaitConnections(n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		count, wait := len(s.conns), s.connWait
+		s.mu.Unlock()
+
+		if count >= n {
+			return true
+		}
+
+		select {
+		case <-wait:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// Subscriptions returns the tokens subscribed on any of the connections
+// along with their modes.
+func (s *Server) Subscriptions() map[uint32]kiteticker.Mode {
+	out := map[uint32]kiteticker.Mode{}
+	for _, c := range s.connections() {
+		c.mu.Lock()
+		for tk, mo := range c.subs {
+			out[tk] = mo
+		}
+		c.mu.Unlock()
+	}
+	return out
+}
+
+// Received returns the messages received from all the clients.
+func (s *Server) Received() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.received...)
+}
+
+// SendTicks sends the ticks to the connections on which their tokens are
+// subscribed, encoded in the subscribed mode. Ticks of each connection
+// are sent in a single message. The mode of the ticks is ignored and ticks
+// which can't be encoded aren't sent.
+func (s *Server) SendTicks(ticks ...models.Tick) {
+	if s.isStalled() {
+		return
+	}
+
+	for _, c := range s.connections() {
+		c.mu.Lock()
+		var out []models.Tick
+		for _, tick := range ticks {
+			if mode, ok := c.subs[tick.InstrumentToken]; ok {
+				tick.Mode = string(mode)
+				out = append(out, tick)
+			}
+		}
+		if len(out) > 0 {
+			if frame, err := kiteticker.EncodeFrame(out...); err == nil {
+				c.ws.WriteMessage(websocket.BinaryMessage, frame)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// SendOrderUpdate sends an order update to all the connections.
+func (s *Server) SendOrderUpdate(order kiteconnect.Order) {
+	b, err := json.Marshal(order)
+	if err != nil {
+		return
+	}
+
+	// Timestamps are sent in the format used by Kite, as zero
+	// times in the default format can't be parsed back.
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return
+	}
+	for k, v := range data {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, str); err == nil {
+			if t.IsZero() {
+				data[k] = nil
+			} else {
+				data[k] = t.In(ist).Format(timeLayout)
+			}
+		}
+	}
+
+	s.sendJSON(map[string]interface{}{"type": "order", "data": data})
+}
+
+// SendError sends an error message to all the connections.
+func (s *Server) SendError(msg string) {
+	s.sendJSON(map[string]interface{}{"type": "error", "data": msg})
+}
+
+// SendRaw sends a message as is to all the connections.
+func (s *Server) SendRaw(messageType int, data []byte) {
+	for _, c := range s.connections() {
+		c.write(messageType, data)
+	}
+}
+
+// Disconnect drops all the connections abruptly without a close frame.
+func (s *Server) Disconnect() {
+	for _, c := range s.connections() {
+		c.ws.Close()
+	}
+}
+
+// CloseWith closes all the connections with a close frame of the given code and reason.
+func (s *Server) CloseWith(code int, reason string) {
+	for _, c := range s.connections() {
+		c.write(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+		c.ws.Close()
+	}
+}
+
+// Stall stops sending ticks and heartbeats, without closing the connections,
+// for the given duration to simulate a dead connection.
+func (s *Server) Stall(d time.Duration) {
+	s.mu.Lock()
+	s.stalled = true
+	s.mu.Unlock()
+
+	time.AfterFunc(d, func() {
+		s.mu.Lock()
+		s.stalled = false
+		s.mu.Unlock()
+	})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if (s.opt.APIKey != "" && q.Get("api_key") != s.opt.APIKey) ||
+		(s.opt.AccessToken != "" && q.Get("access_token") != s.opt.AccessToken) {
+		http.Error(w, "invalid api_key or access_token", http.StatusForbidden)
+		return
+	}
+
+	upgrader := websocket.Upgrader{}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &conn{ws: ws, subs: map[uint32]kiteticker.Mode{}}
+	s.mu.Lock()
+	s.conns[c] = true
+	close(s.connWait)
+	s.connWait = make(chan struct{})
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		m, ok := parseMessage(msg)
+		if !ok {
+			continue
+		}
+
+		c.mu.Lock()
+		for _, tk := range m.Token {
+			switch m.Type {
+			case "subscribe":
+				// Kite subscribes in quote mode by default.
+				c.subs[tk] = kiteticker.ModeQuote
+			case "unsubscribe":
+				delete(c.subs, tk)
+			case "mode":
+				if _, ok := c.subs[tk]; ok {
+					c.subs[tk] = m.Mode
+				}
+			}
+		}
+		c.mu.Unlock()
+
+		s.mu.Lock()
+		s.received = append(s.received, m)
+		s.mu.Unlock()
+	}
+}
+
+// parseMessage parses a subscribe, unsubscribe or mode message.
+func parseMessage(msg []byte) (Message, bool) {
+	var in struct {
+		Type string          `json:"a"`
+		Val  json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(msg, &in); err != nil {
+		return Message{}, false
+	}
+
+	m := Message{Type: in.Type}
+	switch in.Type {
+	case "subscribe", "unsubscribe":
+		if err := json.Unmarshal(in.Val, &m.Token); err != nil {
+			return m, false
+		}
+	case "mode":
+		var v []json.RawMessage
+		if err := json.Unmarshal(in.Val, &v); err != nil || len(v) != 2 {
+			return m, false
+		}
+		if json.Unmarshal(v[0], &m.Mode) != nil || json.Unmarshal(v[1], &m.Token) != nil {
+			return m, false
+		}
+	default:
+		return m, false
+	}
+
+	return m, true
+}
+
+func (s *Server) heartbeat() {
+	t := time.NewTicker(s.opt.Heartbeat)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			if !s.isStalled() {
+				s.SendRaw(websocket.BinaryMessage, []byte{0})
+			}
+		}
+	}
+}
+
+func (s *Server) sendJSON(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.SendRaw(websocket.TextMessage, b)
+}
+
+func (s *Server) connections() []*conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		out = append(out, c)
+	}
+	return out
+}
+
+func (s *Server) isStalled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stalled
+}
+
+func (c *conn) write(messageType int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ws.WriteMessage(messageType, data)
+}
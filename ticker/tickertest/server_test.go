@@ -0,0 +1,315 @@
+package tickertest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	kiteconnect "github.com/zerodha/gokiteconnect/v4"
+	"github.com/zerodha/gokiteconnect/v4/models"
+	kiteticker "github.com/zerodha/gokiteconnect/v4/ticker"
+)
+
+const (
+	tokenNSE   uint32 = 408065
+	tokenIndex uint32 = 256265
+	tokenCDS   uint32 = 412675
+)
+
+// client is a connected ticker along with the callbacks it received.
+type client struct {
+	*kiteticker.Ticker
+
+	mu     sync.Mutex
+	ticks  []models.Tick
+	orders []kiteconnect.Order
+	errs   []error
+	closes []int
+	msgs   int
+
+	connected chan struct{}
+	done      chan struct{}
+}
+
+func connect(t *testing.T, srv *Server, apiKey string) *client {
+	c := &client{
+		Ticker:    kiteticker.New(apiKey, "token"),
+		connected: make(chan struct{}, 10),
+		done:      make(chan struct{}),
+	}
+	c.SetRootURL(srv.URL())
+	c.OnConnect(func() {
+		c.connected <- struct{}{}
+	})
+	c.OnTick(func(tick models.Tick) {
+		c.mu.Lock()
+		c.ticks = append(c.ticks, tick)
+		c.mu.Unlock()
+	})
+	c.OnOrderUpdate(func(order kiteconnect.Order) {
+		c.mu.Lock()
+		c.orders = append(c.orders, order)
+		c.mu.Unlock()
+	})
+	c.OnError(func(err error) {
+		c.mu.Lock()
+		c.errs = append(c.errs, err)
+		c.mu.Unlock()
+	})
+	c.OnClose(func(code int, reason string) {
+		c.mu.Lock()
+		c.closes = append(c.closes, code)
+		c.mu.Unlock()
+	})
+	c.OnMessage(func(messageType int, message []byte) {
+		c.mu.Lock()
+		c.msgs++
+		c.mu.Unlock()
+	})
+
+	go func() {
+		c.Serve()
+		close(c.done)
+	}()
+	t.Cleanup(func() {
+		c.Stop()
+		<-c.done
+	})
+
+	return c
+}
+
+func (c *client) waitConnect(t *testing.T) {
+	select {
+	case <-c.connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connection")
+	}
+}
+
+func (c *client) received() ([]models.Tick, []kiteconnect.Order, []error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]models.Tick{}, c.ticks...), append([]kiteconnect.Order{}, c.orders...), append([]error{}, c.errs...)
+}
+
+func (c *client) messages() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.msgs
+}
+
+func fullTick(token uint32, price float64) models.Tick {
+	ts := models.Time{Time: time.Unix(1625461887, 0)}
+	tick := models.Tick{
+		InstrumentToken:    token,
+		LastPrice:          price,
+		LastTradedQuantity: 5,
+		AverageTradePrice:  price - 1,
+		VolumeTraded:       1000,
+		TotalBuyQuantity:   300,
+		TotalSellQuantity:  400,
+		OI:                 10,
+		OIDayHigh:          12,
+		OIDayLow:           8,
+		Timestamp:          ts,
+		LastTradeTime:      ts,
+		OHLC:               models.OHLC{Open: price - 2, High: price + 3, Low: price - 4, Close: price - 5},
+	}
+	for i := 0; i < 5; i++ {
+		tick.Depth.Buy[i] = models.DepthItem{Price: price - float64(i+1), Quantity: uint32(10 * (i + 1)), Orders: uint32(i + 1)}
+		tick.Depth.Sell[i] = models.DepthItem{Price: price + float64(i+1), Quantity: uint32(20 * (i + 1)), Orders: uint32(i + 2)}
+	}
+	return tick
+}
+
+func TestServerModes(t *testing.T) {
+	t.Parallel()
+	srv := NewServer(Options{Heartbeat: -1})
+	defer srv.Close()
+
+	c := connect(t, srv, "key")
+	c.waitConnect(t)
+	require.True(t, srv.WaitConnections(1, 5*time.Second))
+
+	require.NoError(t, c.Subscribe([]uint32{tokenNSE, tokenIndex, tokenCDS, 1}))
+	require.NoError(t, c.SetMode(kiteticker.ModeFull, []uint32{tokenNSE, tokenIndex}))
+	require.NoError(t, c.SetMode(kiteticker.ModeLTP, []uint32{tokenCDS}))
+	require.NoError(t, c.Unsubscribe([]uint32{1}))
+
+	require.Eventually(t, func() bool {
+		return len(srv.Received()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, map[uint32]kiteticker.Mode{
+		tokenNSE:   kiteticker.ModeFull,
+		tokenIndex: kiteticker.ModeFull,
+		tokenCDS:   kiteticker.ModeLTP,
+	}, srv.Subscriptions())
+	require.Equal(t, Message{Type: "mode", Mode: kiteticker.ModeLTP, Token: []uint32{tokenCDS}}, srv.Received()[2])
+
+	nse := fullTick(tokenNSE, 1573.15)
+	srv.SendTicks(nse, fullTick(tokenIndex, 15000.5), fullTick(tokenCDS, 74.1234567), fullTick(1, 10))
+
+	require.Eventually(t, func() bool {
+		ticks, _, _ := c.received()
+		return len(ticks) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ticks, _, _ := c.received()
+
+	nse.Mode, nse.IsTradable = "full", true
+	nse.NetChange = nse.LastPrice - nse.OHLC.Close
+	require.Equal(t, nse, ticks[0])
+
+	require.Equal(t, models.Tick{
+		Mode:            "full",
+		InstrumentToken: tokenIndex,
+		IsIndex:         true,
+		LastPrice:       15000.5,
+		NetChange:       5,
+		Timestamp:       models.Time{Time: time.Unix(1625461887, 0)},
+		OHLC:            models.OHLC{Open: 14998.5, High: 15003.5, Low: 14996.5, Close: 14995.5},
+	}, ticks[1])
+
+	require.Equal(t, "ltp", ticks[2].Mode)
+	require.InDelta(t, 74.1234567, ticks[2].LastPrice, 1e-9)
+}
+
+func TestServerMessages(t *testing.T) {
+	t.Parallel()
+	srv := NewServer(Options{APIKey: "key", Heartbeat: -1})
+	defer srv.Close()
+
+	c := connect(t, srv, "key")
+	c.waitConnect(t)
+	require.True(t, srv.WaitConnections(1, 5*time.Second))
+
+	srv.SendOrderUpdate(kiteconnect.Order{
+		OrderID:        "1",
+		Status:         "COMPLETE",
+		OrderTimestamp: models.Time{Time: time.Date(2021, 7, 5, 10, 0, 0, 0, ist)},
+	})
+	srv.SendError("invalid token")
+
+	require.Eventually(t, func() bool {
+		_, orders, errs := c.received()
+		return len(orders) == 1 && len(errs) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, orders, errs := c.received()
+	require.Equal(t, "1", orders[0].OrderID)
+	require.Equal(t, "COMPLETE", orders[0].Status)
+	require.True(t, orders[0].OrderTimestamp.Equal(time.Date(2021, 7, 5, 10, 0, 0, 0, ist)))
+	require.True(t, orders[0].ExchangeTimestamp.IsZero())
+	require.EqualError(t, errs[0], "invalid token")
+
+	// Invalid credentials are rejected.
+	bad := connect(t, srv, "other")
+	require.Eventually(t, func() bool {
+		_, _, errs := bad.received()
+		return len(errs) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, srv.Connections())
+}
+
+func TestServerDisconnect(t *testing.T) {
+	t.Parallel()
+	srv := NewServer(Options{Heartbeat: 20 * time.Millisecond})
+	defer srv.Close()
+
+	c := connect(t, srv, "key")
+	c.waitConnect(t)
+	require.True(t, srv.WaitConnections(1, 5*time.Second))
+	require.NoError(t, c.Subscribe([]uint32{tokenNSE}))
+
+	// Heartbeats stop while stalled.
+	require.Eventually(t, func() bool {
+		return c.messages() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	srv.Stall(300 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	n := c.messages()
+	time.Sleep(150 * time.Millisecond)
+	require.Equal(t, n, c.messages())
+	require.Eventually(t, func() bool {
+		return c.messages() > n
+	}, 5*time.Second, 10*time.Millisecond)
+
+	srv.CloseWith(4000, "going away")
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.closes) == 1 && c.closes[0] == 4000
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Ticker reconnects and resubscribes.
+	require.Eventually(t, func() bool {
+		_, ok := srv.Subscriptions()[tokenNSE]
+		return ok
+	}, 10*time.Second, 10*time.Millisecond)
+
+	srv.Disconnect()
+	require.Eventually(t, func() bool {
+		return len(srv.Received()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestServerPlay(t *testing.T) {
+	t.Parallel()
+	srv := NewServer(Options{Heartbeat: -1})
+	defer srv.Close()
+
+	c := connect(t, srv, "key")
+	c.waitConnect(t)
+	require.True(t, srv.WaitConnections(1, 5*time.Second))
+	require.NoError(t, c.Subscribe([]uint32{tokenNSE}))
+	require.NoError(t, c.SetMode(kiteticker.ModeLTP, []uint32{tokenNSE}))
+	require.Eventually(t, func() bool {
+		return len(srv.Subscriptions()) == 1 && srv.Subscriptions()[tokenNSE] == kiteticker.ModeLTP
+	}, 5*time.Second, 10*time.Millisecond)
+
+	feed := Script(
+		[]models.Tick{{InstrumentToken: tokenNSE, LastPrice: 10}},
+		[]models.Tick{{InstrumentToken: tokenNSE, LastPrice: 11}, {InstrumentToken: tokenIndex, LastPrice: 1}},
+	)
+	require.NoError(t, srv.Play(context.Background(), feed, time.Millisecond))
+
+	require.Eventually(t, func() bool {
+		ticks, _, _ := c.received()
+		return len(ticks) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	ticks, _, _ := c.received()
+	require.Equal(t, 10.0, ticks[0].LastPrice)
+	require.Equal(t, 11.0, ticks[1].LastPrice)
+}
+
+func TestRandomWalk(t *testing.T) {
+	t.Parallel()
+
+	walk := func() []models.Tick {
+		w := NewRandomWalk(1, map[uint32]float64{tokenNSE: 1500, tokenIndex: 15000})
+		w.Now = func() time.Time { return time.Unix(1625461887, 0) }
+
+		var out []models.Tick
+		for i := 0; i < 100; i++ {
+			ticks, ok := w.Next()
+			require.True(t, ok)
+			require.Len(t, ticks, 2)
+			out = append(out, ticks...)
+		}
+		return out
+	}
+
+	a, b := walk(), walk()
+	require.Equal(t, a, b)
+
+	last := a[len(a)-1]
+	require.Equal(t, tokenNSE, last.InstrumentToken)
+	require.NotEqual(t, 1500.0, last.LastPrice)
+	require.InDelta(t, last.LastPrice, roundTick(last.LastPrice), 1e-9)
+	require.True(t, last.OHLC.High >= last.LastPrice && last.OHLC.Low <= last.LastPrice)
+	require.Equal(t, 1500.0, last.OHLC.Open)
+	require.True(t, last.Depth.Buy[0].Price < last.LastPrice && last.Depth.Sell[0].Price > last.LastPrice)
+}
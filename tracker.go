@@ -0,0 +1,367 @@
+package kiteconnect
+
+import (
+	"sync"
+	"time"
+)
+
+// OrderEventType represents the type of an order event.
+type OrderEventType int
+
+const (
+	// OrderEventPending is emitted when an order is received but isn't open at the exchange yet.
+	OrderEventPending OrderEventType = iota + 1
+	// OrderEventOpen is emitted when an order is open at the exchange.
+	OrderEventOpen
+	// OrderEventTriggerPending is emitted when a stoploss order is waiting for its trigger.
+	OrderEventTriggerPending
+	// OrderEventModified is emitted when the quantity, price, trigger price or type of an order changes.
+	OrderEventModified
+	// OrderEventPartiallyFilled is emitted when a part of an order is filled.
+	OrderEventPartiallyFilled
+	// OrderEventFilled is emitted when an order is completely filled.
+	OrderEventFilled
+	// OrderEventCancelled is emitted when an order is cancelled, including the
+	// unfilled part of IOC orders.
+	OrderEventCancelled
+	// OrderEventRejected is emitted when an order is rejected.
+	OrderEventRejected
+)
+
+// String returns the name of the event type.
+func (e OrderEventType) String() string {
+	switch e {
+	case OrderEventPending:
+		return "pending"
+	case OrderEventOpen:
+		return "open"
+	case OrderEventTriggerPending:
+		return "trigger_pending"
+	case OrderEventModified:
+		return "modified"
+	case OrderEventPartiallyFilled:
+		return "partially_filled"
+	case OrderEventFilled:
+		return "filled"
+	case OrderEventCancelled:
+		return "cancelled"
+	case OrderEventRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// OrderEvent represents a change in the state of an order.
+type OrderEvent struct {
+	Type OrderEventType
+	// Order is the state of the order after the change.
+	Order Order
+	// Fill is the quantity filled by the change for fill events.
+	Fill OrderFill
+	// Reason is the status message of rejected and cancelled orders.
+	Reason string
+}
+
+// OrderFill represents a part of an order being filled.
+type OrderFill struct {
+	Quantity float64
+	// Price is the average price of the quantity filled.
+	Price float64
+	Time  time.Time
+}
+
+// OrderTracker merges the updates of orders received from the ticker, postbacks
+// and the order history into the latest state of every order. Updates may
+// arrive out of order or duplicated and the ones which are older than the
+// current state, or which make an invalid status transition, are ignored.
+// Statuses move from pending to open (including trigger pending and modifications)
+// to one of COMPLETE, CANCELLED or REJECTED after which the order doesn't change.
+// It's safe for concurrent use.
+type OrderTracker struct {
+	mu       sync.RWMutex
+	orders   map[string]*trackedOrder
+	onEvent  func(OrderEvent)
+	watchers map[string][]chan struct{}
+}
+
+type trackedOrder struct {
+	order Order
+	fills []OrderFill
+}
+
+// NewOrderTracker creates an order tracker.
+func NewOrderTracker() *OrderTracker {
+	return &OrderTracker{
+		orders:   map[string]*trackedOrder{},
+		watchers: map[string][]chan struct{}{},
+	}
+}
+
+// OnEvent sets the callback for the events of all the orders. It's called
+// after the update which caused the event has been applied.
+func (t *OrderTracker) OnEvent(f func(event OrderEvent)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEvent = f
+}
+
+// Update merges an update of an order and returns the events it caused,
+// which are none if the update was ignored.
+func (t *OrderTracker) Update(order Order) []OrderEvent {
+	if order.OrderID == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	tr, ok := t.orders[order.OrderID]
+	if !ok {
+		tr = &trackedOrder{}
+		t.orders[order.OrderID] = tr
+	}
+
+	var prev *Order
+	if ok {
+		prev = &tr.order
+	}
+
+	if ok && !validTransition(*prev, order) {
+		t.mu.Unlock()
+		return nil
+	}
+
+	events := orderEvents(prev, order)
+	for _, e := range events {
+		if e.Fill.Quantity > 0 {
+			tr.fills = append(tr.fills, e.Fill)
+		}
+	}
+	tr.order = order
+	f := t.onEvent
+
+	// Waiters are signalled without blocking as they only need the latest state.
+	for _, ch := range t.watchers[order.OrderID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	t.mu.Unlock()
+
+	if f != nil {
+		for _, e := range events {
+			f(e)
+		}
+	}
+
+	return events
+}
+
+// UpdateHistory merges the history of an order as returned by GetOrderHistory.
+func (t *OrderTracker) UpdateHistory(history []Order) []OrderEvent {
+	var events []OrderEvent
+	for _, o := range history {
+		events = append(events, t.Update(o)...)
+	}
+	return events
+}
+
+// Sync fetches the history of an order and merges it.
+func (t *OrderTracker) Sync(c *Client, orderID string) ([]OrderEvent, error) {
+	history, err := c.GetOrderHistory(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return t.UpdateHistory(history), nil
+}
+
+// Order returns the current state of an order.
+func (t *OrderTracker) Order(orderID string) (Order, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tr, ok := t.orders[orderID]
+	if !ok {
+		return Order{}, false
+	}
+	return tr.order, true
+}
+
+// Fills returns the fills of an order in the order they were received.
+func (t *OrderTracker) Fills(orderID string) []OrderFill {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tr, ok := t.orders[orderID]
+	if !ok {
+		return nil
+	}
+	return append([]OrderFill(nil), tr.fills...)
+}
+
+// Orders returns the current state of all the orders.
+func (t *OrderTracker) Orders() Orders {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make(Orders, 0, len(t.orders))
+	for _, tr := range t.orders {
+		out = append(out, tr.order)
+	}
+	return out
+}
+
+// Forget removes an order from the tracker.
+func (t *OrderTracker) Forget(orderID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.orders, orderID)
+}
+
+// watch returns a channel which is signalled whenever an order is updated
+// and a function to stop watching.
+func (t *OrderTracker) watch(orderID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	t.mu.Lock()
+	t.watchers[orderID] = append(t.watchers[orderID], ch)
+	t.mu.Unlock()
+
+	return ch, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		ws := t.watchers[orderID]
+		for i, w := range ws {
+			if w == ch {
+				ws = append(ws[:i], ws[i+1:]...)
+				break
+			}
+		}
+		if len(ws) == 0 {
+			delete(t.watchers, orderID)
+		} else {
+			t.watchers[orderID] = ws
+		}
+	}
+}
+
+// IsTerminalStatus returns true if an order with the status can't change anymore.
+func IsTerminalStatus(status string) bool {
+	return statusStage(status) == stageTerminal
+}
+
+// Stages of the life cycle of an order.
+const (
+	stagePending = iota + 1
+	stageActive
+	stageTerminal
+)
+
+// statusStage returns the stage of a status. Unknown statuses are active.
+func statusStage(status string) int {
+	switch status {
+	case OrderStatusPutOrderReqReceived, OrderStatusValidationPending,
+		OrderStatusOpenPending, OrderStatusAMOReqReceived:
+		return stagePending
+	case OrderStatusComplete, OrderStatusCancelled, OrderStatusRejected:
+		return stageTerminal
+	default:
+		return stageActive
+	}
+}
+
+// validTransition reports if an update can be applied over the current state of an order.
+func validTransition(cur, next Order) bool {
+	var (
+		curStage  = statusStage(cur.Status)
+		nextStage = statusStage(next.Status)
+	)
+
+	switch {
+	case curStage == stageTerminal:
+		// Terminal orders don't change.
+		return false
+	case nextStage < curStage:
+		return false
+	case next.FilledQuantity < cur.FilledQuantity:
+		return false
+	}
+
+	// Updates of the same stage are ordered by time when it's known.
+	if nextStage == curStage {
+		ct, nt := orderTime(cur), orderTime(next)
+		if !ct.IsZero() && !nt.IsZero() && nt.Before(ct) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// orderTime returns the time of the latest change of an order.
+func orderTime(o Order) time.Time {
+	if !o.ExchangeUpdateTimestamp.IsZero() {
+		return o.ExchangeUpdateTimestamp.Time
+	}
+	return o.OrderTimestamp.Time
+}
+
+// orderEvents returns the events of an order changing from prev, which is nil
+// for new orders, to next.
+func orderEvents(prev *Order, next Order) []OrderEvent {
+	var (
+		events  []OrderEvent
+		changed = prev == nil || prev.Status != next.Status
+	)
+
+	if prev != nil && (prev.Quantity != next.Quantity || prev.Price != next.Price ||
+		prev.TriggerPrice != next.TriggerPrice || prev.OrderType != next.OrderType) &&
+		statusStage(next.Status) != stageTerminal {
+		events = append(events, OrderEvent{Type: OrderEventModified, Order: next})
+	}
+
+	var filled, filledValue float64
+	if prev != nil {
+		filled, filledValue = prev.FilledQuantity, prev.FilledQuantity*prev.AveragePrice
+	}
+	if next.FilledQuantity > filled {
+		fill := OrderFill{
+			Quantity: next.FilledQuantity - filled,
+			Price:    (next.FilledQuantity*next.AveragePrice - filledValue) / (next.FilledQuantity - filled),
+			Time:     orderTime(next),
+		}
+
+		typ := OrderEventPartiallyFilled
+		if next.Status == OrderStatusComplete {
+			typ = OrderEventFilled
+		}
+		events = append(events, OrderEvent{Type: typ, Order: next, Fill: fill})
+	}
+
+	if !changed {
+		return events
+	}
+
+	switch next.Status {
+	case OrderStatusOpen:
+		events = append(events, OrderEvent{Type: OrderEventOpen, Order: next})
+	case OrderStatusTriggerPending:
+		events = append(events, OrderEvent{Type: OrderEventTriggerPending, Order: next})
+	case OrderStatusComplete:
+		// Complete orders without a fill quantity are still filled.
+		if next.FilledQuantity <= filled {
+			events = append(events, OrderEvent{Type: OrderEventFilled, Order: next})
+		}
+	case OrderStatusCancelled:
+		events = append(events, OrderEvent{Type: OrderEventCancelled, Order: next, Reason: next.StatusMessage})
+	case OrderStatusRejected:
+		events = append(events, OrderEvent{Type: OrderEventRejected, Order: next, Reason: next.StatusMessage})
+	default:
+		if prev == nil && statusStage(next.Status) == stagePending {
+			events = append(events, OrderEvent{Type: OrderEventPending, Order: next})
+		}
+	}
+
+	return events
+}
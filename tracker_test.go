@@ -0,0 +1,148 @@
+package kiteconnect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+func trackerOrder(status string, filled, avg float64, sec int) Order {
+	return Order{
+		OrderID:                 "1",
+		Status:                  status,
+		Quantity:                10,
+		Price:                   100,
+		OrderType:               OrderTypeLimit,
+		FilledQuantity:          filled,
+		PendingQuantity:         10 - filled,
+		AveragePrice:            avg,
+		ExchangeUpdateTimestamp: models.Time{Time: time.Unix(int64(1600000000+sec), 0)},
+	}
+}
+
+func eventTypes(events []OrderEvent) []OrderEventType {
+	var types []OrderEventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestOrderTrackerLifecycle(t *testing.T) {
+	t.Parallel()
+
+	tr := NewOrderTracker()
+
+	var received []OrderEventType
+	tr.OnEvent(func(e OrderEvent) { received = append(received, e.Type) })
+
+	tt := []struct {
+		name   string
+		order  Order
+		events []OrderEventType
+	}{
+		{name: "pending", order: trackerOrder(OrderStatusValidationPending, 0, 0, 0), events: []OrderEventType{OrderEventPending}},
+		{name: "open", order: trackerOrder(OrderStatusOpen, 0, 0, 1), events: []OrderEventType{OrderEventOpen}},
+		{name: "duplicate", order: trackerOrder(OrderStatusOpen, 0, 0, 1)},
+		{name: "late pending", order: trackerOrder(OrderStatusOpenPending, 0, 0, 0)},
+		{name: "partial fill", order: trackerOrder(OrderStatusOpen, 4, 100, 3), events: []OrderEventType{OrderEventPartiallyFilled}},
+		{name: "older update", order: trackerOrder(OrderStatusOpen, 4, 100, 2)},
+		{name: "fill decreases", order: trackerOrder(OrderStatusOpen, 2, 100, 4)},
+		{
+			name: "modified",
+			order: func() Order {
+				o := trackerOrder(OrderStatusOpen, 4, 100, 5)
+				o.Price = 99
+				return o
+			}(),
+			events: []OrderEventType{OrderEventModified},
+		},
+		{name: "complete", order: trackerOrder(OrderStatusComplete, 10, 99.4, 6), events: []OrderEventType{OrderEventFilled}},
+		{name: "after terminal", order: trackerOrder(OrderStatusCancelled, 10, 99.4, 7)},
+	}
+
+	for _, tc := range tt {
+		require.Equal(t, tc.events, eventTypes(tr.Update(tc.order)), tc.name)
+	}
+
+	require.Equal(t, []OrderEventType{
+		OrderEventPending, OrderEventOpen, OrderEventPartiallyFilled, OrderEventModified, OrderEventFilled,
+	}, received)
+
+	o, ok := tr.Order("1")
+	require.True(t, ok)
+	require.Equal(t, OrderStatusComplete, o.Status)
+
+	// Fill prices are derived from the average price.
+	fills := tr.Fills("1")
+	require.Len(t, fills, 2)
+	require.Equal(t, 4.0, fills[0].Quantity)
+	require.Equal(t, 100.0, fills[0].Price)
+	require.Equal(t, 6.0, fills[1].Quantity)
+	require.InDelta(t, 99.0, fills[1].Price, 1e-9)
+	require.Equal(t, time.Unix(1600000006, 0), fills[1].Time)
+}
+
+func TestOrderTrackerEvents(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name    string
+		updates []Order
+		events  []OrderEventType
+		reason  string
+	}{
+		{
+			name:    "rejected",
+			updates: []Order{{OrderID: "1", Status: OrderStatusRejected, StatusMessage: "Insufficient funds"}},
+			events:  []OrderEventType{OrderEventRejected},
+			reason:  "Insufficient funds",
+		},
+		{
+			name: "stoploss triggered",
+			updates: []Order{
+				{OrderID: "1", Status: OrderStatusTriggerPending, Quantity: 5},
+				{OrderID: "1", Status: OrderStatusOpen, Quantity: 5},
+				{OrderID: "1", Status: OrderStatusComplete, Quantity: 5, FilledQuantity: 5, AveragePrice: 10},
+			},
+			events: []OrderEventType{OrderEventTriggerPending, OrderEventOpen, OrderEventFilled},
+		},
+		{
+			name: "ioc partially cancelled",
+			updates: []Order{
+				{OrderID: "1", Status: OrderStatusOpen, Quantity: 5},
+				{OrderID: "1", Status: OrderStatusCancelled, Quantity: 5, FilledQuantity: 2, AveragePrice: 10, StatusMessage: "IOC"},
+			},
+			events: []OrderEventType{OrderEventOpen, OrderEventPartiallyFilled, OrderEventCancelled},
+			reason: "IOC",
+		},
+		{
+			name:    "first seen complete",
+			updates: []Order{{OrderID: "1", Status: OrderStatusComplete, Quantity: 5, FilledQuantity: 5}},
+			events:  []OrderEventType{OrderEventFilled},
+		},
+		{
+			name:    "no order id",
+			updates: []Order{{Status: OrderStatusOpen}},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := NewOrderTracker().UpdateHistory(tc.updates)
+			require.Equal(t, tc.events, eventTypes(events))
+			if tc.reason != "" {
+				require.Equal(t, tc.reason, events[len(events)-1].Reason)
+			}
+		})
+	}
+
+	require.True(t, IsTerminalStatus(OrderStatusRejected))
+	require.False(t, IsTerminalStatus(OrderStatusTriggerPending))
+	require.Equal(t, "partially_filled", OrderEventPartiallyFilled.String())
+}
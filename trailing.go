@@ -0,0 +1,437 @@
+package kiteconnect
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+const (
+	// trailingMaxModifications is the number of times an order can be modified.
+	trailingMaxModifications = 25
+	// trailingModifyInterval is the default minimum interval between the
+	// modifications of a stoploss order.
+	trailingModifyInterval = time.Second
+)
+
+// TrailingStopParams represents a stoploss which trails the price of a position.
+type TrailingStopParams struct {
+	Exchange        Exchange
+	Tradingsymbol   string
+	InstrumentToken uint32
+	Product         Product
+	// TransactionType is the side of the position, eg: TransactionTypeBuy for
+	// a long position which is protected by a stoploss order to sell.
+	TransactionType TransactionType
+	Quantity        int
+
+	// Trail is the distance of the stoploss trigger from the best price since
+	// the stop started, or TrailPercent as the percentage of the best price.
+	Trail        float64
+	TrailPercent float64
+	// Step is the minimum move of the trigger for the order to be modified.
+	// Defaults to the tick size.
+	Step     float64
+	TickSize float64
+
+	// OrderType is OrderTypeSLM, which is the default, or OrderTypeSL with
+	// the limit price at LimitOffset beyond the trigger.
+	OrderType   OrderType
+	LimitOffset float64
+
+	// Tag identifies the stoploss order so that the stop can be resumed
+	// after a restart. It should be unique for every stop.
+	Tag string
+
+	// ModifyInterval is the minimum interval between modifications of the order.
+	// Defaults to a second.
+	ModifyInterval time.Duration
+	// MaxModifications is the number of modifications after which the order is
+	// left as it is. Defaults to 25, which is the limit of modifications of an order.
+	MaxModifications int
+}
+
+// TrailingStop is the state of a trailing stop.
+type TrailingStop struct {
+	Params        TrailingStopParams
+	OrderID       string
+	TriggerPrice  float64
+	BestPrice     float64
+	Modifications int
+	LastModified  time.Time
+	// Done is true once the stoploss order is complete, cancelled or rejected.
+	Done bool
+}
+
+// TrailingStopManager maintains stoploss orders which trail the last price of
+// their positions, modifying the trigger as the price moves in favour of the
+// position by a step or more. Feed it the ticks and order updates of the ticker,
+// eg: ticker.OnTick(m.OnTick) and ticker.OnOrderUpdate(m.OnOrderUpdate).
+// Modifications are made in the background, throttled per order and limited
+// by the order rate limit of the client. It's safe for concurrent use.
+type TrailingStopManager struct {
+	c *Client
+
+	mu      sync.Mutex
+	stops   map[string]*trailingStop
+	onError func(tag string, err error)
+	now     func() time.Time
+}
+
+type trailingStop struct {
+	TrailingStop
+	inflight bool
+	// pending is set while the order of a stop is being placed.
+	pending bool
+}
+
+// NewTrailingStopManager creates a trailing stop manager which places and modifies orders with the client.
+func NewTrailingStopManager(c *Client) *TrailingStopManager {
+	return &TrailingStopManager{
+		c:     c,
+		stops: map[string]*trailingStop{},
+		now:   time.Now,
+	}
+}
+
+// OnError sets the callback for errors in modifying the orders of stops.
+func (m *TrailingStopManager) OnError(f func(tag string, err error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onError = f
+}
+
+// Start places the stoploss order of a position at the trail from its last
+// price and starts trailing it.
+func (m *TrailingStopManager) Start(p TrailingStopParams) (TrailingStop, error) {
+	p, err := trailingDefaults(p)
+	if err != nil {
+		return TrailingStop{}, err
+	}
+
+	// The tag is reserved while the order is placed so that concurrent starts
+	// of a stop don't place two orders.
+	s := &trailingStop{TrailingStop: TrailingStop{Params: p}, pending: true}
+	m.mu.Lock()
+	_, ok := m.stops[p.Tag]
+	if !ok {
+		m.stops[p.Tag] = s
+	}
+	m.mu.Unlock()
+	if ok {
+		return TrailingStop{}, NewError(InputError, fmt.Sprintf("Trailing stop %s already exists", p.Tag), nil)
+	}
+
+	stop, err := m.place(p)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if m.stops[p.Tag] == s {
+			delete(m.stops, p.Tag)
+		}
+		return TrailingStop{}, err
+	}
+	s.TrailingStop, s.pending = stop, false
+	return stop, nil
+}
+
+// place places the stoploss order of a stop at the trail from the last price.
+func (m *TrailingStopManager) place(p TrailingStopParams) (TrailingStop, error) {
+	key := string(p.Exchange) + ":" + p.Tradingsymbol
+	ltp, err := m.c.GetLTP(key)
+	if err != nil {
+		return TrailingStop{}, err
+	}
+	q, ok := ltp[key]
+	if !ok || q.LastPrice <= 0 {
+		return TrailingStop{}, NewError(DataError, fmt.Sprintf("No last price for %s", key), nil)
+	}
+
+	s := &trailingStop{TrailingStop: TrailingStop{Params: p, BestPrice: q.LastPrice}}
+	s.TriggerPrice = s.trigger(q.LastPrice)
+
+	resp, err := m.c.PlaceOrder(VarietyRegular, s.orderParams(s.TriggerPrice))
+	if err != nil {
+		return TrailingStop{}, err
+	}
+	s.OrderID = resp.OrderID
+
+	return s.TrailingStop, nil
+}
+
+// Resume resumes trailing stops after a restart from their open stoploss
+// orders, which are found by their tags. The modifications made earlier are
+// counted from the order history. Stops whose orders aren't open are returned
+// as done and aren't trailed. An error is returned for a stop which is already
+// being trailed.
+func (m *TrailingStopManager) Resume(params ...TrailingStopParams) ([]TrailingStop, error) {
+	orders, err := m.c.GetOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []TrailingStop
+	for _, p := range params {
+		p, err := trailingDefaults(p)
+		if err != nil {
+			return out, err
+		}
+
+		// The latest order with the tag is the stoploss order.
+		var order *Order
+		for i := range orders {
+			o := &orders[i]
+			if o.Tag == p.Tag && o.Exchange == p.Exchange && o.TradingSymbol == p.Tradingsymbol &&
+				(o.OrderType == OrderTypeSL || o.OrderType == OrderTypeSLM) {
+				order = o
+			}
+		}
+		if order == nil || IsTerminalStatus(order.Status) {
+			out = append(out, TrailingStop{Params: p, Done: true})
+			continue
+		}
+
+		history, err := m.c.GetOrderHistory(order.OrderID)
+		if err != nil {
+			return out, err
+		}
+
+		s := &trailingStop{TrailingStop: TrailingStop{
+			Params:       p,
+			OrderID:      order.OrderID,
+			TriggerPrice: order.TriggerPrice,
+		}}
+		for _, h := range history {
+			if h.Status == OrderStatusModified {
+				s.Modifications++
+			}
+		}
+
+		s.BestPrice = s.best(s.TriggerPrice)
+
+		m.mu.Lock()
+		_, ok := m.stops[p.Tag]
+		if !ok {
+			m.stops[p.Tag] = s
+		}
+		m.mu.Unlock()
+		if ok {
+			return out, NewError(InputError, fmt.Sprintf("Trailing stop %s already exists", p.Tag), nil)
+		}
+		out = append(out, s.TrailingStop)
+	}
+
+	return out, nil
+}
+
+// Stop stops trailing a stop, leaving its order as it is.
+func (m *TrailingStopManager) Stop(tag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.stops, tag)
+}
+
+// Stops returns the state of the trailing stops.
+func (m *TrailingStopManager) Stops() []TrailingStop {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]TrailingStop, 0, len(m.stops))
+	for _, s := range m.stops {
+		if !s.pending {
+			out = append(out, s.TrailingStop)
+		}
+	}
+	return out
+}
+
+// OnTick trails the stops of the tick's instrument.
+func (m *TrailingStopManager) OnTick(tick models.Tick) {
+	if tick.LastPrice <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.stops {
+		if s.Done || s.pending || s.Params.InstrumentToken != tick.InstrumentToken {
+			continue
+		}
+
+		s.track(tick.LastPrice)
+		trigger, ok := s.next(m.now())
+		if !ok {
+			continue
+		}
+
+		s.inflight = true
+		go m.modify(s, trigger)
+	}
+}
+
+// OnOrderUpdate marks the stops whose orders are complete, cancelled or rejected as done.
+func (m *TrailingStopManager) OnOrderUpdate(order Order) {
+	if !IsTerminalStatus(order.Status) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.stops {
+		if s.OrderID == order.OrderID {
+			s.Done = true
+		}
+	}
+}
+
+// modify modifies the trigger of a stop's order.
+func (m *TrailingStopManager) modify(s *trailingStop, trigger float64) {
+	err := m.c.orderLimiter.wait(context.Background())
+	if err == nil {
+		_, err = m.c.ModifyOrder(VarietyRegular, s.OrderID, s.orderParams(trigger))
+	}
+
+	m.mu.Lock()
+	s.inflight = false
+	s.LastModified = m.now()
+	if err == nil {
+		s.TriggerPrice = trigger
+		s.Modifications++
+	}
+	f := m.onError
+	tag := s.Params.Tag
+	m.mu.Unlock()
+
+	if err != nil && f != nil {
+		f(tag, err)
+	}
+}
+
+// track updates the best price of the stop.
+func (s *trailingStop) track(price float64) {
+	if s.Params.TransactionType == TransactionTypeBuy {
+		s.BestPrice = math.Max(s.BestPrice, price)
+	} else {
+		s.BestPrice = math.Min(s.BestPrice, price)
+	}
+}
+
+// next returns the trigger the order is to be modified to, if it's to be modified now.
+func (s *trailingStop) next(now time.Time) (float64, bool) {
+	p := s.Params
+	if s.inflight || s.Modifications >= p.MaxModifications || now.Sub(s.LastModified) < p.ModifyInterval {
+		return 0, false
+	}
+
+	trigger := s.trigger(s.BestPrice)
+	if p.TransactionType == TransactionTypeBuy && trigger >= s.TriggerPrice+p.Step-1e-9 ||
+		p.TransactionType == TransactionTypeSell && trigger <= s.TriggerPrice-p.Step+1e-9 {
+		return trigger, true
+	}
+	return 0, false
+}
+
+// trail returns the distance of the trigger from a price.
+func (s *trailingStop) trail(price float64) float64 {
+	if s.Params.TrailPercent > 0 {
+		return price * s.Params.TrailPercent / 100
+	}
+	return s.Params.Trail
+}
+
+// best returns the best price which a trigger is set from, the inverse of trigger
+// apart from the rounding.
+func (s *trailingStop) best(trigger float64) float64 {
+	p := s.Params
+	if p.TransactionType == TransactionTypeBuy {
+		if p.TrailPercent > 0 {
+			return trigger / (1 - p.TrailPercent/100)
+		}
+		return trigger + p.Trail
+	}
+	if p.TrailPercent > 0 {
+		return trigger / (1 + p.TrailPercent/100)
+	}
+	return trigger - p.Trail
+}
+
+// trigger returns the trigger for a best price, rounded away from the price to the tick.
+func (s *trailingStop) trigger(best float64) float64 {
+	if s.Params.TransactionType == TransactionTypeBuy {
+		return roundToTick(best-s.trail(best), s.Params.TickSize, math.Floor)
+	}
+	return roundToTick(best+s.trail(best), s.Params.TickSize, math.Ceil)
+}
+
+// orderParams returns the parameters of the stoploss order at a trigger.
+func (s *trailingStop) orderParams(trigger float64) OrderParams {
+	p := s.Params
+	o := OrderParams{
+		Exchange:        p.Exchange,
+		Tradingsymbol:   p.Tradingsymbol,
+		Validity:        ValidityDay,
+		Product:         p.Product,
+		OrderType:       p.OrderType,
+		TransactionType: TransactionTypeSell,
+		Quantity:        p.Quantity,
+		TriggerPrice:    trigger,
+		Tag:             p.Tag,
+	}
+	if p.TransactionType == TransactionTypeSell {
+		o.TransactionType = TransactionTypeBuy
+	}
+
+	if p.OrderType == OrderTypeSL {
+		if o.TransactionType == TransactionTypeSell {
+			o.Price = roundToTick(trigger-p.LimitOffset, p.TickSize, math.Floor)
+		} else {
+			o.Price = roundToTick(trigger+p.LimitOffset, p.TickSize, math.Ceil)
+		}
+	}
+	return o
+}
+
+// trailingDefaults validates the parameters of a stop and sets the defaults.
+func trailingDefaults(p TrailingStopParams) (TrailingStopParams, error) {
+	switch {
+	case p.Exchange == "" || p.Tradingsymbol == "" || p.InstrumentToken == 0:
+		return p, NewError(InputError, "Exchange, tradingsymbol and instrument token are required", nil)
+	case !p.TransactionType.Valid():
+		return p, NewError(InputError, "Transaction type of the position is required", nil)
+	case p.Quantity <= 0:
+		return p, NewError(InputError, "Quantity should be positive", nil)
+	case p.Trail <= 0 && p.TrailPercent <= 0:
+		return p, NewError(InputError, "Trail or trail percent should be positive", nil)
+	case p.TrailPercent >= 100:
+		return p, NewError(InputError, "Trail percent should be less than 100", nil)
+	case p.Tag == "":
+		return p, NewError(InputError, "Tag is required", nil)
+	}
+
+	if p.OrderType == "" {
+		p.OrderType = OrderTypeSLM
+	}
+	if p.OrderType != OrderTypeSL && p.OrderType != OrderTypeSLM {
+		return p, NewError(InputError, "Order type should be SL or SL-M", nil)
+	}
+	if p.TickSize <= 0 {
+		p.TickSize = defaultTickSize
+	}
+	if p.Step < p.TickSize {
+		p.Step = p.TickSize
+	}
+	if p.ModifyInterval <= 0 {
+		p.ModifyInterval = trailingModifyInterval
+	}
+	if p.MaxModifications <= 0 {
+		p.MaxModifications = trailingMaxModifications
+	}
+	return p, nil
+}
@@ -0,0 +1,318 @@
+package kiteconnect
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerodha/gokiteconnect/v4/models"
+)
+
+// fakeClock is a clock which only moves when told to.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTrailingManager(t *testing.T, s *exchangeServer, c *Client) (*TrailingStopManager, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	m := NewTrailingStopManager(c)
+	m.now = clock.now
+	m.OnError(func(tag string, err error) {
+		t.Errorf("error modifying %s: %v", tag, err)
+	})
+	return m, clock
+}
+
+// modifiedTriggers returns the trigger prices the orders were modified to.
+func (s *exchangeServer) modifiedTriggers() []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []float64
+	for _, m := range s.modified {
+		out = append(out, m["trigger_price"].(float64))
+	}
+	return out
+}
+
+func waitModified(t *testing.T, s *exchangeServer, triggers ...float64) {
+	require.Eventually(t, func() bool {
+		return len(s.modifiedTriggers()) == len(triggers)
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, triggers, s.modifiedTriggers())
+}
+
+func trailingTick(price float64) models.Tick {
+	return models.Tick{InstrumentToken: 408065, LastPrice: price}
+}
+
+func TestTrailingStopLong(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.ltp = map[string]float64{"NSE:INFY": 100}
+	m, clock := newTrailingManager(t, s, c)
+
+	stop, err := m.Start(TrailingStopParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		InstrumentToken: 408065,
+		Product:         ProductMIS,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        10,
+		Trail:           2,
+		Step:            0.5,
+		Tag:             "trail1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 98.0, stop.TriggerPrice)
+
+	placed := s.placed[0]
+	require.Equal(t, TransactionTypeSell, placed["transaction_type"])
+	require.Equal(t, OrderTypeSLM, placed["order_type"])
+	require.Equal(t, 98.0, placed["trigger_price"])
+
+	// Moves smaller than the step and against the position are ignored.
+	m.OnTick(trailingTick(100.4))
+	m.OnTick(trailingTick(99))
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(101))
+	waitModified(t, s, 99)
+
+	// Modifications are throttled and the best price is applied later.
+	m.OnTick(trailingTick(103))
+	m.OnTick(trailingTick(102))
+	waitModified(t, s, 99)
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(102.5))
+	waitModified(t, s, 99, 101)
+
+	stops := m.Stops()
+	require.Len(t, stops, 1)
+	require.Equal(t, 101.0, stops[0].TriggerPrice)
+	require.Equal(t, 103.0, stops[0].BestPrice)
+	require.Equal(t, 2, stops[0].Modifications)
+
+	// Stops are done once the order is triggered.
+	m.OnOrderUpdate(Order{OrderID: stop.OrderID, Status: OrderStatusComplete})
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(110))
+	require.True(t, m.Stops()[0].Done)
+	require.Len(t, s.modifiedTriggers(), 2)
+
+	// Stops can't be started twice.
+	_, err = m.Start(stops[0].Params)
+	require.Error(t, err)
+}
+
+func TestTrailingStopShort(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.ltp = map[string]float64{"NSE:INFY": 200}
+	m, clock := newTrailingManager(t, s, c)
+
+	_, err := m.Start(TrailingStopParams{
+		Exchange:         ExchangeNSE,
+		Tradingsymbol:    "INFY",
+		InstrumentToken:  408065,
+		Product:          ProductMIS,
+		TransactionType:  TransactionTypeSell,
+		Quantity:         10,
+		TrailPercent:     1,
+		OrderType:        OrderTypeSL,
+		LimitOffset:      0.5,
+		Tag:              "trail2",
+		MaxModifications: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransactionTypeBuy, s.placed[0]["transaction_type"])
+	require.Equal(t, 202.0, s.placed[0]["trigger_price"])
+	require.Equal(t, 202.5, s.placed[0]["price"])
+
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(190))
+	waitModified(t, s, 191.9)
+	require.Equal(t, 192.4, s.modified[0]["price"])
+
+	// No more modifications are made after the maximum.
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(180))
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, s.modifiedTriggers(), 1)
+}
+
+func TestTrailingStopStartOnce(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	m, _ := newTrailingManager(t, s, c)
+	p := TrailingStopParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		InstrumentToken: 408065,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        10,
+		Trail:           2,
+		Tag:             "trail7",
+	}
+
+	// The tag is released when the order can't be placed.
+	_, err := m.Start(p)
+	require.Error(t, err)
+	require.Empty(t, m.Stops())
+
+	// Concurrent starts of a stop place a single order.
+	s.mu.Lock()
+	s.ltp = map[string]float64{"NSE:INFY": 100}
+	s.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, 5)
+	)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = m.Start(p)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	require.Equal(t, 4, failed)
+	require.Len(t, s.placed, 1)
+	require.Len(t, m.Stops(), 1)
+}
+
+func TestTrailingStopResume(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.seedOrders(
+		map[string]interface{}{"exchange": "NSE", "tradingsymbol": "INFY", "order_type": "SL-M", "trigger_price": 90, "status": OrderStatusCancelled, "tag": "trail3"},
+		map[string]interface{}{"exchange": "NSE", "tradingsymbol": "INFY", "order_type": "SL-M", "trigger_price": 98, "status": OrderStatusTriggerPending, "tag": "trail3"},
+		map[string]interface{}{"exchange": "NSE", "tradingsymbol": "SBIN", "order_type": "SL-M", "trigger_price": 500, "status": OrderStatusComplete, "tag": "trail4"},
+	)
+	s.history["2"] = []map[string]interface{}{{"order_id": "2", "status": OrderStatusModified}, {"order_id": "2", "status": OrderStatusModified}}
+
+	m, clock := newTrailingManager(t, s, c)
+	params := TrailingStopParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		InstrumentToken: 408065,
+		Product:         ProductMIS,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        10,
+		Trail:           2,
+		Tag:             "trail3",
+	}
+	done := params
+	done.Tradingsymbol, done.InstrumentToken, done.Tag = "SBIN", 779521, "trail4"
+
+	stops, err := m.Resume(params, done)
+	require.NoError(t, err)
+	require.Len(t, stops, 2)
+	require.Equal(t, "2", stops[0].OrderID)
+	require.Equal(t, 2, stops[0].Modifications)
+	require.Equal(t, 100.0, stops[0].BestPrice)
+	require.True(t, stops[1].Done)
+	require.Len(t, m.Stops(), 1)
+
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(101))
+	waitModified(t, s, 99)
+}
+
+func TestTrailingStopResumePercent(t *testing.T) {
+	t.Parallel()
+
+	s, c := newExchangeServer(t)
+	s.seedOrders(
+		map[string]interface{}{"exchange": "NSE", "tradingsymbol": "INFY", "order_type": "SL-M", "trigger_price": 98, "status": OrderStatusTriggerPending, "tag": "trail5"},
+		map[string]interface{}{"exchange": "NSE", "tradingsymbol": "SBIN", "order_type": "SL-M", "trigger_price": 202, "status": OrderStatusTriggerPending, "tag": "trail6"},
+	)
+
+	m, clock := newTrailingManager(t, s, c)
+	long := TrailingStopParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		InstrumentToken: 408065,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        10,
+		TrailPercent:    2,
+		Tag:             "trail5",
+	}
+	short := long
+	short.Tradingsymbol, short.InstrumentToken, short.TransactionType, short.TrailPercent, short.Tag = "SBIN", 779521, TransactionTypeSell, 1, "trail6"
+
+	// The best prices are where the triggers were set from.
+	stops, err := m.Resume(long, short)
+	require.NoError(t, err)
+	require.InDelta(t, 100, stops[0].BestPrice, 1e-9)
+	require.InDelta(t, 200, stops[1].BestPrice, 1e-9)
+
+	// Stops which are already being trailed can't be resumed.
+	_, err = m.Resume(long)
+	require.Error(t, err)
+	require.Equal(t, InputError, err.(Error).ErrorType)
+
+	clock.advance(time.Second)
+	m.OnTick(trailingTick(102))
+	waitModified(t, s, 99.95)
+}
+
+func TestTrailingStopInvalid(t *testing.T) {
+	t.Parallel()
+
+	valid := TrailingStopParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		InstrumentToken: 408065,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        10,
+		Trail:           2,
+		Tag:             "trail",
+	}
+	p, err := trailingDefaults(valid)
+	require.NoError(t, err)
+	require.Equal(t, OrderType(OrderTypeSLM), p.OrderType)
+	require.Equal(t, defaultTickSize, p.Step)
+	require.Equal(t, trailingMaxModifications, p.MaxModifications)
+
+	for _, f := range []func(p *TrailingStopParams){
+		func(p *TrailingStopParams) { p.InstrumentToken = 0 },
+		func(p *TrailingStopParams) { p.TransactionType = "" },
+		func(p *TrailingStopParams) { p.Quantity = 0 },
+		func(p *TrailingStopParams) { p.Trail = 0 },
+		func(p *TrailingStopParams) { p.TrailPercent = 100 },
+		func(p *TrailingStopParams) { p.Tag = "" },
+		func(p *TrailingStopParams) { p.OrderType = OrderTypeLimit },
+	} {
+		p := valid
+		f(&p)
+		_, err := trailingDefaults(p)
+		require.Error(t, err)
+		require.Equal(t, InputError, err.(Error).ErrorType)
+	}
+}
@@ -0,0 +1,101 @@
+package kiteconnect
+
+// Exchange represents the exchange of an instrument, eg: ExchangeNSE.
+// The Exchange* constants are untyped so they can be used both as
+// an Exchange and as a plain string.
+type Exchange string
+
+// Variety represents the variety of an order, eg: VarietyRegular.
+type Variety string
+
+// Product represents the product of an order or position, eg: ProductMIS.
+type Product string
+
+// OrderType represents the type of an order, eg: OrderTypeLimit.
+type OrderType string
+
+// TransactionType represents the side of an order, eg: TransactionTypeBuy.
+type TransactionType string
+
+// Validity represents the validity of an order, eg: ValidityDay.
+type Validity string
+
+// Valid returns true if the exchange is one of the known exchanges.
+func (e Exchange) Valid() bool {
+	switch e {
+	case ExchangeNSE, ExchangeBSE, ExchangeMCX, ExchangeNFO, ExchangeBFO, ExchangeCDS, ExchangeBCD:
+		return true
+	}
+	return false
+}
+
+// String returns the exchange as sent to the API.
+func (e Exchange) String() string {
+	return string(e)
+}
+
+// Valid returns true if the variety is one of the known varieties.
+func (v Variety) Valid() bool {
+	switch v {
+	case VarietyRegular, VarietyAMO, VarietyBO, VarietyCO, VarietyIceberg, VarietyAuction:
+		return true
+	}
+	return false
+}
+
+// String returns the variety as sent to the API.
+func (v Variety) String() string {
+	return string(v)
+}
+
+// Valid returns true if the product is one of the known products.
+func (p Product) Valid() bool {
+	switch p {
+	case ProductBO, ProductCO, ProductMIS, ProductCNC, ProductNRML, ProductMTF:
+		return true
+	}
+	return false
+}
+
+// String returns the product as sent to the API.
+func (p Product) String() string {
+	return string(p)
+}
+
+// Valid returns true if the order type is one of the known order types.
+func (o OrderType) Valid() bool {
+	switch o {
+	case OrderTypeMarket, OrderTypeLimit, OrderTypeSL, OrderTypeSLM:
+		return true
+	}
+	return false
+}
+
+// String returns the order type as sent to the API.
+func (o OrderType) String() string {
+	return string(o)
+}
+
+// Valid returns true if the transaction type is either buy or sell.
+func (t TransactionType) Valid() bool {
+	return t == TransactionTypeBuy || t == TransactionTypeSell
+}
+
+// String returns the transaction type as sent to the API.
+func (t TransactionType) String() string {
+	return string(t)
+}
+
+// Valid returns true if the validity is one of the known validities.
+func (v Validity) Valid() bool {
+	switch v {
+	case ValidityDay, ValidityIOC, ValidityTTL:
+		return true
+	}
+	return false
+}
+
+// String returns the validity as sent to the API.
+func (v Validity) String() string {
+	return string(v)
+}
@@ -0,0 +1,70 @@
+package kiteconnect
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-querystring/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypesValid(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, Exchange(ExchangeNFO).Valid())
+	require.False(t, Exchange("nse").Valid())
+	require.True(t, Variety(VarietyIceberg).Valid())
+	require.False(t, Variety("").Valid())
+	require.True(t, Product(ProductMTF).Valid())
+	require.False(t, Product("mis").Valid())
+	require.True(t, OrderType(OrderTypeSLM).Valid())
+	require.False(t, OrderType("STOP").Valid())
+	require.True(t, TransactionType(TransactionTypeSell).Valid())
+	require.False(t, TransactionType("SHORT").Valid())
+	require.True(t, Validity(ValidityTTL).Valid())
+	require.False(t, Validity("GTC").Valid())
+
+	require.Equal(t, "SL-M", OrderType(OrderTypeSLM).String())
+}
+
+func TestTypesEncoding(t *testing.T) {
+	t.Parallel()
+
+	// Untyped constants and string literals both convert to the typed fields.
+	params := OrderParams{
+		Exchange:        ExchangeNSE,
+		Tradingsymbol:   "INFY",
+		Validity:        "DAY",
+		Product:         ProductMIS,
+		OrderType:       OrderTypeLimit,
+		TransactionType: TransactionTypeBuy,
+		Quantity:        1,
+		Price:           1500,
+	}
+
+	v, err := query.Values(params)
+	require.NoError(t, err)
+	require.Equal(t, "exchange=NSE&order_type=LIMIT&price=1500&product=MIS&quantity=1&tradingsymbol=INFY&transaction_type=BUY&validity=DAY", v.Encode())
+
+	b, err := json.Marshal(OrderMarginParam{
+		Exchange:        ExchangeNFO,
+		Tradingsymbol:   "NIFTY24JANFUT",
+		TransactionType: TransactionTypeSell,
+		Variety:         VarietyRegular,
+		Product:         ProductNRML,
+		OrderType:       OrderTypeMarket,
+		Quantity:        50,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"exchange":"NFO","tradingsymbol":"NIFTY24JANFUT","transaction_type":"SELL","variety":"regular","product":"NRML","order_type":"MARKET","quantity":50}`, string(b))
+
+	var p Position
+	require.NoError(t, json.Unmarshal([]byte(`{"exchange":"BSE","product":"CNC"}`), &p))
+	require.Equal(t, Exchange(ExchangeBSE), p.Exchange)
+	require.Equal(t, Product(ProductCNC), p.Product)
+
+	// Unknown values from the API are decoded as they are.
+	require.NoError(t, json.Unmarshal([]byte(`{"exchange":"NCO"}`), &p))
+	require.Equal(t, Exchange("NCO"), p.Exchange)
+	require.False(t, p.Exchange.Valid())
+}
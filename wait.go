@@ -0,0 +1,137 @@
+package kiteconnect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// Interval of polling the order history when waiting for an order,
+	// which doubles after every poll till the maximum.
+	waitPollInterval    time.Duration = 500 * time.Millisecond
+	waitPollMaxInterval time.Duration = 5000 * time.Millisecond
+	// Interval of polling the order history when the order tracker receives
+	// updates, in case an update is missed.
+	waitTrackerPollInterval time.Duration = 10000 * time.Millisecond
+)
+
+// SetOrderTracker sets the tracker used to wait for orders. Feed it the ticker's
+// order updates, eg: ticker.OnOrderUpdate(func(o kiteconnect.Order) { tracker.Update(o) }),
+// so that WaitForOrder doesn't have to poll the order history frequently.
+// Waits which are already in progress keep using the previous tracker.
+func (c *Client) SetOrderTracker(t *OrderTracker) {
+	c.trackerMu.Lock()
+	defer c.trackerMu.Unlock()
+	c.orderTracker = t
+}
+
+func (c *Client) getOrderTracker() *OrderTracker {
+	c.trackerMu.RLock()
+	defer c.trackerMu.RUnlock()
+	return c.orderTracker
+}
+
+// WaitForOrder waits till an order reaches one of the given statuses, which
+// default to COMPLETE, CANCELLED and REJECTED, and returns the order and its trades.
+// If an order tracker is set, its updates are used and the order history is
+// polled only occasionally, else the order history is polled at an interval
+// which grows from 500ms to 5s. Network and server errors in fetching the order
+// history are retried at the poll interval, while the other errors are returned
+// right away. An error is returned if the order reaches a terminal status which
+// isn't one of the given ones or if the context is done, along with the latest
+// known state of the order.
+func (c *Client) WaitForOrder(ctx context.Context, orderID string, statuses ...string) (Order, []Trade, error) {
+	if len(statuses) == 0 {
+		statuses = []string{OrderStatusComplete, OrderStatusCancelled, OrderStatusRejected}
+	}
+
+	var (
+		tracker  = c.getOrderTracker()
+		updates  <-chan struct{}
+		interval = waitPollInterval
+		order    Order
+	)
+
+	if tracker != nil {
+		ch, stop := tracker.watch(orderID)
+		defer stop()
+		updates, interval = ch, waitTrackerPollInterval
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return order, nil, ctx.Err()
+
+		case <-updates:
+
+		case <-timer.C:
+			history, err := c.GetOrderHistory(orderID)
+			switch {
+			case err != nil && !isTransientError(err):
+				return order, nil, err
+			case err != nil:
+				// The order may still be live, so it's polled again.
+			case tracker != nil:
+				tracker.UpdateHistory(history)
+			case len(history) > 0:
+				order = history[len(history)-1]
+			}
+
+			timer.Reset(interval)
+			if tracker == nil && interval < waitPollMaxInterval {
+				interval *= 2
+				if interval > waitPollMaxInterval {
+					interval = waitPollMaxInterval
+				}
+			}
+		}
+
+		if tracker != nil {
+			if o, ok := tracker.Order(orderID); ok {
+				order = o
+			}
+		}
+		if order.OrderID == "" {
+			continue
+		}
+
+		for _, s := range statuses {
+			if order.Status == s {
+				trades, err := c.orderTrades(order)
+				return order, trades, err
+			}
+		}
+
+		if IsTerminalStatus(order.Status) {
+			trades, err := c.orderTrades(order)
+			if err != nil {
+				return order, trades, err
+			}
+			return order, trades, NewError(OrderError, fmt.Sprintf("Order %s is %s: %s", orderID, order.Status, order.StatusMessage), nil)
+		}
+	}
+}
+
+// orderTrades returns the trades of an order if any of it is filled.
+func (c *Client) orderTrades(order Order) ([]Trade, error) {
+	if order.FilledQuantity <= 0 {
+		return nil, nil
+	}
+	return c.GetOrderTrades(order.OrderID)
+}
+
+// isTransientError returns true if a request may succeed on retrying, which is
+// the case for network errors, rate limits and server errors.
+func isTransientError(err error) bool {
+	e, ok := err.(Error)
+	if !ok {
+		return true
+	}
+	return e.ErrorType == NetworkError || e.Code == http.StatusTooManyRequests || e.Code >= http.StatusInternalServerError
+}
@@ -0,0 +1,166 @@
+package kiteconnect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// orderServer serves the order history and trades of order 1, advancing
+// through the given statuses on every history request. The first history
+// requests fail with the given errors.
+type orderServer struct {
+	mu       sync.Mutex
+	statuses []string
+	errors   []Error
+	polls    int
+}
+
+func (s *orderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var data interface{}
+	switch r.URL.Path {
+	case "/orders/1":
+		if len(s.errors) > 0 {
+			e := s.errors[0]
+			s.errors = s.errors[1:]
+			w.WriteHeader(e.Code)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "error_type": e.ErrorType, "message": e.Message})
+			return
+		}
+
+		i := s.polls
+		if i >= len(s.statuses) {
+			i = len(s.statuses) - 1
+		}
+		s.polls++
+
+		// Maps are used as zero timestamps of orders don't unmarshal.
+		var history []map[string]interface{}
+		for _, st := range s.statuses[:i+1] {
+			o := map[string]interface{}{"order_id": "1", "status": st, "quantity": 10, "status_message": "message"}
+			if st == OrderStatusComplete {
+				o["filled_quantity"], o["average_price"] = 10, 100
+			}
+			history = append(history, o)
+		}
+		data = history
+	case "/orders/1/trades":
+		data = []map[string]interface{}{{"order_id": "1", "trade_id": "t1", "quantity": 10, "average_price": 100}}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": data})
+}
+
+func newWaitClient(t *testing.T, s *orderServer) *Client {
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	c := New("api_key")
+	c.SetBaseURI(srv.URL)
+	return c
+}
+
+func TestWaitForOrderPolling(t *testing.T) {
+	t.Parallel()
+
+	s := &orderServer{statuses: []string{OrderStatusOpen, OrderStatusComplete}}
+	c := newWaitClient(t, s)
+
+	order, trades, err := c.WaitForOrder(context.Background(), "1")
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusComplete, order.Status)
+	require.Equal(t, []Trade{{OrderID: "1", TradeID: "t1", Quantity: 10, AveragePrice: 100}}, trades)
+	require.Equal(t, 2, s.polls)
+}
+
+func TestWaitForOrderErrors(t *testing.T) {
+	t.Parallel()
+
+	// Server errors are retried as the order may still be live.
+	s := &orderServer{
+		statuses: []string{OrderStatusComplete},
+		errors:   []Error{newError(NetworkError, "Gateway timed out", http.StatusGatewayTimeout, nil)},
+	}
+	c := newWaitClient(t, s)
+
+	order, _, err := c.WaitForOrder(context.Background(), "1")
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusComplete, order.Status)
+
+	// Other errors are returned right away.
+	s = &orderServer{
+		statuses: []string{OrderStatusComplete},
+		errors:   []Error{newError(InputError, "Invalid order", http.StatusBadRequest, nil)},
+	}
+	c = newWaitClient(t, s)
+
+	_, _, err = c.WaitForOrder(context.Background(), "1")
+	require.Error(t, err)
+	require.Equal(t, InputError, err.(Error).ErrorType)
+	require.Equal(t, 0, s.polls)
+
+	require.True(t, isTransientError(NewError(GeneralError, "Something went wrong", nil)))
+	require.False(t, isTransientError(NewError(TokenError, "Session expired", nil)))
+}
+
+func TestWaitForOrderStatuses(t *testing.T) {
+	t.Parallel()
+
+	// Waiting for the order to be open.
+	c := newWaitClient(t, &orderServer{statuses: []string{OrderStatusOpen}})
+	order, trades, err := c.WaitForOrder(context.Background(), "1", OrderStatusOpen, OrderStatusTriggerPending)
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusOpen, order.Status)
+	require.Nil(t, trades)
+
+	// Rejected while waiting for completion.
+	c = newWaitClient(t, &orderServer{statuses: []string{OrderStatusRejected}})
+	order, _, err = c.WaitForOrder(context.Background(), "1", OrderStatusComplete)
+	require.Error(t, err)
+	require.Equal(t, OrderError, err.(Error).ErrorType)
+	require.Equal(t, OrderStatusRejected, order.Status)
+
+	// Context done before the order completes.
+	c = newWaitClient(t, &orderServer{statuses: []string{OrderStatusOpen}})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	order, _, err = c.WaitForOrder(ctx, "1")
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, OrderStatusOpen, order.Status)
+}
+
+func TestWaitForOrderTracker(t *testing.T) {
+	t.Parallel()
+
+	s := &orderServer{statuses: []string{OrderStatusOpen}}
+	c := newWaitClient(t, s)
+
+	tracker := NewOrderTracker()
+	c.SetOrderTracker(tracker)
+
+	// Order updates are delivered by the tracker instead of polling.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		tracker.Update(Order{OrderID: "1", Status: OrderStatusComplete, Quantity: 10, FilledQuantity: 10, AveragePrice: 100})
+	}()
+
+	start := time.Now()
+	order, trades, err := c.WaitForOrder(context.Background(), "1")
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusComplete, order.Status)
+	require.Len(t, trades, 1)
+	require.Equal(t, 1, s.polls)
+	require.True(t, time.Since(start) < waitPollInterval)
+}